@@ -3,10 +3,13 @@
 //go:generate asset agpl_3.0.txt
 //go:generate asset apache_2.0.txt
 //go:generate asset artistic_2.0.txt
+//go:generate asset bsd_0_clause.txt
 //go:generate asset bsd_2_clause.txt
 //go:generate asset bsd_3_clause_clear.txt
 //go:generate asset bsd_3_clause.txt
 //go:generate asset cc0_1.0.txt
+//go:generate asset cc_by_4.0.txt
+//go:generate asset cc_by_sa_4.0.txt
 //go:generate asset epl_1.0.txt
 //go:generate asset gpl_2.0.txt
 //go:generate asset gpl_3.0.txt
@@ -14,6 +17,7 @@
 //go:generate asset lgpl_2.1.txt
 //go:generate asset lgpl_3.0.txt
 //go:generate asset mit.txt
+//go:generate asset mit_0.txt
 //go:generate asset mpl_2.0.txt
 //go:generate asset ms_pl.txt
 //go:generate asset ms_rl.txt
@@ -22,6 +26,7 @@
 //go:generate asset osl_3.0.txt
 //go:generate asset unlicense.txt
 //go:generate asset wtfpl.txt
+//go:generate asset zlib.txt
 
 package assets
 
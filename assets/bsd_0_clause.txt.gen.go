@@ -0,0 +1,7 @@
+// AUTOMATICALLY GENERATED FILE. DO NOT EDIT.
+
+package assets
+
+var bsd_0_clause = txt(asset{Name: "bsd_0_clause.txt", Content: "" +
+	"---\ntitle: BSD Zero Clause License\nspdx: 0BSD\nsource: https://opensource.org/licenses/0BSD\n\ndescription: A public-domain-equivalent license derived from the ISC license, without the requirement to include the copyright notice and license text in copies.\n\nhow: Create a text file (typically named LICENSE or LICENSE.txt) in the root of your source code and copy the text of the license into the file. Replace [year] with the current year and [fullname] with the name (or names) of the copyright holders.\n\nrequired: []\n\npermitted:\n  - commercial-use\n  - distribution\n  - modifications\n  - private-use\n\nforbidden:\n  - no-liability\n\n---\n\nCopyright (C) [year] by [fullname]\n\nPermission to use, copy, modify, and/or distribute this software for any\npurpose with or without fee is hereby granted.\n\nTHE SOFTWARE IS PROVIDED \"AS IS\" AND THE AUTHOR DISCLAIMS ALL WARRANTIES\nWITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF\nMERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR\nANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES\nWHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN\nACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF\nOR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.\n" +
+	"", etag: `"MMrjgksXvfM="`})
@@ -3,5 +3,5 @@
 package assets
 
 var bsd_2_clause = txt(asset{Name: "bsd_2_clause.txt", Content: "" +
-	"---\ntitle: BSD 2-clause \"Simplified\" License\nnickname: Simplified BSD\ntab-slug: bsd\nredirect_from: /licenses/bsd/\ncategory: BSD\nvariant: true\nsource: http://opensource.org/licenses/BSD-2-Clause\n\ndescription: A permissive license that comes in two variants, the <a href=\"/licenses/bsd\">BSD 2-Clause</a> and <a href=\"/licenses/bsd-3-clause\">BSD 3-Clause</a>. Both have very minute differences to the MIT license.\n\nhow: Create a text file (typically named LICENSE or LICENSE.txt) in the root of your source code and copy the text of the license into the file. Replace [year] with the current year and [fullname] with the name (or names) of the copyright holders.\n\nrequired:\n  - include-copyright\n\npermitted:\n  - commercial-use\n  - modifications\n  - distribution\n  - sublicense\n  - private-use\n\nforbidden:\n  - no-liability\n\n---\n\nCopyright (c) [year], [fullname]\nAll rights reserved.\n\nRedistribution and use in source and binary forms, with or without\nmodification, are permitted provided that the following conditions are met:\n\n* Redistributions of source code must retain the above copyright notice, this\n  list of conditions and the following disclaimer.\n\n* Redistributions in binary form must reproduce the above copyright notice,\n  this list of conditions and the following disclaimer in the documentation\n  and/or other materials provided with the distribution.\n\nTHIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS \"AS IS\"\nAND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE\nIMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE\nDISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE\nFOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL\nDAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR\nSERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER\nCAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,\nOR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE\nOF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.\n" +
-	"", etag: `"xnC7QeELVQY="`})
+	"---\ntitle: BSD 2-clause \"Simplified\" License\nspdx: BSD-2-Clause\nnickname: Simplified BSD\ntab-slug: bsd\nredirect_from: /licenses/bsd/\ncategory: BSD\nvariant: true\nsource: http://opensource.org/licenses/BSD-2-Clause\n\ndescription: A permissive license that comes in two variants, the <a href=\"/licenses/bsd\">BSD 2-Clause</a> and <a href=\"/licenses/bsd-3-clause\">BSD 3-Clause</a>. Both have very minute differences to the MIT license.\n\nhow: Create a text file (typically named LICENSE or LICENSE.txt) in the root of your source code and copy the text of the license into the file. Replace [year] with the current year and [fullname] with the name (or names) of the copyright holders.\n\nrequired:\n  - include-copyright\n\npermitted:\n  - commercial-use\n  - modifications\n  - distribution\n  - sublicense\n  - private-use\n\nforbidden:\n  - no-liability\n\n---\n\nCopyright (c) [year], [fullname]\nAll rights reserved.\n\nRedistribution and use in source and binary forms, with or without\nmodification, are permitted provided that the following conditions are met:\n\n* Redistributions of source code must retain the above copyright notice, this\n  list of conditions and the following disclaimer.\n\n* Redistributions in binary form must reproduce the above copyright notice,\n  this list of conditions and the following disclaimer in the documentation\n  and/or other materials provided with the distribution.\n\nTHIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS \"AS IS\"\nAND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE\nIMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE\nDISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE\nFOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL\nDAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR\nSERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER\nCAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,\nOR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE\nOF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.\n" +
+	"", etag: `"CO5Trg1wZQk="`})
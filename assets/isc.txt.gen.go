@@ -3,5 +3,5 @@
 package assets
 
 var isc = txt(asset{Name: "isc.txt", Content: "" +
-	"---\ntitle: ISC License\ntab-slug: isc\ncategory: BSD\nsource: http://opensource.org/licenses/isc-license\n\ndescription: A permissive license lets people do anything with your code with proper attribution and without warranty. The ISC license is functionally equivalent to the <a href=\"/licenses/bsd\">BSD 2-Clause</a> and <a href=\"/licenses/mit\">MIT</a> licenses, removing some language that is no longer necessary.\n\nhow: Create a text file (typically named LICENSE or LICENSE.txt) in the root of your source code and copy the text of the license into the file. Replace [year] with the current year and [fullname] with the name (or names) of the copyright holders.\n\nrequired:\n  - include-copyright\n\npermitted:\n  - commercial-use\n  - distribution\n  - modifications\n  - private-use\n  - sublicense\n\nforbidden:\n  - no-liability\n\n---\n\nCopyright (c) [year], [fullname]\n\nPermission to use, copy, modify, and/or distribute this software for any\npurpose with or without fee is hereby granted, provided that the above\ncopyright notice and this permission notice appear in all copies.\n\nTHE SOFTWARE IS PROVIDED \"AS IS\" AND THE AUTHOR DISCLAIMS ALL WARRANTIES\nWITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF\nMERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR\nANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES\nWHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN\nACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF\nOR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.\n" +
-	"", etag: `"m1ByQy6u6k4="`})
+	"---\ntitle: ISC License\nspdx: ISC\ntab-slug: isc\ncategory: BSD\nsource: http://opensource.org/licenses/isc-license\n\ndescription: A permissive license lets people do anything with your code with proper attribution and without warranty. The ISC license is functionally equivalent to the <a href=\"/licenses/bsd\">BSD 2-Clause</a> and <a href=\"/licenses/mit\">MIT</a> licenses, removing some language that is no longer necessary.\n\nhow: Create a text file (typically named LICENSE or LICENSE.txt) in the root of your source code and copy the text of the license into the file. Replace [year] with the current year and [fullname] with the name (or names) of the copyright holders.\n\nrequired:\n  - include-copyright\n\npermitted:\n  - commercial-use\n  - distribution\n  - modifications\n  - private-use\n  - sublicense\n\nforbidden:\n  - no-liability\n\n---\n\nCopyright (c) [year], [fullname]\n\nPermission to use, copy, modify, and/or distribute this software for any\npurpose with or without fee is hereby granted, provided that the above\ncopyright notice and this permission notice appear in all copies.\n\nTHE SOFTWARE IS PROVIDED \"AS IS\" AND THE AUTHOR DISCLAIMS ALL WARRANTIES\nWITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF\nMERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR\nANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES\nWHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN\nACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF\nOR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.\n" +
+	"", etag: `"0ZHK1ViAi74="`})
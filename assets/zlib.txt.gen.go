@@ -0,0 +1,7 @@
+// AUTOMATICALLY GENERATED FILE. DO NOT EDIT.
+
+package assets
+
+var zlib = txt(asset{Name: "zlib.txt", Content: "" +
+	"---\ntitle: zlib License\nspdx: Zlib\nsource: https://opensource.org/licenses/Zlib\n\ndescription: A short permissive license, compatible with GPL. It requires altered source versions to be plainly marked as such.\n\nhow: Create a text file (typically named LICENSE or LICENSE.txt) in the root of your source code and copy the text of the license into the file. Replace [year] with the current year and [fullname] with the name (or names) of the copyright holders.\n\nrequired:\n  - include-copyright\n  - document-changes\n\npermitted:\n  - commercial-use\n  - modifications\n  - distribution\n  - private-use\n\nforbidden:\n  - no-liability\n\n---\n\nzlib License\n\nCopyright (c) [year] [fullname]\n\nThis software is provided 'as-is', without any express or implied\nwarranty. In no event will the authors be held liable for any damages\narising from the use of this software.\n\nPermission is granted to anyone to use this software for any purpose,\nincluding commercial applications, and to alter it and redistribute it\nfreely, subject to the following restrictions:\n\n1. The origin of this software must not be misrepresented; you must not\n   claim that you wrote the original software. If you use this software\n   in a product, an acknowledgment in the product documentation would be\n   appreciated but is not required.\n2. Altered source versions must be plainly marked as such, and must not be\n   misrepresented as being the original software.\n3. This notice may not be removed or altered from any source distribution.\n" +
+	"", etag: `"HGpVGuo0YfA="`})
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pmezard/licenses/licensecheck"
+)
+
+// cacheFormat is bumped when the cache file layout or the matching algorithm
+// change, to invalidate existing caches.
+const cacheFormat = 15
+
+// cachedMatch is the serialized form of a MatchResult, templates being
+// referred to by TemplateKey.
+type cachedMatch struct {
+	Template      string              `json:"template"`
+	Score         float64             `json:"score"`
+	ExtraWords    []string            `json:"extraWords"`
+	MissingWords  []string            `json:"missingWords"`
+	Extra         []licensecheck.Word `json:"extra"`
+	Missing       []licensecheck.Word `json:"missing"`
+	HeaderWords   []string            `json:"headerWords"`
+	Parts         []cachedMatch       `json:"parts"`
+	OrLater       bool                `json:"orLater"`
+	Common        int                 `json:"common"`
+	LicenseWords  int                 `json:"licenseWords"`
+	TemplateWords int                 `json:"templateWords"`
+	URL           string              `json:"url"`
+}
+
+type cacheFile struct {
+	Version string                 `json:"version"`
+	Matches map[string]cachedMatch `json:"matches"`
+}
+
+// matchCache stores match results on disk, keyed by license content hash, so
+// they can be reused across runs. It is tied to the matcher templates and
+// discarded when they change. It is safe for concurrent use.
+type matchCache struct {
+	path      string
+	matcher   *licensecheck.Matcher
+	templates map[string]*licensecheck.Template
+	// lock guards file and dirty.
+	lock  sync.Mutex
+	file  cacheFile
+	dirty bool
+}
+
+// templatesVersion returns a hash identifying the matcher templates, copyright
+// patterns and stopwords setting.
+func templatesVersion(m *licensecheck.Matcher) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n", cacheFormat)
+	for _, t := range m.Templates() {
+		fmt.Fprintf(h, "%q %q %q %q\n", t.Title, t.Nickname, t.SPDX, t.Text)
+	}
+	for _, re := range m.CopyrightPatterns() {
+		fmt.Fprintf(h, "copyright %q\n", re.String())
+	}
+	if m.IgnoresStopwords() {
+		fmt.Fprintf(h, "stopwords\n")
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// openMatchCache loads the match cache stored in dir. Missing, unreadable or
+// outdated cache files are replaced with an empty cache.
+func openMatchCache(dir string, matcher *licensecheck.Matcher) *matchCache {
+	c := &matchCache{
+		path:      filepath.Join(dir, "matches.json"),
+		matcher:   matcher,
+		templates: map[string]*licensecheck.Template{},
+		file: cacheFile{
+			Version: templatesVersion(matcher),
+		},
+	}
+	for _, t := range matcher.Templates() {
+		c.templates[licensecheck.TemplateKey(t)] = t
+	}
+	data, err := ioutil.ReadFile(c.path)
+	if err == nil {
+		f := cacheFile{}
+		if json.Unmarshal(data, &f) == nil && f.Version == c.file.Version {
+			c.file.Matches = f.Matches
+		}
+	}
+	if c.file.Matches == nil {
+		c.file.Matches = map[string]cachedMatch{}
+	}
+	return c
+}
+
+func (c *matchCache) encode(m licensecheck.MatchResult) cachedMatch {
+	e := cachedMatch{
+		Score:         m.Score,
+		ExtraWords:    m.ExtraWords,
+		MissingWords:  m.MissingWords,
+		Extra:         m.Extra,
+		Missing:       m.Missing,
+		HeaderWords:   m.HeaderWords,
+		OrLater:       m.OrLater,
+		Common:        m.Common,
+		LicenseWords:  m.LicenseWords,
+		TemplateWords: m.TemplateWords,
+		URL:           m.URL,
+	}
+	if m.Template != nil {
+		e.Template = licensecheck.TemplateKey(m.Template)
+	}
+	for _, p := range m.Parts {
+		e.Parts = append(e.Parts, c.encode(p))
+	}
+	return e
+}
+
+func (c *matchCache) decode(e cachedMatch) (licensecheck.MatchResult, bool) {
+	m := licensecheck.MatchResult{
+		Score:         e.Score,
+		ExtraWords:    e.ExtraWords,
+		MissingWords:  e.MissingWords,
+		Extra:         e.Extra,
+		Missing:       e.Missing,
+		HeaderWords:   e.HeaderWords,
+		OrLater:       e.OrLater,
+		Common:        e.Common,
+		LicenseWords:  e.LicenseWords,
+		TemplateWords: e.TemplateWords,
+		URL:           e.URL,
+	}
+	if e.Template != "" {
+		t, ok := c.templates[e.Template]
+		if !ok {
+			return m, false
+		}
+		m.Template = t
+	}
+	for _, p := range e.Parts {
+		part, ok := c.decode(p)
+		if !ok {
+			return m, false
+		}
+		m.Parts = append(m.Parts, part)
+	}
+	return m, true
+}
+
+// Match returns the cached match result of license data, or matches it and
+// caches the result.
+func (c *matchCache) Match(data []byte) licensecheck.MatchResult {
+	sum := sha256.Sum256(data)
+	key := hex.EncodeToString(sum[:])
+	c.lock.Lock()
+	e, ok := c.file.Matches[key]
+	c.lock.Unlock()
+	if ok {
+		if m, ok := c.decode(e); ok {
+			return m
+		}
+	}
+	m := c.matcher.Match(data)
+	e = c.encode(m)
+	c.lock.Lock()
+	c.file.Matches[key] = e
+	c.dirty = true
+	c.lock.Unlock()
+	return m
+}
+
+// Save writes the cache to disk if it was modified. The file is replaced
+// atomically so concurrent runs do not read partial caches.
+func (c *matchCache) Save() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.dirty {
+		return nil
+	}
+	data, err := json.Marshal(&c.file)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(c.path)
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	f, err := createAtomic(c.path)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Commit()
+	}
+	if err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
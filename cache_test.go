@@ -0,0 +1,102 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pmezard/licenses/licensecheck"
+)
+
+func TestMatchCache(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "src", "colors", "black",
+		"LICENSE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := licensecheck.NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	cache := openMatchCache(dir, m)
+	wanted := m.Match(data)
+	if len(wanted.Parts) == 0 {
+		t.Fatal("dual license was not detected")
+	}
+	// Miss
+	if got := cache.Match(data); !reflect.DeepEqual(got, wanted) {
+		t.Fatalf("unexpected match: %+v != %+v", got, wanted)
+	}
+	if !cache.dirty {
+		t.Fatal("cache miss did not update the cache")
+	}
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Hit, read from disk
+	cache = openMatchCache(dir, m)
+	if len(cache.file.Matches) != 1 {
+		t.Fatalf("unexpected cache entries: %d", len(cache.file.Matches))
+	}
+	if got := cache.Match(data); !reflect.DeepEqual(got, wanted) {
+		t.Fatalf("unexpected cached match: %+v != %+v", got, wanted)
+	}
+	if cache.dirty {
+		t.Fatal("cache hit updated the cache")
+	}
+
+	// Templates changes invalidate the cache
+	m2, err := licensecheck.NewMatcherWithDirs([]string{
+		filepath.Join("testdata", "templates")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if templatesVersion(m) == templatesVersion(m2) {
+		t.Fatal("templates version did not change")
+	}
+	m3, err := licensecheck.NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m3.IgnoreStopwords()
+	if templatesVersion(m) == templatesVersion(m3) {
+		t.Fatal("templates version ignores stopwords setting")
+	}
+	cache = openMatchCache(dir, m2)
+	if len(cache.file.Matches) != 0 {
+		t.Fatal("outdated cache entries were loaded")
+	}
+
+	// Corrupted caches are ignored
+	err = ioutil.WriteFile(filepath.Join(dir, "matches.json"), []byte("{"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache = openMatchCache(dir, m)
+	if len(cache.file.Matches) != 0 {
+		t.Fatal("corrupted cache entries were loaded")
+	}
+}
+
+func TestListLicensesCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	opts := listOptions{CacheDir: dir}
+	wanted, err := listTestdataLicensesWith([]string{"colors/red"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "matches.json")); err != nil {
+		t.Fatalf("cache was not written: %s", err)
+	}
+	got, err := listTestdataLicensesWith([]string{"colors/red"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, wanted) {
+		t.Fatalf("cached licenses differ: %+v != %+v", got, wanted)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// readJSONReport reads a report written with -json.
+func readJSONReport(path string) ([]jsonLicense, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entries := []jsonLicense{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %s", path, err)
+	}
+	return entries, nil
+}
+
+// reportLicense returns the license of a JSON report entry, as compared by
+// diffReports: its SPDX expression, its template title if it has none, "none"
+// if the package has no license file and "?" if it is unknown or failed to
+// load.
+func reportLicense(e jsonLicense) string {
+	switch {
+	case e.Error != "":
+		return "?"
+	case e.SPDX != "" && e.SPDX != spdxNoAssertion && e.SPDX != spdxNone:
+		return e.SPDX
+	case e.SPDX == spdxNone:
+		return "none"
+	case e.Template != nil && e.Class != "" && e.Class != Unknown.String():
+		return e.Template.Title
+	}
+	return "?"
+}
+
+// reportLicenses maps the packages of report entries to their licenses, see
+// reportLicense. Packages with several license files have their distinct
+// licenses sorted and joined with commas.
+func reportLicenses(entries []jsonLicense) map[string]string {
+	names := map[string][]string{}
+	for _, e := range entries {
+		name := reportLicense(e)
+		found := false
+		for _, n := range names[e.Package] {
+			found = found || n == name
+		}
+		if !found {
+			names[e.Package] = append(names[e.Package], name)
+		}
+	}
+	licenses := map[string]string{}
+	for pkg, n := range names {
+		sort.Strings(n)
+		licenses[pkg] = strings.Join(n, ", ")
+	}
+	return licenses
+}
+
+// reportChange is the difference of a package license between two reports.
+// Old is empty for added packages and New for removed ones.
+type reportChange struct {
+	Package string
+	Old     string
+	New     string
+}
+
+// Kind returns "added", "removed" or "changed".
+func (c reportChange) Kind() string {
+	switch {
+	case c.Old == "":
+		return "added"
+	case c.New == "":
+		return "removed"
+	}
+	return "changed"
+}
+
+type sortedReportChanges []reportChange
+
+func (s sortedReportChanges) Len() int {
+	return len(s)
+}
+
+func (s sortedReportChanges) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s sortedReportChanges) Less(i, j int) bool {
+	return s[i].Package < s[j].Package
+}
+
+// diffReports returns the packages added, removed or whose license changed
+// between old and cur report entries, sorted by package.
+func diffReports(old, cur []jsonLicense) []reportChange {
+	oldLicenses := reportLicenses(old)
+	curLicenses := reportLicenses(cur)
+	changes := []reportChange{}
+	for pkg, license := range curLicenses {
+		if oldLicenses[pkg] != license {
+			changes = append(changes, reportChange{
+				Package: pkg,
+				Old:     oldLicenses[pkg],
+				New:     license,
+			})
+		}
+	}
+	for pkg, license := range oldLicenses {
+		if _, ok := curLicenses[pkg]; !ok {
+			changes = append(changes, reportChange{
+				Package: pkg,
+				Old:     license,
+			})
+		}
+	}
+	sort.Sort(sortedReportChanges(changes))
+	return changes
+}
+
+// writeReportDiff writes one line per change, made of its kind, package and
+// licenses.
+func writeReportDiff(out io.Writer, changes []reportChange) error {
+	w := tabwriter.NewWriter(out, 1, 4, 2, ' ', 0)
+	for _, c := range changes {
+		license := c.New
+		switch c.Kind() {
+		case "removed":
+			license = c.Old
+		case "changed":
+			license = c.Old + " -> " + c.New
+		}
+		_, err := fmt.Fprintf(w, "%s\t%s\t%s\n", c.Kind(), c.Package, license)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// regressions returns the licenses of packages added or changed since the
+// old report, which are the only ones checked against the policy when
+// comparing reports.
+func regressions(licenses []License, changes []reportChange) []License {
+	changed := map[string]bool{}
+	for _, c := range changes {
+		if c.New != "" {
+			changed[c.Package] = true
+		}
+	}
+	kept := []License{}
+	for _, l := range licenses {
+		if changed[l.Package] {
+			kept = append(kept, l)
+		}
+	}
+	return kept
+}
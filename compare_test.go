@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiffReports(t *testing.T) {
+	mit := &jsonTemplate{Title: "MIT License", SPDX: "MIT"}
+	bsd := &jsonTemplate{Title: "BSD 3-clause \"New\" or \"Revised\" License",
+		SPDX: "BSD-3-Clause"}
+	custom := &jsonTemplate{Title: "Custom License"}
+	old := []jsonLicense{
+		{Package: "a/relicensed", Template: mit, SPDX: "MIT", Class: "exact"},
+		{Package: "a/removed", Template: mit, SPDX: "MIT", Class: "exact"},
+		{Package: "a/same", Template: custom, SPDX: "NOASSERTION", Class: "confident"},
+		{Package: "a/unknown", Template: mit, SPDX: "NOASSERTION", Class: "unknown"},
+		{Package: "a/dual", Template: mit, SPDX: "MIT", Class: "exact"},
+	}
+	cur := []jsonLicense{
+		{Package: "a/added", SPDX: "NONE", Class: "unknown"},
+		{Package: "a/dual", Template: bsd, SPDX: "BSD-3-Clause", Class: "exact"},
+		{Package: "a/dual", Template: mit, SPDX: "MIT", Class: "exact"},
+		{Package: "a/relicensed", Template: bsd, SPDX: "BSD-3-Clause", Class: "exact"},
+		{Package: "a/same", Template: custom, SPDX: "NOASSERTION", Class: "confident"},
+		{Package: "a/unknown", Error: "cannot find package"},
+	}
+	got := []string{}
+	for _, c := range diffReports(old, cur) {
+		got = append(got, c.Kind()+" "+c.Package+" "+c.Old+" => "+c.New)
+	}
+	wanted := []string{
+		"added a/added  => none",
+		"changed a/dual MIT => BSD-3-Clause, MIT",
+		"changed a/relicensed MIT => BSD-3-Clause",
+		"removed a/removed MIT => ",
+	}
+	if strings.Join(got, "\n") != strings.Join(wanted, "\n") {
+		t.Fatalf("unexpected changes:\n%s\n!=\n%s", strings.Join(got, "\n"),
+			strings.Join(wanted, "\n"))
+	}
+}
+
+func TestCompare(t *testing.T) {
+	gopath, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOPATH", gopath)
+	t.Setenv("GO111MODULE", "off")
+	// colors/red used to be Apache licensed, colors/blue is gone
+	report := filepath.Join(t.TempDir(), "report.json")
+	err = ioutil.WriteFile(report, []byte(`[
+  {"package": "colors/blue", "spdxExpression": "Apache-2.0"},
+  {"package": "colors/red", "spdxExpression": "Apache-2.0"},
+  {"package": "colors/white", "spdxExpression": "MIT"}
+]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(args ...string) (string, error) {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		err := printLicenses(append([]string{"-no-cache", "-a", "-compare", report},
+			args...), stdout, stderr)
+		return stdout.String(), err
+	}
+	out, err := run("colors/red", "colors/white")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := `removed  colors/blue  Apache-2.0
+changed  colors/red   Apache-2.0 -> MIT
+`
+	if out != wanted {
+		t.Fatalf("unexpected output:\n%s\n!=\n%s", out, wanted)
+	}
+	// colors/white was already MIT licensed
+	_, err = run("-deny", "MIT", "colors/red", "colors/white")
+	perr, ok := err.(*PolicyError)
+	if !ok || len(perr.Violations) != 1 || perr.Violations[0].Package != "colors/red" {
+		t.Fatalf("regression was not reported: %v", err)
+	}
+	_, err = run("-deny", "MIT", "colors/white")
+	if err != nil {
+		t.Fatalf("unchanged license was rejected: %v", err)
+	}
+	if _, err := run("-json", "colors/red"); err == nil {
+		t.Fatal("-compare was combined with -json")
+	}
+}
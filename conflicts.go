@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// licenseConflict lists the different licenses of the packages of a module,
+// or of an import path prefix outside of modules.
+type licenseConflict struct {
+	Module string
+	// Prefix is true if Module is the import path prefix of packages outside
+	// of modules, see conflictScope.
+	Prefix bool
+	// Licenses holds the first package entry of each license, in input
+	// order.
+	Licenses []License
+}
+
+// conflictScopeKey identifies the module or import path prefix of a conflict.
+type conflictScopeKey struct {
+	Module string
+	Prefix bool
+}
+
+type sortedLicenseConflicts []licenseConflict
+
+func (s sortedLicenseConflicts) Len() int {
+	return len(s)
+}
+
+func (s sortedLicenseConflicts) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s sortedLicenseConflicts) Less(i, j int) bool {
+	return s[i].Module < s[j].Module
+}
+
+// conflictScope returns the shortest of prefixes containing pkg, or pkg if
+// there is none. Prefixes are the directories of license files, as import
+// paths. Packages vendored below a prefix are not part of it, their license
+// being their own.
+func conflictScope(pkg string, prefixes []string) string {
+	scope := pkg
+	for _, p := range prefixes {
+		if len(p) >= len(scope) || !strings.HasPrefix(pkg, p+"/") {
+			continue
+		}
+		if !strings.Contains(pkg[len(p):]+"/", "/vendor/") {
+			scope = p
+		}
+	}
+	return scope
+}
+
+// findConflicts returns the modules whose packages have licenses matching
+// different templates, sorted by module path. Such inconsistencies are
+// usually accidental, like a subdirectory copied from another project with
+// its license. Entries are expected to be grouped already, see groupLicenses.
+// Outside of modules, entries conflict with the ones below the directory of
+// their license file, see conflictScope. Packages with overridden licenses, or whose license is
+// unknown or below confidence, are ignored. Packages sharing a license file,
+// or with license files matching the same template, do not conflict.
+func findConflicts(licenses []License, confidence float64) []licenseConflict {
+	prefixes := []string{}
+	for _, l := range licenses {
+		if l.Module == "" && l.Path != "" && !l.Override {
+			prefixes = append(prefixes, path.Dir(l.Path))
+		}
+	}
+	names := map[conflictScopeKey][]string{}
+	byScope := map[conflictScopeKey][]License{}
+	for _, l := range licenses {
+		if l.Override || classify(l, confidence).Class == Unknown {
+			continue
+		}
+		scope := conflictScopeKey{Module: l.Module}
+		if l.Module == "" {
+			scope = conflictScopeKey{
+				Module: conflictScope(l.Package, prefixes),
+				Prefix: true,
+			}
+		}
+		name := matchName(l.MatchResult)
+		found := false
+		for _, n := range names[scope] {
+			found = found || n == name
+		}
+		if !found {
+			names[scope] = append(names[scope], name)
+			byScope[scope] = append(byScope[scope], l)
+		}
+	}
+	conflicts := []licenseConflict{}
+	for scope, kept := range byScope {
+		if len(kept) > 1 {
+			conflicts = append(conflicts, licenseConflict{
+				Module:   scope.Module,
+				Prefix:   scope.Prefix,
+				Licenses: kept,
+			})
+		}
+	}
+	sort.Sort(sortedLicenseConflicts(conflicts))
+	return conflicts
+}
+
+// writeConflicts writes a warning line for each conflict, listing the
+// licenses of the module or package and a license file of each.
+func writeConflicts(w io.Writer, conflicts []licenseConflict) error {
+	for _, c := range conflicts {
+		licenses := []string{}
+		for _, l := range c.Licenses {
+			licenses = append(licenses, fmt.Sprintf("%s (%s)", matchName(l.MatchResult),
+				l.Path))
+		}
+		scope := "module " + c.Module
+		if c.Prefix {
+			scope = "package " + c.Module
+		}
+		_, err := fmt.Fprintf(w, "warning: %s has conflicting licenses: %s\n",
+			scope, strings.Join(licenses, ", "))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
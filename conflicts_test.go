@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestConflicts(t *testing.T) {
+	// mixed/soft is MIT licensed, mixed/strict is GPL licensed
+	enterModule(t, "mixed")
+	licenses, err := listLicenses(context.Background(), "", []string{"mixed/cmd/mix"},
+		listOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	err = writeConflicts(buf, findConflicts(licenses, 0.9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := "warning: module mixed has conflicting licenses: MIT License [MIT] " +
+		"(mixed/soft/LICENSE), GNU General Public License v3.0 [GPL-3.0-only] " +
+		"(mixed/strict/COPYING)\n"
+	if buf.String() != wanted {
+		t.Fatalf("unexpected warnings:\n%s\n!=\n%s", buf.String(), wanted)
+	}
+
+	// Low confidence licenses do not conflict
+	if conflicts := findConflicts(licenses, 0.99); len(conflicts) != 0 {
+		t.Fatalf("unexpected conflicts: %+v", conflicts)
+	}
+
+	// Warnings are printed whatever the grouping
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	err = printLicenses([]string{"-no-cache", "mixed/cmd/mix"}, stdout, stderr)
+	if err != nil {
+		t.Fatalf("%s\n%s", err, stderr)
+	}
+	if !strings.Contains(stderr.String(), wanted) {
+		t.Fatalf("conflict not reported:\n%s", stderr)
+	}
+}
+
+func TestNoConflicts(t *testing.T) {
+	enterModule(t, "shapes")
+	licenses, err := listLicenses(context.Background(), "", []string{"shapes/cmd/draw"},
+		listOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conflicts := findConflicts(licenses, 0.9); len(conflicts) != 0 {
+		t.Fatalf("unexpected conflicts: %+v", conflicts)
+	}
+}
+
+func TestConflictsGOPATH(t *testing.T) {
+	// hues is MIT licensed, hues/warm is GPL licensed. shades/dark vendors a
+	// BSD licensed package, which does not conflict.
+	licenses, err := listTestdataLicenses([]string{"hues", "shades/dark"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	grouped, err := groupLicenses(licenses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ls := range [][]License{licenses, grouped} {
+		buf := &bytes.Buffer{}
+		err = writeConflicts(buf, findConflicts(ls, 0.9))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wanted := "warning: package hues has conflicting licenses: MIT License [MIT] " +
+			"(hues/LICENSE), GNU General Public License v3.0 [GPL-3.0-only] " +
+			"(hues/warm/COPYING)\n"
+		if buf.String() != wanted {
+			t.Fatalf("unexpected warnings:\n%s\n!=\n%s", buf.String(), wanted)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const cyclonedxSpecVersion = "1.5"
+
+type cyclonedxOptions struct {
+	// Confidence is the score below which licenses are not reported, see
+	// classify.
+	Confidence float64
+	// Created is the BOM creation time.
+	Created time.Time
+}
+
+type cyclonedxLicense struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// cyclonedxLicenseChoice is either a single license or an SPDX expression.
+type cyclonedxLicenseChoice struct {
+	License    *cyclonedxLicense `json:"license,omitempty"`
+	Expression string            `json:"expression,omitempty"`
+}
+
+type cyclonedxComponent struct {
+	Type     string                   `json:"type"`
+	BOMRef   string                   `json:"bom-ref"`
+	Name     string                   `json:"name"`
+	Version  string                   `json:"version,omitempty"`
+	Licenses []cyclonedxLicenseChoice `json:"licenses"`
+}
+
+type cyclonedxTool struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+type cyclonedxMetadata struct {
+	Timestamp string `json:"timestamp"`
+	Tools     struct {
+		Components []cyclonedxTool `json:"components"`
+	} `json:"tools"`
+}
+
+type cyclonedxBOM struct {
+	BOMFormat   string               `json:"bomFormat"`
+	SpecVersion string               `json:"specVersion"`
+	Version     int                  `json:"version"`
+	Metadata    cyclonedxMetadata    `json:"metadata"`
+	Components  []cyclonedxComponent `json:"components"`
+}
+
+// cyclonedxLicenses returns the license choices of a component: its SPDX
+// identifier or expression when the license is confidently detected, its
+// template title if it has no identifier, and nothing otherwise.
+func cyclonedxLicenses(l License, confidence float64) []cyclonedxLicenseChoice {
+	choices := []cyclonedxLicenseChoice{}
+	if spdx := confidentSPDX(l, confidence); spdx != "" {
+		if strings.Contains(spdx, " ") {
+			return append(choices, cyclonedxLicenseChoice{Expression: spdx})
+		}
+		return append(choices, cyclonedxLicenseChoice{
+			License: &cyclonedxLicense{ID: spdx},
+		})
+	}
+	if l.Err == "" && l.Template != nil && classify(l, confidence).Class != Unknown {
+		choices = append(choices, cyclonedxLicenseChoice{
+			License: &cyclonedxLicense{Name: matchName(l.MatchResult)},
+		})
+	}
+	return choices
+}
+
+// writeCycloneDX writes licenses as a CycloneDX JSON BOM, every entry being a
+// library component with its licenses, see cyclonedxLicenses, and its module
+// version if known. Components are sorted by name.
+func writeCycloneDX(out io.Writer, licenses []License, opts cyclonedxOptions) error {
+	licenses, err := sortLicenses(licenses, "package")
+	if err != nil {
+		return err
+	}
+	bom := cyclonedxBOM{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: cyclonedxSpecVersion,
+		Version:     1,
+		Components:  []cyclonedxComponent{},
+	}
+	bom.Metadata.Timestamp = opts.Created.UTC().Format(time.RFC3339)
+	bom.Metadata.Tools.Components = []cyclonedxTool{
+		{Type: "application", Name: "licenses"},
+	}
+	seen := map[string]bool{}
+	for _, l := range licenses {
+		ref := l.Package
+		if l.Version != "" {
+			ref += "@" + l.Version
+		}
+		base := ref
+		for i := 2; seen[ref]; i++ {
+			ref = fmt.Sprintf("%s#%d", base, i)
+		}
+		seen[ref] = true
+		bom.Components = append(bom.Components, cyclonedxComponent{
+			Type:     "library",
+			BOMRef:   ref,
+			Name:     l.Package,
+			Version:  l.Version,
+			Licenses: cyclonedxLicenses(l, opts.Confidence),
+		})
+	}
+	data, err := json.MarshalIndent(&bom, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(append(data, '\n'))
+	return err
+}
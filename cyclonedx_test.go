@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCycloneDX(t *testing.T) {
+	licenses, err := listTestdataLicenses([]string{"colors/purple"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	licenses = append(licenses, License{
+		Package: "example.com/versioned",
+		Version: "v1.2.3",
+	})
+	buf := &bytes.Buffer{}
+	err = writeCycloneDX(buf, licenses, cyclonedxOptions{
+		Confidence: 0.9,
+		Created:    time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bom := struct {
+		BOMFormat   string `json:"bomFormat"`
+		SpecVersion string `json:"specVersion"`
+		Metadata    struct {
+			Timestamp string `json:"timestamp"`
+		} `json:"metadata"`
+		Components []struct {
+			Name     string `json:"name"`
+			BOMRef   string `json:"bom-ref"`
+			Version  string `json:"version"`
+			Licenses []struct {
+				License *struct {
+					ID   string `json:"id"`
+					Name string `json:"name"`
+				} `json:"license"`
+				Expression string `json:"expression"`
+			} `json:"licenses"`
+		} `json:"components"`
+	}{}
+	if err := json.Unmarshal(buf.Bytes(), &bom); err != nil {
+		t.Fatalf("could not decode BOM: %s\n%s", err, buf.String())
+	}
+	if bom.BOMFormat != "CycloneDX" || bom.SpecVersion != "1.5" ||
+		bom.Metadata.Timestamp != "2020-01-02T03:04:05Z" {
+		t.Fatalf("unexpected BOM header: %+v", bom)
+	}
+	if len(bom.Components) != 5 {
+		t.Fatalf("unexpected components: %+v", bom.Components)
+	}
+	red := bom.Components[3]
+	if red.Name != "colors/red" || len(red.Licenses) != 1 ||
+		red.Licenses[0].License == nil || red.Licenses[0].License.ID != "MIT" {
+		t.Fatalf("unexpected colors/red component: %+v", red)
+	}
+	if missing := bom.Components[1]; missing.Name != "colors/missing" ||
+		len(missing.Licenses) != 0 {
+		t.Fatalf("unexpected colors/missing component: %+v", missing)
+	}
+	versioned := bom.Components[4]
+	if versioned.Version != "v1.2.3" || versioned.BOMRef != "example.com/versioned@v1.2.3" {
+		t.Fatalf("unexpected versioned component: %+v", versioned)
+	}
+}
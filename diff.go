@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pmezard/licenses/licensecheck"
+)
+
+// diffMaxLines is the maximum number of differing sentences displayed for a
+// license.
+const diffMaxLines = 20
+
+var (
+	// reSentenceEnd matches sentence terminators followed by whitespace, and
+	// blank lines.
+	reSentenceEnd = regexp.MustCompile(`[.;:!?]\s+|\n\s*\n`)
+	reSpaces      = regexp.MustCompile(`\s+`)
+	// reWordChar matches characters making license words.
+	reWordChar = regexp.MustCompile(`[\w']`)
+)
+
+// splitSentences returns the cleaned sentences of supplied license text.
+// Lines are wrapped differently across copies of a license, diffing sentences
+// instead of lines avoids reporting reflowed text.
+func splitSentences(text string) []string {
+	text = string(licensecheck.CleanLicenseData([]byte(text)))
+	sentences := []string{}
+	for _, s := range reSentenceEnd.Split(text, -1) {
+		s = strings.TrimSpace(reSpaces.ReplaceAllString(s, " "))
+		if reWordChar.MatchString(s) {
+			sentences = append(sentences, s)
+		}
+	}
+	return sentences
+}
+
+type diffLine struct {
+	// Op is '-' for template only lines, '+' for license only ones and ' '
+	// for common ones.
+	Op   byte
+	Text string
+}
+
+// diffLines returns the longest common subsequence diff turning a into b.
+func diffLines(a, b []string) []diffLine {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	lines := []diffLine{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		} else {
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, diffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, diffLine{'+', b[j]})
+	}
+	return lines
+}
+
+// diffLicense returns the sentences differing between the template and the
+// license text, prefixed with "-" when only in the template and "+" when only
+// in the license. At most maxLines are returned, followed by a line counting
+// the omitted ones.
+func diffLicense(templ *licensecheck.Template, license string,
+	maxLines int) []string {
+
+	changes := []string{}
+	for _, l := range diffLines(splitSentences(templ.Text),
+		splitSentences(license)) {
+		if l.Op != ' ' {
+			changes = append(changes, string(l.Op)+" "+l.Text)
+		}
+	}
+	if len(changes) > maxLines {
+		omitted := len(changes) - maxLines
+		changes = append(changes[:maxLines],
+			fmt.Sprintf("... %d more", omitted))
+	}
+	return changes
+}
+
+// formatDiff returns the diff of the license file against its template, see
+// diffLicense, or nil if it cannot be computed. Licenses found in source file
+// headers and multi-licensed files are not diffed.
+func formatDiff(l License) []string {
+	if l.Template == nil || len(l.Parts) > 0 || l.AbsPath == "" ||
+		filepath.Ext(l.AbsPath) == ".go" {
+		return nil
+	}
+	data, err := licensecheck.ReadLicenseFile(l.AbsPath)
+	if err != nil {
+		return []string{fmt.Sprintf("cannot read license: %s", err)}
+	}
+	return diffLicense(l.Template, string(data), diffMaxLines)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pmezard/licenses/licensecheck"
+)
+
+func TestDiffLines(t *testing.T) {
+	lines := diffLines([]string{"a", "b", "c", "d"}, []string{"a", "c", "e", "d"})
+	got := []string{}
+	for _, l := range lines {
+		got = append(got, string(l.Op)+l.Text)
+	}
+	if s := strings.Join(got, ","); s != " a,-b, c,+e, d" {
+		t.Fatalf("unexpected diff: %s", s)
+	}
+}
+
+func TestDiffLicense(t *testing.T) {
+	m, err := licensecheck.NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mit *licensecheck.Template
+	for _, templ := range m.Templates() {
+		if templ.SPDX == "MIT" {
+			mit = templ
+		}
+	}
+	// Reflowed text with a modified sentence and an additional one.
+	paragraphs := strings.Split(mit.Text, "\n\n")
+	for i, p := range paragraphs {
+		paragraphs[i] = strings.Replace(p, "\n", " ", -1)
+	}
+	license := strings.Join(paragraphs, "\n\n")
+	license = strings.Replace(license, "free of charge", "for a small fee", 1)
+	license += "\nThis license is governed by the laws of Neverland.\n"
+	wanted := []string{
+		"- permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the \"software\"), to deal in the software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the software, and to permit persons to whom the software is furnished to do so, subject to the following conditions",
+		"+ permission is hereby granted, for a small fee, to any person obtaining a copy of this software and associated documentation files (the \"software\"), to deal in the software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the software, and to permit persons to whom the software is furnished to do so, subject to the following conditions",
+		"+ this license is governed by the laws of neverland",
+	}
+	got := diffLicense(mit, license, 10)
+	if strings.Join(got, "\n") != strings.Join(wanted, "\n") {
+		t.Fatalf("unexpected diff:\n%s\n!=\n%s", strings.Join(got, "\n"),
+			strings.Join(wanted, "\n"))
+	}
+	got = diffLicense(mit, license, 2)
+	if len(got) != 3 || got[2] != "... 1 more" {
+		t.Fatalf("diff is not truncated: %v", got)
+	}
+}
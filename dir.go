@@ -0,0 +1,149 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pmezard/licenses/licensecheck"
+)
+
+// skippedDirs lists directories not scanned by listDirLicenses by default.
+// They hold version control metadata, vendored JavaScript dependencies or test
+// fixtures, whose license files are not relevant and can be numerous.
+var skippedDirs = map[string]bool{
+	".git":         true,
+	".hg":          true,
+	"node_modules": true,
+	"testdata":     true,
+}
+
+// listDirLicenses walks the directory tree at root and matches the license
+// files of every directory, without relying on go tooling, so non-Go source
+// trees can be scanned. Licenses are reported per directory, the Package
+// field being the slash-separated directory path relative to root, "." for
+// root itself. Directories without license file are not reported, neither are
+// skippedDirs ones unless IncludeHidden is set. AbsPath fields are absolute
+// even if root is not. Only AllFiles, TemplateDirs, CopyrightRegexp,
+// IgnoreStopwords, CacheDir, Top, IncludeHidden and Concurrency options are
+// used.
+func listDirLicenses(root string, opts listOptions) ([]License, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	matcher, err := newMatcher(opts)
+	if err != nil {
+		return nil, err
+	}
+	match := matcher.Match
+	if opts.CacheDir != "" {
+		cache := openMatchCache(opts.CacheDir, matcher)
+		defer cache.Save()
+		match = cache.Match
+	}
+	licenses := []License{}
+	fpaths := []string{}
+	err = filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			return nil
+		}
+		if path != root && !opts.IncludeHidden && skippedDirs[fi.Name()] {
+			return filepath.SkipDir
+		}
+		fis, err := ioutil.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		files := []licenseFile{}
+		for _, fi := range fis {
+			if _, ok := regularFile(path, fi); !ok {
+				continue
+			}
+			if score := licensecheck.ScoreLicenseName(fi.Name()); score > 0 {
+				files = append(files, licenseFile{
+					Name:  fi.Name(),
+					Score: score,
+				})
+			}
+		}
+		if len(files) == 0 {
+			return nil
+		}
+		markVendoredFiles(path, files)
+		sortLicenseFiles(files)
+		if !opts.AllFiles {
+			files = files[:1]
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			fpath := filepath.Join(path, f.Name)
+			licenses = append(licenses, License{
+				Package:   filepath.ToSlash(rel),
+				Path:      filepath.ToSlash(filepath.Join(rel, f.Name)),
+				AbsPath:   fpath,
+				NameScore: f.Score,
+			})
+			fpaths = append(fpaths, fpath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	matched, stop := startFileMatches(fpaths, opts.Concurrency,
+		func(fpath string) fileMatch {
+			return matchLicenseFile(fpath, match, matcher, opts.Top)
+		})
+	defer stop()
+	for i := range licenses {
+		l := &licenses[i]
+		m := matched[l.AbsPath].Wait()
+		if m.Err != nil {
+			return nil, m.Err
+		}
+		l.MatchResult = m.MatchResult
+		l.Copyright = m.Copyright
+		l.Guesses = m.Guesses
+		l.Size = m.Size
+		l.Lines = m.Lines
+	}
+	return licenses, nil
+}
+
+// listFileLicense matches the license file at path, without relying on go
+// tooling nor the match cache, which helps debugging templates. The license is
+// reported with its slash-separated path as Package and Path. Only
+// TemplateDirs, CopyrightRegexp, IgnoreStopwords and Top options are used.
+func listFileLicense(path string, opts listOptions) ([]License, error) {
+	fpath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	matcher, err := newMatcher(opts)
+	if err != nil {
+		return nil, err
+	}
+	m := matchLicenseFile(fpath, matcher.Match, matcher, opts.Top)
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	path = filepath.ToSlash(filepath.Clean(path))
+	return []License{{
+		Package:     path,
+		MatchResult: m.MatchResult,
+		Path:        path,
+		AbsPath:     fpath,
+		NameScore:   licensecheck.ScoreLicenseName(filepath.Base(fpath)),
+		Copyright:   m.Copyright,
+		Guesses:     m.Guesses,
+		Size:        m.Size,
+		Lines:       m.Lines,
+	}}, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDirLicenses(t *testing.T) {
+	licenses, err := listDirLicenses(filepath.Join("testdata", "tree"), listOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, l := range licenses {
+		got = append(got, fmt.Sprintf("%s %s %s %d%%", l.Package, l.Path,
+			l.Template.SPDX, int(100*l.Score)))
+	}
+	wanted := []string{
+		". LICENSE MIT 98%",
+		"lib lib/COPYING Apache-2.0 100%",
+		"web web/LICENSE.md ISC 100%",
+	}
+	if strings.Join(got, "\n") != strings.Join(wanted, "\n") {
+		t.Fatalf("unexpected licenses:\n%s\n!=\n%s", strings.Join(got, "\n"),
+			strings.Join(wanted, "\n"))
+	}
+	for _, l := range licenses {
+		if !filepath.IsAbs(l.AbsPath) {
+			t.Fatalf("license path is not absolute: %s", l.AbsPath)
+		}
+		if _, err := os.Stat(l.AbsPath); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := listDirLicenses(filepath.Join("testdata", "missing"),
+		listOptions{}); err == nil {
+		t.Fatal("missing directory was scanned")
+	}
+}
+
+func TestDirLicensesSkipped(t *testing.T) {
+	mit, err := ioutil.ReadFile(filepath.Join("testdata", "tree", "LICENSE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	for _, dir := range []string{".", ".git", ".hg", "node_modules/pad", "testdata",
+		"src"} {
+		path := filepath.Join(root, filepath.FromSlash(dir))
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(path, "LICENSE"), mit, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list := func(opts listOptions) string {
+		licenses, err := listDirLicenses(root, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, l := range licenses {
+			got = append(got, l.Path)
+		}
+		return strings.Join(got, ",")
+	}
+	if got := list(listOptions{}); got != "LICENSE,src/LICENSE" {
+		t.Fatalf("unexpected licenses: %s", got)
+	}
+	wanted := "LICENSE,.git/LICENSE,.hg/LICENSE,node_modules/pad/LICENSE," +
+		"src/LICENSE,testdata/LICENSE"
+	if got := list(listOptions{IncludeHidden: true}); got != wanted {
+		t.Fatalf("unexpected licenses: %s != %s", got, wanted)
+	}
+	// Skipped directories can be scanned explicitly
+	licenses, err := listDirLicenses(filepath.Join(root, ".git"), listOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(licenses) != 1 || licenses[0].Path != "LICENSE" {
+		t.Fatalf("unexpected licenses: %+v", licenses)
+	}
+}
+
+func TestFileLicense(t *testing.T) {
+	path := filepath.Join("testdata", "src", "colors", "red", "LICENSE")
+	licenses, err := listFileLicense(path, listOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(licenses) != 1 {
+		t.Fatalf("unexpected licenses: %v", licenses)
+	}
+	l := licenses[0]
+	if l.Package != "testdata/src/colors/red/LICENSE" || l.Template == nil ||
+		l.Template.SPDX != "MIT" || int(100*l.Score) != 98 {
+		t.Fatalf("unexpected license: %s %v %f", l.Package, l.Template, l.Score)
+	}
+	if !filepath.IsAbs(l.AbsPath) || l.NameScore != 1 {
+		t.Fatalf("unexpected license file: %s %v", l.AbsPath, l.NameScore)
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	err = printLicenses([]string{"-w", "-file", path}, stdout, stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := `testdata/src/colors/red/LICENSE  MIT License [MIT] (98%)
+                                 ~words: mit, license
+                                 matched 91/93 template words (0 extra)
+                                 size: 1059 bytes, 19 lines
+`
+	if stdout.String() != wanted {
+		t.Fatalf("unexpected output:\n%s\n!=\n%s", stdout.String(), wanted)
+	}
+
+	if _, err := listFileLicense(filepath.Join("testdata", "missing"),
+		listOptions{}); err == nil {
+		t.Fatal("missing file was matched")
+	}
+	err = printLicenses([]string{"-file", path, "colors/red"}, stdout, stderr)
+	if err == nil {
+		t.Fatal("package arguments were accepted with -file")
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pmezard/licenses/licensecheck"
+)
+
+// exceptionDateFormat is the format of exceptions expiry dates.
+const exceptionDateFormat = "2006-01-02"
+
+// exception approves a package license violating the policy.
+type exception struct {
+	// Package is the import path of the approved package. Like overrides,
+	// exceptions apply to subpackages too.
+	Package string `json:"package"`
+	// License is the approved license name, see licensecheck.MatchNames.
+	License string `json:"license"`
+	// Note justifies the exception, like a ticket reference.
+	Note string `json:"note"`
+	// Expires is the date, formatted as exceptionDateFormat, from which the
+	// exception no longer applies. Exceptions without date never expire.
+	Expires string `json:"expires"`
+}
+
+// Expired returns true if the exception no longer applies at now.
+func (e *exception) Expired(now time.Time) bool {
+	if e.Expires == "" {
+		return false
+	}
+	expires, err := time.Parse(exceptionDateFormat, e.Expires)
+	return err != nil || !now.Before(expires)
+}
+
+// Matches returns true if the exception applies to l package and license,
+// whether it expired or not.
+func (e *exception) Matches(l License) bool {
+	if l.Package != e.Package && !strings.HasPrefix(l.Package, e.Package+"/") {
+		return false
+	}
+	parts := l.Parts
+	if len(parts) == 0 {
+		parts = []licensecheck.MatchResult{l.MatchResult}
+	}
+	for _, p := range parts {
+		if p.Template != nil && licensecheck.MatchNames(p.Template, []string{e.License}) {
+			return true
+		}
+	}
+	return false
+}
+
+// exceptions lists approved package licenses, see loadExceptions.
+type exceptions []*exception
+
+// parseExceptionsYAML parses YAML exceptions files, made of a sequence of
+// flat mappings of exception fields, like a "- package: github.com/foo/bar"
+// line followed by "  license: GPL-3.0" and "  note: see TICKET-123" ones.
+// Comments and quoted values are supported, nested structures are not.
+func parseExceptionsYAML(data []byte) (exceptions, error) {
+	fields, err := parseYAMLFields(data)
+	if err != nil {
+		return nil, err
+	}
+	excs := exceptions{}
+	for _, f := range fields {
+		if f.Item {
+			excs = append(excs, &exception{})
+		}
+		if len(excs) == 0 {
+			return nil, fmt.Errorf("expected \"- key: value\" at line %d", f.Line)
+		}
+		e := excs[len(excs)-1]
+		switch f.Key {
+		case "package":
+			e.Package = f.Value
+		case "license":
+			e.License = f.Value
+		case "note":
+			e.Note = f.Value
+		case "expires":
+			e.Expires = f.Value
+		default:
+			return nil, fmt.Errorf("unknown exception key %q at line %d", f.Key, f.Line)
+		}
+	}
+	return excs, nil
+}
+
+// loadExceptions reads an exceptions file, either as a JSON array of objects
+// or a YAML sequence for .yaml and .yml files, see parseExceptionsYAML.
+// Exceptions must have a package and a license referring to a template of
+// matcher, and their expiry date must be valid.
+func loadExceptions(path string, matcher *licensecheck.Matcher) (exceptions, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	excs := exceptions{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		excs, err = parseExceptionsYAML(data)
+	default:
+		err = json.Unmarshal(data, &excs)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %s", path, err)
+	}
+	for i, e := range excs {
+		e.Package = strings.TrimSuffix(e.Package, "/")
+		if e.Package == "" || e.License == "" {
+			return nil, fmt.Errorf("exception %d has no package or license in %s", i+1,
+				path)
+		}
+		if licensecheck.FindTemplate(matcher.Templates(), e.License) == nil {
+			return nil, fmt.Errorf("unknown license %q for %s in %s", e.License,
+				e.Package, path)
+		}
+		if e.Expires != "" {
+			if _, err := time.Parse(exceptionDateFormat, e.Expires); err != nil {
+				return nil, fmt.Errorf("invalid expiry date %q for %s in %s", e.Expires,
+					e.Package, path)
+			}
+		}
+	}
+	return excs, nil
+}
+
+// excepted is a policy violation matching an exception.
+type excepted struct {
+	License   License
+	Exception *exception
+}
+
+// Filter returns the violations not approved by an active exception, the ones
+// approved, and the ones whose exception expired at now. Expired exceptions
+// are ignored when another one applies.
+func (excs exceptions) Filter(violations []License, now time.Time) ([]License,
+	[]excepted, []excepted) {
+
+	kept := []License{}
+	approved := []excepted{}
+	expired := []excepted{}
+	for _, l := range violations {
+		var active, outdated *exception
+		for _, e := range excs {
+			if !e.Matches(l) {
+				continue
+			}
+			if e.Expired(now) {
+				outdated = e
+			} else {
+				active = e
+			}
+		}
+		switch {
+		case active != nil:
+			approved = append(approved, excepted{License: l, Exception: active})
+		case outdated != nil:
+			kept = append(kept, l)
+			expired = append(expired, excepted{License: l, Exception: outdated})
+		default:
+			kept = append(kept, l)
+		}
+	}
+	return kept, approved, expired
+}
+
+// writeExceptions summarizes the violations approved by exceptions, with
+// their justification, and the ones whose exception expired.
+func writeExceptions(w io.Writer, approved, expired []excepted) error {
+	format := func(x excepted) string {
+		s := fmt.Sprintf("  %s: %s", x.License.Package, x.Exception.License)
+		if x.Exception.Note != "" {
+			s += ", " + x.Exception.Note
+		}
+		if x.Exception.Expires != "" {
+			s += " (expires " + x.Exception.Expires + ")"
+		}
+		return s
+	}
+	lines := []string{}
+	if len(approved) > 0 {
+		lines = append(lines, fmt.Sprintf(
+			"%d policy violations approved by exceptions:", len(approved)))
+		for _, x := range approved {
+			lines = append(lines, format(x))
+		}
+	}
+	if len(expired) > 0 {
+		lines = append(lines, fmt.Sprintf(
+			"%d policy violations have expired exceptions:", len(expired)))
+		for _, x := range expired {
+			lines = append(lines, format(x))
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExceptions(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "exceptions.json")
+	err := ioutil.WriteFile(jsonPath, []byte(`[
+	{"package": "colors/red", "license": "MIT", "note": "TICKET-1",
+		"expires": "2030-01-01"},
+	{"package": "colors/black", "license": "Apache-2.0", "expires": "2020-01-01"},
+	{"package": "colors/yellow", "license": "GPL-3.0"}
+]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	yamlPath := filepath.Join(dir, "exceptions.yaml")
+	err = ioutil.WriteFile(yamlPath, []byte(`# Approved licenses
+- package: colors/red
+  license: MIT
+  note: "TICKET-1"
+  expires: 2030-01-01 # Yearly review
+- package: colors/black/
+  license: Apache-2.0
+  expires: 2020-01-01
+- package: colors/yellow
+  license: GPL-3.0
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matcher, err := newMatcher(listOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	licenses, err := listTestdataLicenses([]string{"colors/black", "colors/green",
+		"colors/red"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &policy{Deny: splitNames("MIT,Apache-2.0")}
+	violations := p.check(licenses, 0.9)
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	for _, path := range []string{jsonPath, yamlPath} {
+		excs, err := loadExceptions(path, matcher)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// colors/red exception is active, colors/black one expired and
+		// colors/yellow one does not apply.
+		kept, approved, expired := excs.Filter(violations, now)
+		if len(kept) != 1 || kept[0].Package != "colors/black" {
+			t.Fatalf("unexpected violations: %+v", kept)
+		}
+		buf := &bytes.Buffer{}
+		err = writeExceptions(buf, approved, expired)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wanted := `1 policy violations approved by exceptions:
+  colors/red: MIT, TICKET-1 (expires 2030-01-01)
+1 policy violations have expired exceptions:
+  colors/black: Apache-2.0 (expires 2020-01-01)
+`
+		if buf.String() != wanted {
+			t.Fatalf("unexpected summary:\n%s\n!=\n%s", buf.String(), wanted)
+		}
+		// Exceptions expire on their date
+		kept, _, _ = excs.Filter(violations, time.Date(2030, 1, 1, 0, 0, 0, 0,
+			time.UTC))
+		if len(kept) != 2 {
+			t.Fatalf("unexpected violations: %+v", kept)
+		}
+	}
+
+	for _, content := range []string{
+		`[{"package": "colors/red"}]`,
+		`[{"package": "colors/red", "license": "unknown"}]`,
+		`[{"package": "colors/red", "license": "MIT", "expires": "01/02/2030"}]`,
+	} {
+		err := ioutil.WriteFile(jsonPath, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadExceptions(jsonPath, matcher); err == nil {
+			t.Fatalf("invalid exceptions were accepted: %s", content)
+		}
+	}
+	err = ioutil.WriteFile(yamlPath, []byte("- package: colors/red\n  owner: me\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadExceptions(yamlPath, matcher); err == nil {
+		t.Fatal("unknown key was accepted")
+	}
+}
+
+func TestExceptionsFlag(t *testing.T) {
+	gopath, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOPATH", gopath)
+	t.Setenv("GO111MODULE", "off")
+	path := filepath.Join(t.TempDir(), "exceptions.json")
+	err = ioutil.WriteFile(path, []byte(`[
+	{"package": "colors/red", "license": "MIT", "note": "TICKET-1"}
+]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	err = printLicenses([]string{"-no-cache", "-deny", "MIT", "-exceptions", path,
+		"colors/red"}, stdout, stderr)
+	if err != nil {
+		t.Fatalf("approved violation failed: %s", err)
+	}
+	wanted := "1 policy violations approved by exceptions:\n" +
+		"  colors/red: MIT, TICKET-1\n"
+	if stderr.String() != wanted {
+		t.Fatalf("unexpected summary:\n%s\n!=\n%s", stderr.String(), wanted)
+	}
+	err = printLicenses([]string{"-no-cache", "-deny", "MIT,Apache-2.0", "-exceptions",
+		path, "colors/red", "colors/black"}, stdout, stderr)
+	if _, ok := err.(*PolicyError); !ok {
+		t.Fatalf("violation without exception did not fail: %v", err)
+	}
+}
+
+func TestParseExceptionsYAMLComments(t *testing.T) {
+	excs, err := parseExceptionsYAML([]byte(`- package: colors/red # Red
+  license: MIT
+  note: "see issue #12" # Quoted
+- package: colors/black
+  license: Apache-2.0
+  note: 'TICKET-1 #2'
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(excs) != 2 {
+		t.Fatalf("unexpected exceptions count: %d", len(excs))
+	}
+	if excs[0].Package != "colors/red" || excs[0].Note != "see issue #12" ||
+		excs[1].Note != "TICKET-1 #2" {
+		t.Fatalf("unexpected exceptions: %+v, %+v", excs[0], excs[1])
+	}
+}
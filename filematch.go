@@ -0,0 +1,94 @@
+package main
+
+import (
+	"github.com/pmezard/licenses/licensecheck"
+)
+
+// fileMatch is the match result of a license file, shared by the packages it
+// applies to.
+type fileMatch struct {
+	licensecheck.MatchResult
+	Copyright []string
+	Guesses   []licensecheck.MatchResult
+	Size      int
+	Lines     int
+	// Err is the error reading the file, if any.
+	Err error
+}
+
+// matchLicenseFile reads and matches the license file at fpath with match. If
+// top is positive, the top best matching templates of matcher are reported in
+// Guesses.
+func matchLicenseFile(fpath string, match func([]byte) licensecheck.MatchResult,
+	matcher *licensecheck.Matcher, top int) fileMatch {
+
+	data, err := licensecheck.ReadLicenseFile(fpath)
+	if err != nil {
+		return fileMatch{Err: err}
+	}
+	m := fileMatch{
+		MatchResult: match(data),
+		Copyright:   licensecheck.ExtractCopyrights(data),
+		Size:        len(data),
+		Lines:       countLines(data),
+	}
+	if top > 0 {
+		m.Guesses = matcher.MatchN(data, top)
+	}
+	return m
+}
+
+// pendingMatch is a license file match computed by startFileMatches workers.
+type pendingMatch struct {
+	m    fileMatch
+	done chan struct{}
+}
+
+// Wait blocks until the match is computed and returns it.
+func (p *pendingMatch) Wait() fileMatch {
+	<-p.done
+	return p.m
+}
+
+// startFileMatches matches the distinct license files of fpaths with
+// matchFile, in at most concurrency goroutines, one if it is not positive.
+// Files are started in fpaths order, so results can be waited for in that
+// order while later ones are computed. It returns the pending matches by file
+// path, and a function stopping the workers, after which remaining matches
+// never complete.
+func startFileMatches(fpaths []string, concurrency int,
+	matchFile func(string) fileMatch) (map[string]*pendingMatch, func()) {
+
+	pending := map[string]*pendingMatch{}
+	queue := make(chan string, len(fpaths))
+	for _, fpath := range fpaths {
+		if _, ok := pending[fpath]; ok {
+			continue
+		}
+		pending[fpath] = &pendingMatch{done: make(chan struct{})}
+		queue <- fpath
+	}
+	close(queue)
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(pending) {
+		concurrency = len(pending)
+	}
+	stop := make(chan struct{})
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			for fpath := range queue {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				p := pending[fpath]
+				p.m = matchFile(fpath)
+				close(p.done)
+			}
+		}()
+	}
+	return pending, func() { close(stop) }
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStartFileMatches(t *testing.T) {
+	var lock sync.Mutex
+	running, maxRunning := 0, 0
+	calls := map[string]int{}
+	matchFile := func(fpath string) fileMatch {
+		lock.Lock()
+		calls[fpath]++
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		lock.Lock()
+		running--
+		lock.Unlock()
+		return fileMatch{Size: len(fpath)}
+	}
+	fpaths := []string{"a", "bb", "a", "ccc", "dddd", "eeeee"}
+	matched, stop := startFileMatches(fpaths, 2, matchFile)
+	defer stop()
+	for _, fpath := range fpaths {
+		if m := matched[fpath].Wait(); m.Size != len(fpath) {
+			t.Fatalf("unexpected %s match: %+v", fpath, m)
+		}
+	}
+	wanted := map[string]int{"a": 1, "bb": 1, "ccc": 1, "dddd": 1, "eeeee": 1}
+	if !reflect.DeepEqual(calls, wanted) {
+		t.Fatalf("files not matched once: %v", calls)
+	}
+	if maxRunning != 2 {
+		t.Fatalf("unexpected concurrent matches: %d", maxRunning)
+	}
+}
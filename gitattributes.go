@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// vendoredAttributes lists .gitattributes attributes marking files as not
+// belonging to the project, like embedded third-party code.
+var vendoredAttributes = map[string]bool{
+	"export-ignore":      true,
+	"linguist-vendored":  true,
+	"linguist-generated": true,
+}
+
+// matchGitPattern returns true if the .gitattributes or .gitignore pattern
+// matches the file name, in the directory of the pattern file. Patterns with
+// slashes other than a leading one only match files of subdirectories, and
+// never match here.
+func matchGitPattern(pattern, name string) bool {
+	pattern = strings.TrimPrefix(pattern, "/")
+	if strings.Contains(pattern, "/") {
+		return false
+	}
+	ok, err := filepath.Match(pattern, name)
+	return err == nil && ok
+}
+
+// parseVendoredFiles returns the files of names marked as vendored by the
+// .gitattributes data, see vendoredAttributes. Later lines override earlier
+// ones, and attributes can be unset with "-attr" or "attr=false".
+func parseVendoredFiles(data []byte, names []string) map[string]bool {
+	vendored := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for _, attr := range fields[1:] {
+			set := true
+			if strings.HasPrefix(attr, "-") || strings.HasPrefix(attr, "!") {
+				attr, set = attr[1:], false
+			} else if i := strings.Index(attr, "="); i >= 0 {
+				attr, set = attr[:i], attr[i+1:] != "false"
+			}
+			if !vendoredAttributes[attr] {
+				continue
+			}
+			for _, name := range names {
+				if matchGitPattern(fields[0], name) {
+					vendored[name] = set
+				}
+			}
+		}
+	}
+	return vendored
+}
+
+// parseIgnoredFiles returns the files of names ignored by the .gitignore
+// data. Negated patterns, starting with "!", include files back.
+func parseIgnoredFiles(data []byte, names []string) map[string]bool {
+	ignored := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		pattern := strings.TrimSpace(scanner.Text())
+		if pattern == "" || strings.HasPrefix(pattern, "#") ||
+			strings.HasSuffix(pattern, "/") {
+			continue
+		}
+		set := true
+		if strings.HasPrefix(pattern, "!") {
+			pattern, set = pattern[1:], false
+		}
+		for _, name := range names {
+			if matchGitPattern(pattern, name) {
+				ignored[name] = set
+			}
+		}
+	}
+	return ignored
+}
+
+// markVendoredFiles sets the Vendored field of the license files of dir
+// marked as vendored or generated by the .gitattributes file of dir, or
+// ignored by its .gitignore file. They are likely embedded third-party
+// licenses, not the license of the directory. Unreadable files are ignored.
+func markVendoredFiles(dir string, files []licenseFile) {
+	names := []string{}
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	vendored := map[string]bool{}
+	if data, err := ioutil.ReadFile(filepath.Join(dir, ".gitattributes")); err == nil {
+		vendored = parseVendoredFiles(data, names)
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(dir, ".gitignore")); err == nil {
+		for name, ignored := range parseIgnoredFiles(data, names) {
+			vendored[name] = vendored[name] || ignored
+		}
+	}
+	for i := range files {
+		files[i].Vendored = vendored[files[i].Name]
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseVendoredFiles(t *testing.T) {
+	names := []string{"LICENSE", "COPYING", "LICENSE.md", "NOTICE"}
+	data := []byte(`# comment
+LICENSE* linguist-vendored
+LICENSE.md -linguist-vendored
+/COPYING export-ignore
+third_party/NOTICE linguist-generated
+NOTICE linguist-generated=false text
+`)
+	got := parseVendoredFiles(data, names)
+	wanted := map[string]bool{
+		"LICENSE":    true,
+		"LICENSE.md": false,
+		"COPYING":    true,
+		"NOTICE":     false,
+	}
+	for _, name := range names {
+		if got[name] != wanted[name] {
+			t.Errorf("unexpected vendored status for %s: %v", name, got[name])
+		}
+	}
+
+	ignored := parseIgnoredFiles([]byte("LICENSE*\n!LICENSE.md\nCOPYING/\n"), names)
+	if !ignored["LICENSE"] || ignored["LICENSE.md"] || ignored["COPYING"] {
+		t.Errorf("unexpected ignored files: %v", ignored)
+	}
+}
+
+func TestMarkVendoredFiles(t *testing.T) {
+	dir := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(dir, ".gitattributes"),
+		[]byte("LICENSE export-ignore\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, ".gitignore"), []byte("LICENSE.md\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []licenseFile{
+		{Name: "LICENSE", Score: 1},
+		{Name: "LICENSE.md", Score: 0.9},
+		{Name: "COPYING", Score: 0.8},
+	}
+	markVendoredFiles(dir, files)
+	sortLicenseFiles(files)
+	if files[0].Name != "COPYING" || !files[1].Vendored || !files[2].Vendored {
+		t.Fatalf("vendored files were not deprioritized: %+v", files)
+	}
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pmezard/licenses/licensecheck"
+)
+
+// modVersion identifies a module version required by a go.mod file.
+type modVersion struct {
+	Path    string
+	Version string
+}
+
+func (m modVersion) String() string {
+	return m.Path + "@" + m.Version
+}
+
+// unquoteModField returns a go.mod field without its double or back quotes.
+func unquoteModField(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '`') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// parseGoModRequires returns the modules required by go.mod data, in order of
+// appearance. Both single line and block require directives are supported.
+func parseGoModRequires(data []byte) ([]modVersion, error) {
+	mods := []modVersion{}
+	inBlock := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if inBlock {
+			if fields[0] == ")" {
+				inBlock = false
+				continue
+			}
+		} else {
+			if fields[0] != "require" {
+				continue
+			}
+			fields = fields[1:]
+			if len(fields) == 1 && fields[0] == "(" {
+				inBlock = true
+				continue
+			}
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid require directive at line %d: %s",
+				n, strings.TrimSpace(line))
+		}
+		mods = append(mods, modVersion{
+			Path:    unquoteModField(fields[0]),
+			Version: unquoteModField(fields[1]),
+		})
+	}
+	return mods, scanner.Err()
+}
+
+// escapeModulePath escapes module paths and versions for module proxy
+// requests, uppercase letters being replaced with "!" followed by their
+// lowercase version.
+func escapeModulePath(s string) string {
+	buf := &bytes.Buffer{}
+	for _, r := range s {
+		if unicode.IsUpper(r) {
+			buf.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+// moduleEnv holds the go environment variables configuring module downloads.
+type moduleEnv struct {
+	GOPROXY   string
+	GOPRIVATE string
+	GONOPROXY string
+}
+
+// readModuleEnv returns the module download settings resolved by "go env", so
+// the ones written with "go env -w" apply like environment variables.
+func readModuleEnv(ctx context.Context) (moduleEnv, error) {
+	cmd := goCommand(ctx, goEnv{}, "env", "-json", "GOPROXY", "GOPRIVATE",
+		"GONOPROXY")
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return moduleEnv{}, fmt.Errorf("'go env' failed: %s\n%s", err,
+			stderr.String())
+	}
+	menv := moduleEnv{}
+	if err := json.Unmarshal(out, &menv); err != nil {
+		return moduleEnv{}, fmt.Errorf("could not decode 'go env' output: %s", err)
+	}
+	return menv, nil
+}
+
+// matchModulePatterns returns true if module path matches any of the GOPRIVATE
+// style glob patterns. Patterns match path prefixes with the same number of
+// components.
+func matchModulePatterns(patterns []string, mod string) bool {
+	for _, p := range patterns {
+		n := strings.Count(p, "/") + 1
+		parts := strings.SplitN(mod, "/", n+1)
+		if len(parts) < n {
+			continue
+		}
+		prefix := strings.Join(parts[:n], "/")
+		if ok, err := path.Match(p, prefix); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// maxModuleZipSize is the maximum size of module zip archives, in bytes, the
+// same as the go command one.
+const maxModuleZipSize = 500 << 20
+
+// moduleProxy is a GOPROXY entry.
+type moduleProxy struct {
+	URL string
+	// FallBack is true if the proxy is followed by "|" and the next one is
+	// tried on any error. Proxies followed by "," fall back only on 404 and
+	// 410 responses.
+	FallBack bool
+}
+
+// moduleProxies returns the module proxies listed in GOPROXY value, defaulting
+// to proxy.golang.org. "direct" entries are ignored, as sources are not
+// fetched from version control systems. It fails if GOPROXY is "off".
+func moduleProxies(value string) ([]moduleProxy, error) {
+	if value == "" {
+		value = "https://proxy.golang.org"
+	}
+	proxies := []moduleProxy{}
+	for rest := value; rest != ""; {
+		u, sep := rest, ""
+		if i := strings.IndexAny(rest, ",|"); i >= 0 {
+			u, sep, rest = rest[:i], rest[i:i+1], rest[i+1:]
+		} else {
+			rest = ""
+		}
+		u = strings.TrimSpace(u)
+		switch u {
+		case "off":
+			return nil, fmt.Errorf("module downloads are disabled by GOPROXY=off")
+		case "direct", "":
+			continue
+		}
+		proxies = append(proxies, moduleProxy{
+			URL:      strings.TrimRight(u, "/"),
+			FallBack: sep == "|",
+		})
+	}
+	if len(proxies) == 0 {
+		return nil, fmt.Errorf("no module proxy in GOPROXY=%s", value)
+	}
+	return proxies, nil
+}
+
+// newProxyClient returns the HTTP client downloading module archives. Requests
+// taking longer than timeout, if positive, fail, stalled response bodies
+// included.
+func newProxyClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout}
+}
+
+// fetchModuleZip downloads the zip archive of supplied module from the first
+// proxy providing it. Like the go command, the next proxy is tried when one
+// does not have the module, or on any error if they are separated by "|".
+func fetchModuleZip(client *http.Client, proxies []moduleProxy, mod modVersion) (
+	[]byte, error) {
+
+	var lastErr error
+	for _, proxy := range proxies {
+		url := fmt.Sprintf("%s/%s/@v/%s.zip", proxy.URL, escapeModulePath(mod.Path),
+			escapeModulePath(mod.Version))
+		data, notFound, err := fetchProxyFile(client, url)
+		if err == nil {
+			return data, nil
+		}
+		if !notFound && !proxy.FallBack {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// fetchProxyFile downloads the module proxy file at url, up to
+// maxModuleZipSize bytes. It returns true with the error if the proxy does not
+// have the file.
+func fetchProxyFile(client *http.Client, url string) ([]byte, bool, error) {
+	rsp, err := client.Get(url)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		notFound := rsp.StatusCode == http.StatusNotFound ||
+			rsp.StatusCode == http.StatusGone
+		return nil, notFound, fmt.Errorf("cannot download %s: %s", url, rsp.Status)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(rsp.Body, maxModuleZipSize+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(data) > maxModuleZipSize {
+		return nil, false, fmt.Errorf("%s is larger than %d bytes", url,
+			maxModuleZipSize)
+	}
+	return data, false, nil
+}
+
+// hashModuleZip returns the "h1:" hash of a module zip archive, as recorded in
+// go.sum files.
+func hashModuleZip(data []byte) (string, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	files := map[string]*zip.File{}
+	names := []string{}
+	for _, f := range r.File {
+		files[f.Name] = f
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+	h := sha256.New()
+	for _, name := range names {
+		rc, err := files[name].Open()
+		if err != nil {
+			return "", err
+		}
+		fh := sha256.New()
+		_, err = io.Copy(fh, rc)
+		rc.Close()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%x  %s\n", fh.Sum(nil), name)
+	}
+	return "h1:" + base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// parseGoSum returns the module zip hashes of go.sum data, keyed by
+// module@version.
+func parseGoSum(data []byte) map[string]string {
+	hashes := map[string]string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") {
+			continue
+		}
+		hashes[fields[0]+"@"+fields[1]] = fields[2]
+	}
+	return hashes
+}
+
+// zipLicense returns the name and content of the best named license file at
+// the root of a module zip archive, or an empty name if there is none.
+func zipLicense(data []byte, mod modVersion) (string, []byte, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", nil, err
+	}
+	prefix := mod.String() + "/"
+	var best *zip.File
+	bestScore := 0.
+	for _, f := range r.File {
+		if !strings.HasPrefix(f.Name, prefix) {
+			continue
+		}
+		name := f.Name[len(prefix):]
+		if strings.Contains(name, "/") {
+			continue
+		}
+		score := licensecheck.ScoreLicenseName(name)
+		if score > bestScore || (score == bestScore && best != nil &&
+			f.Name < best.Name) {
+			best, bestScore = f, score
+		}
+	}
+	if best == nil || bestScore <= 0 {
+		return "", nil, nil
+	}
+	rc, err := best.Open()
+	if err != nil {
+		return "", nil, err
+	}
+	defer rc.Close()
+	content, err := licensecheck.ReadLicenseData(rc)
+	if err != nil {
+		return "", nil, err
+	}
+	return best.Name[len(prefix):], licensecheck.StripMarkup(best.Name, content), nil
+}
+
+// listGoModLicenses returns the licenses of the modules required by the
+// go.mod file at gomod, read from the module zip archives served by GOPROXY
+// instead of a local checkout. Modules matching GOPRIVATE or GONOPROXY are
+// reported as errors. These variables are resolved by "go env", see
+// readModuleEnv. Archives are checked against the go.sum file next to go.mod,
+// which must list every fetched module, unless GONOSUMCHECK is set to 1.
+// License files are extracted in dir so they can be read again, by
+// writeNotice for instance.
+func listGoModLicenses(ctx context.Context, gomod, dir string,
+	matcher *licensecheck.Matcher, client *http.Client) ([]License, error) {
+
+	data, err := ioutil.ReadFile(gomod)
+	if err != nil {
+		return nil, err
+	}
+	mods, err := parseGoModRequires(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %s", gomod, err)
+	}
+	menv, err := readModuleEnv(ctx)
+	if err != nil {
+		return nil, err
+	}
+	proxies, err := moduleProxies(menv.GOPROXY)
+	if err != nil {
+		return nil, err
+	}
+	verify := os.Getenv("GONOSUMCHECK") != "1"
+	sums := map[string]string{}
+	if verify {
+		data, err := ioutil.ReadFile(filepath.Join(filepath.Dir(gomod), "go.sum"))
+		if err != nil {
+			return nil, fmt.Errorf("cannot verify module archives, set GONOSUMCHECK=1 "+
+				"to skip verification: %s", err)
+		}
+		sums = parseGoSum(data)
+	}
+	private := append(splitNames(menv.GOPRIVATE), splitNames(menv.GONOPROXY)...)
+	licenses := []License{}
+	for _, mod := range mods {
+		if matchModulePatterns(private, mod.Path) {
+			licenses = append(licenses, License{
+				Package:     mod.Path,
+				Err:         "private module, not fetched from proxy",
+				ErrCategory: ErrOther,
+			})
+			continue
+		}
+		zipData, err := fetchModuleZip(client, proxies, mod)
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch %s: %s", mod, err)
+		}
+		if verify {
+			sum, ok := sums[mod.String()]
+			if !ok {
+				return nil, fmt.Errorf("missing go.sum entry for %s", mod)
+			}
+			hash, err := hashModuleZip(zipData)
+			if err != nil {
+				return nil, fmt.Errorf("could not hash %s: %s", mod, err)
+			}
+			if hash != sum {
+				return nil, fmt.Errorf("checksum mismatch for %s: %s != %s",
+					mod, hash, sum)
+			}
+		}
+		name, content, err := zipLicense(zipData, mod)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s archive: %s", mod, err)
+		}
+		if name == "" {
+			licenses = append(licenses, License{
+				Package: mod.Path,
+				Version: mod.Version,
+			})
+			continue
+		}
+		fpath := filepath.Join(dir, escapeModulePath(mod.String()), name)
+		err = os.MkdirAll(filepath.Dir(fpath), 0755)
+		if err == nil {
+			err = ioutil.WriteFile(fpath, content, 0644)
+		}
+		if err != nil {
+			return nil, err
+		}
+		licenses = append(licenses, License{
+			Package:     mod.Path,
+			MatchResult: matcher.Match(content),
+			Path:        mod.String() + "/" + name,
+			Version:     mod.Version,
+			AbsPath:     fpath,
+			NameScore:   licensecheck.ScoreLicenseName(name),
+			Copyright:   licensecheck.ExtractCopyrights(content),
+			Size:        len(content),
+			Lines:       countLines(content),
+		})
+	}
+	return licenses, nil
+}
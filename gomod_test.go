@@ -0,0 +1,313 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pmezard/licenses/licensecheck"
+)
+
+func TestParseGoModRequires(t *testing.T) {
+	data := []byte(`module example.com/app
+
+go 1.16
+
+require github.com/a/b v1.2.3 // indirect
+require (
+	// A comment
+	github.com/c/d v0.1.0
+	"github.com/e/f" v2.0.0+incompatible
+)
+
+replace github.com/a/b => ../b
+`)
+	mods, err := parseGoModRequires(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, m := range mods {
+		got = append(got, m.String())
+	}
+	wanted := "github.com/a/b@v1.2.3,github.com/c/d@v0.1.0," +
+		"github.com/e/f@v2.0.0+incompatible"
+	if strings.Join(got, ",") != wanted {
+		t.Fatalf("unexpected requirements: %s != %s", strings.Join(got, ","), wanted)
+	}
+	_, err = parseGoModRequires([]byte("require github.com/a/b\n"))
+	if err == nil {
+		t.Fatal("invalid require directive was accepted")
+	}
+}
+
+func TestMatchModulePatterns(t *testing.T) {
+	patterns := []string{"*.corp.example.com", "github.com/acme"}
+	tests := []struct {
+		Path  string
+		Match bool
+	}{
+		{"git.corp.example.com/x/y", true},
+		{"github.com/acme/tools", true},
+		{"github.com/acme", true},
+		{"github.com/other/tools", false},
+		{"corp.example.com/x", false},
+	}
+	for _, test := range tests {
+		if matchModulePatterns(patterns, test.Path) != test.Match {
+			t.Errorf("%s matching should be %v", test.Path, test.Match)
+		}
+	}
+}
+
+// makeModuleZip returns a module zip archive with supplied files, relative to
+// the module root.
+func makeModuleZip(t *testing.T, mod string, files map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+	for name, content := range files {
+		f, err := w.Create(mod + "/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// cpZipHash is the go.sum hash of testdata/gomod/cp-v0.1.0.zip, the
+// github.com/cespare/cp v0.1.0 module archive served by proxy.golang.org.
+const cpZipHash = "h1:SE+dxFebS7Iik5LK0tsi1k9ZCxEaFX4AjQmoyA+1dJk="
+
+func TestHashModuleZip(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "gomod", "cp-v0.1.0.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := hashModuleZip(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != cpZipHash {
+		t.Fatalf("unexpected hash: %s != %s", hash, cpZipHash)
+	}
+}
+
+func TestFetchModuleZip(t *testing.T) {
+	handler := func(code int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if code != http.StatusOK {
+					http.Error(w, http.StatusText(code), code)
+					return
+				}
+				w.Write([]byte("zip"))
+			}))
+	}
+	ok := handler(http.StatusOK)
+	defer ok.Close()
+	missing := handler(http.StatusGone)
+	defer missing.Close()
+	broken := handler(http.StatusBadGateway)
+	defer broken.Close()
+
+	tests := []struct {
+		GoProxy string
+		Fetched bool
+	}{
+		{ok.URL, true},
+		{missing.URL + "," + ok.URL, true},
+		{broken.URL + "|" + ok.URL, true},
+		{broken.URL + "," + ok.URL, false},
+		{"direct," + broken.URL + "|direct|" + ok.URL, true},
+		{missing.URL + ",direct", false},
+	}
+	mod := modVersion{Path: "example.com/mod", Version: "v1.0.0"}
+	for _, test := range tests {
+		proxies, err := moduleProxies(test.GoProxy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := fetchModuleZip(ok.Client(), proxies, mod)
+		if test.Fetched && (err != nil || string(data) != "zip") {
+			t.Errorf("could not fetch module with %s: %v", test.GoProxy, err)
+		} else if !test.Fetched && err == nil {
+			t.Errorf("module was fetched with %s", test.GoProxy)
+		}
+	}
+}
+
+func TestProxyClientTimeout(t *testing.T) {
+	stalled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("zip"))
+			w.(http.Flusher).Flush()
+			<-stalled
+		}))
+	defer server.Close()
+	defer close(stalled)
+
+	client := newProxyClient(100 * time.Millisecond)
+	_, _, err := fetchProxyFile(client, server.URL+"/example.com/mod/@v/v1.0.0.zip")
+	if err == nil {
+		t.Fatal("stalled download did not time out")
+	}
+}
+
+func TestGoModLicenses(t *testing.T) {
+	cp, err := ioutil.ReadFile(filepath.Join("testdata", "gomod", "cp-v0.1.0.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mit, err := ioutil.ReadFile(filepath.Join("testdata", "src", "colors", "red",
+		"LICENSE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zips := map[string][]byte{
+		"/github.com/cespare/cp/@v/v0.1.0.zip": cp,
+		"/github.com/!acme/red/@v/v1.0.0.zip": makeModuleZip(t,
+			"github.com/Acme/red@v1.0.0", map[string]string{
+				"LICENSE":     string(mit),
+				"red.go":      "package red\n",
+				"sub/COPYING": "Some other license",
+			}),
+		"/example.com/bare/@v/v0.1.0.zip": makeModuleZip(t,
+			"example.com/bare@v0.1.0", map[string]string{
+				"bare.go": "package bare\n",
+			}),
+	}
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.URL.Path)
+			data, ok := zips[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(data)
+		}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	gomod := filepath.Join(dir, "go.mod")
+	writeGoMod := func(requires ...string) {
+		err := ioutil.WriteFile(gomod, []byte("module example.com/app\n\nrequire (\n\t"+
+			strings.Join(requires, "\n\t")+"\n)\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeGoSum := func(content string) {
+		err := ioutil.WriteFile(filepath.Join(dir, "go.sum"), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+	writeGoMod("github.com/cespare/cp v0.1.0", "private.example.com/secret v1.0.0")
+	writeGoSum("github.com/cespare/cp v0.1.0 " + cpZipHash + "\n" +
+		"github.com/cespare/cp v0.1.0/go.mod h1:xxx=\n")
+	t.Setenv("GOPROXY", "off")
+	m, err := licensecheck.NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listGoModLicenses(ctx, gomod, t.TempDir(), m, server.Client()); err == nil {
+		t.Fatal("modules downloaded with GOPROXY=off")
+	}
+
+	// Settings written with "go env -w" apply too
+	goenv := filepath.Join(t.TempDir(), "env")
+	err = ioutil.WriteFile(goenv, []byte("GOPRIVATE=private.example.com\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOENV", goenv)
+	t.Setenv("GOPROXY", server.URL+",direct")
+	t.Setenv("GOPRIVATE", "")
+	t.Setenv("GONOPROXY", "")
+	t.Setenv("GONOSUMCHECK", "")
+	formatLicenses := func(licenses []License) string {
+		got := []string{}
+		for _, l := range licenses {
+			s := l.Package + " " + l.Path
+			if l.Template != nil {
+				s += " " + l.Template.SPDX
+			}
+			if l.Err != "" {
+				s += " error"
+			}
+			got = append(got, s)
+		}
+		return strings.Join(got, "\n")
+	}
+	licenses, err := listGoModLicenses(ctx, gomod, t.TempDir(), m, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := strings.Join([]string{
+		"github.com/cespare/cp github.com/cespare/cp@v0.1.0/LICENSE.txt MIT",
+		"private.example.com/secret  error",
+	}, "\n")
+	if got := formatLicenses(licenses); got != wanted {
+		t.Fatalf("unexpected licenses:\n%s\n!=\n%s", got, wanted)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("unexpected proxy requests: %v", requests)
+	}
+	data, err := ioutil.ReadFile(licenses[0].AbsPath)
+	if err != nil || !bytes.HasPrefix(data, []byte("Copyright (c) 2015 Caleb Spare")) {
+		t.Fatalf("license file was not extracted: %v", err)
+	}
+
+	// Archives not matching go.sum are rejected.
+	writeGoSum("github.com/cespare/cp v0.1.0 h1:invalid=\n")
+	_, err = listGoModLicenses(ctx, gomod, t.TempDir(), m, server.Client())
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("checksum mismatch not detected: %v", err)
+	}
+
+	// So are modules missing from go.sum, or without go.sum at all.
+	writeGoMod("example.com/bare v0.1.0", "github.com/Acme/red v1.0.0")
+	writeGoSum("github.com/cespare/cp v0.1.0 " + cpZipHash + "\n")
+	_, err = listGoModLicenses(ctx, gomod, t.TempDir(), m, server.Client())
+	if err == nil || !strings.Contains(err.Error(), "missing go.sum entry") {
+		t.Fatalf("missing go.sum entry not detected: %v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, "go.sum")); err != nil {
+		t.Fatal(err)
+	}
+	_, err = listGoModLicenses(ctx, gomod, t.TempDir(), m, server.Client())
+	if err == nil || !strings.Contains(err.Error(), "GONOSUMCHECK") {
+		t.Fatalf("missing go.sum not detected: %v", err)
+	}
+
+	t.Setenv("GONOSUMCHECK", "1")
+	licenses, err = listGoModLicenses(ctx, gomod, t.TempDir(), m, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted = strings.Join([]string{
+		"example.com/bare ",
+		"github.com/Acme/red github.com/Acme/red@v1.0.0/LICENSE MIT",
+	}, "\n")
+	if got := formatLicenses(licenses); got != wanted {
+		t.Fatalf("unexpected licenses:\n%s\n!=\n%s", got, wanted)
+	}
+}
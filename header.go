@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pmezard/licenses/licensecheck"
+)
+
+const (
+	// headerLines is the number of lines scanned for license headers at the
+	// top of source files.
+	headerLines = 40
+	// headerMinScore is the minimum score for a source comment to be
+	// considered a license header.
+	headerMinScore = 0.9
+)
+
+var (
+	reSPDXTag = regexp.MustCompile(`SPDX-License-Identifier:\s*(.+)`)
+)
+
+// headerComments returns the comment blocks found in the first headerLines
+// of supplied source, with comment markers removed, and the license
+// expression of the first SPDX-License-Identifier tag if any.
+func headerComments(data []byte) ([]string, string) {
+	blocks := []string{}
+	block := []string{}
+	spdx := ""
+	flush := func() {
+		if len(block) > 0 {
+			blocks = append(blocks, strings.Join(block, "\n"))
+			block = []string{}
+		}
+	}
+	inBlock := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for i := 0; i < headerLines && scanner.Scan(); i++ {
+		line := strings.TrimSpace(scanner.Text())
+		if m := reSPDXTag.FindStringSubmatch(line); m != nil && spdx == "" {
+			spdx = strings.TrimSpace(strings.SplitN(m[1], "*/", 2)[0])
+		}
+		switch {
+		case inBlock:
+			if n := strings.Index(line, "*/"); n >= 0 {
+				line = line[:n]
+				inBlock = false
+			}
+			block = append(block, strings.TrimPrefix(line, "*"))
+			if !inBlock {
+				flush()
+			}
+		case strings.HasPrefix(line, "//"):
+			block = append(block, strings.TrimPrefix(line, "//"))
+		case strings.HasPrefix(line, "/*"):
+			flush()
+			line = line[2:]
+			inBlock = true
+			if n := strings.Index(line, "*/"); n >= 0 {
+				line = line[:n]
+				inBlock = false
+			}
+			block = append(block, line)
+			if !inBlock {
+				flush()
+			}
+		default:
+			flush()
+		}
+	}
+	flush()
+	return blocks, spdx
+}
+
+// resolveSPDXExpression returns the match result of an SPDX license
+// expression, with a score of 1 if all its identifiers refer to templates.
+// Expressions made of several licenses, like "MIT OR Apache-2.0", are reported
+// as Parts, and "+" or "-or-later" identifiers set OrLater. License exceptions
+// are ignored, "GPL-2.0 WITH Classpath-exception-2.0" resolving to GPL-2.0.
+// Parts being alternatives, expressions combining licenses with AND are not
+// resolved.
+func resolveSPDXExpression(expr string,
+	templates []*licensecheck.Template) licensecheck.MatchResult {
+
+	tokens := strings.Fields(strings.NewReplacer("(", " ", ")", " ").Replace(expr))
+	parts := []licensecheck.MatchResult{}
+	for i := 0; i < len(tokens); i++ {
+		switch strings.ToUpper(tokens[i]) {
+		case "OR":
+			continue
+		case "WITH":
+			i++
+			continue
+		case "AND":
+			return licensecheck.MatchResult{}
+		}
+		id, orLater := tokens[i], false
+		if strings.HasSuffix(id, "+") {
+			id, orLater = id[:len(id)-1], true
+		} else if n := len(id) - len("-or-later"); n > 0 &&
+			strings.EqualFold(id[n:], "-or-later") {
+			id, orLater = id[:n], true
+		}
+		t := licensecheck.FindTemplate(templates, id)
+		if t == nil {
+			return licensecheck.MatchResult{}
+		}
+		parts = append(parts, licensecheck.MatchResult{
+			Template: t,
+			Score:    1,
+			OrLater:  orLater && strings.HasSuffix(t.SPDX, "-only"),
+		})
+	}
+	switch len(parts) {
+	case 0:
+		return licensecheck.MatchResult{}
+	case 1:
+		return parts[0]
+	}
+	return licensecheck.MatchResult{
+		Template: parts[0].Template,
+		Score:    1,
+		Parts:    parts,
+	}
+}
+
+// findHeaderLicense looks for license information at the top of the package
+// Go source files, either as an SPDX-License-Identifier tag or as a comment
+// matching a template. It returns the path of the source file, made of the
+// package import path and the file name, its filesystem path and the match
+// result. Paths are empty if nothing was found.
+func findHeaderLicense(info *PkgInfo, matcher *licensecheck.Matcher) (string, string,
+	licensecheck.MatchResult, error) {
+
+	bestPath := ""
+	best := licensecheck.MatchResult{}
+	for _, name := range info.GoFiles {
+		fpath := filepath.Join(info.Dir, name)
+		data, err := ioutil.ReadFile(fpath)
+		if err != nil {
+			return "", "", licensecheck.MatchResult{}, err
+		}
+		blocks, spdx := headerComments(data)
+		if spdx != "" {
+			m := resolveSPDXExpression(spdx, matcher.Templates())
+			return filepath.Join(info.ImportPath, name), fpath, m, nil
+		}
+		for _, block := range blocks {
+			m := matcher.Match([]byte(block))
+			if m.Score >= headerMinScore && m.Score > best.Score {
+				best = m
+				bestPath = fpath
+			}
+		}
+	}
+	if bestPath == "" {
+		return "", "", licensecheck.MatchResult{}, nil
+	}
+	return filepath.Join(info.ImportPath, filepath.Base(bestPath)), bestPath,
+		best, nil
+}
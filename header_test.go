@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/pmezard/licenses/licensecheck"
+)
+
+func TestHeaderComments(t *testing.T) {
+	_, spdx := headerComments([]byte("/* SPDX-License-Identifier: MIT OR ISC */\n"))
+	if spdx != "MIT OR ISC" {
+		t.Fatalf("unexpected SPDX tag: %q", spdx)
+	}
+	blocks, spdx := headerComments([]byte(`// Copyright 2016 Someone.
+// SPDX-License-Identifier: Apache-2.0
+
+/* Some
+ * block */
+
+// Package foo does things.
+package foo
+`))
+	if spdx != "Apache-2.0" {
+		t.Fatalf("unexpected SPDX tag: %q", spdx)
+	}
+	wanted := []string{
+		" Copyright 2016 Someone.\n SPDX-License-Identifier: Apache-2.0",
+		" Some\n block ",
+		" Package foo does things.",
+	}
+	if len(blocks) != len(wanted) {
+		t.Fatalf("unexpected blocks: %q", blocks)
+	}
+	for i, b := range blocks {
+		if b != wanted[i] {
+			t.Fatalf("unexpected block %d: %q != %q", i, b, wanted[i])
+		}
+	}
+}
+
+func TestResolveSPDXExpression(t *testing.T) {
+	m, err := licensecheck.NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		Expr string
+		SPDX string
+	}{
+		{"MIT", "MIT"},
+		{"MIT OR Apache-2.0", "MIT OR Apache-2.0"},
+		{"(MIT OR GPL-2.0+)", "MIT OR GPL-2.0-or-later"},
+		{"GPL-3.0-or-later", "GPL-3.0-or-later"},
+		{"GPL-2.0-only WITH Classpath-exception-2.0", "GPL-2.0-only"},
+		{"MIT+", "MIT"},
+		{"MIT AND Apache-2.0", ""},
+		{"MIT OR Unknown-1.0", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		r := resolveSPDXExpression(test.Expr, m.Templates())
+		if got := r.SPDX(); got != test.SPDX {
+			t.Errorf("%q: unexpected SPDX expression: %q != %q", test.Expr, got,
+				test.SPDX)
+		}
+		if (r.Score == 1) != (test.SPDX != "") {
+			t.Errorf("%q: unexpected score: %v", test.Expr, r.Score)
+		}
+	}
+}
+
+func TestHeaderSPDX(t *testing.T) {
+	err := compareTestLicenses([]string{"colors/orange"}, []testResult{
+		{Package: "colors/orange", License: "MIT License", Score: 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	licenses, err := listTestdataLicenses([]string{"colors/orange"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if licenses[0].Path != "colors/orange/orange.go" {
+		t.Fatalf("unexpected license path: %s", licenses[0].Path)
+	}
+	// colors/amber declares "(MIT OR GPL-2.0+)"
+	licenses, err = listTestdataLicenses([]string{"colors/amber"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(licenses) != 1 || len(licenses[0].Parts) != 2 ||
+		licenses[0].Parts[1].SPDX() != "GPL-2.0-or-later" {
+		t.Fatalf("unexpected licenses: %+v", licenses)
+	}
+}
+
+func TestHeaderComment(t *testing.T) {
+	err := compareTestLicenses([]string{"colors/indigo"}, []testResult{
+		{Package: "colors/indigo", License: `BSD 2-clause "Simplified" License`,
+			Score: 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,48 @@
+//go:build dev
+// +build dev
+
+package licensecheck
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pmezard/licenses/assets"
+)
+
+// devAssets is true in builds with the dev tag, where embedded templates are
+// read from the assets directory on every LoadTemplates call, so they can be
+// edited without regenerating the assets.
+const devAssets = true
+
+// assetsDir, when set, is read instead of the assets package directory. It is
+// set by tests, to edit templates without touching the tracked ones.
+var assetsDir string
+
+// readAssets returns the content of the template assets files. The embedded
+// content is used when the assets package cannot be located from the current
+// directory, like when scanning another module.
+func readAssets() ([]string, error) {
+	contents := []string{}
+	for _, a := range assets.Assets {
+		var f *os.File
+		var err error
+		if assetsDir != "" {
+			f, err = os.Open(filepath.Join(assetsDir, a.Name))
+		} else {
+			f, err = a.Open()
+		}
+		if err != nil {
+			contents = append(contents, a.Content)
+			continue
+		}
+		data, err := ioutil.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		contents = append(contents, string(data))
+	}
+	return contents, nil
+}
@@ -0,0 +1,54 @@
+//go:build dev
+// +build dev
+
+package licensecheck
+
+import (
+	"go/build"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestDevAssets(t *testing.T) {
+	pkg, err := build.Import("github.com/pmezard/licenses/assets", ".",
+		build.FindOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(pkg.Dir, "isc.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Edit a copy of the template, the others fall back to embedded content
+	assetsDir = t.TempDir()
+	defer func() { assetsDir = "" }()
+	path := filepath.Join(assetsDir, "isc.txt")
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	findISC := func() *Template {
+		templates, err := LoadTemplates()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, templ := range templates {
+			if templ.SPDX == "ISC" {
+				return templ
+			}
+		}
+		t.Fatal("ISC template not found")
+		return nil
+	}
+	if _, ok := findISC().Words["zanzibar"]; ok {
+		t.Fatal("ISC template already contains the test word")
+	}
+	edited := append(append([]byte{}, data...), []byte("Zanzibar\n")...)
+	if err := ioutil.WriteFile(path, edited, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := findISC().Words["zanzibar"]; !ok {
+		t.Fatal("template edit was not reloaded")
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !dev
+// +build !dev
+
+package licensecheck
+
+import (
+	"github.com/pmezard/licenses/assets"
+)
+
+// devAssets is true in builds with the dev tag, see assets_dev.go.
+const devAssets = false
+
+// readAssets returns the content of the template assets.
+func readAssets() ([]string, error) {
+	contents := []string{}
+	for _, a := range assets.Assets {
+		contents = append(contents, a.Content)
+	}
+	return contents, nil
+}
@@ -0,0 +1,115 @@
+package licensecheck
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	utf8BOM    = []byte{0xef, 0xbb, 0xbf}
+	utf16LEBOM = []byte{0xff, 0xfe}
+	utf16BEBOM = []byte{0xfe, 0xff}
+	gzipMagic  = []byte{0x1f, 0x8b}
+)
+
+// cp1252 maps Windows-1252 bytes in [0x80, 0xa0) to runes, zero entries being
+// undefined. Other bytes are identical to Latin-1 ones.
+var cp1252 = [32]rune{
+	0x20ac, 0, 0x201a, 0x0192, 0x201e, 0x2026, 0x2020, 0x2021,
+	0x02c6, 0x2030, 0x0160, 0x2039, 0x0152, 0, 0x017d, 0,
+	0, 0x2018, 0x2019, 0x201c, 0x201d, 0x2022, 0x2013, 0x2014,
+	0x02dc, 0x2122, 0x0161, 0x203a, 0x0153, 0, 0x017e, 0x0178,
+}
+
+// DecodeLicenseData returns license data converted to UTF-8. Byte order marks
+// are stripped and UTF-16 data transcoded. Data which is not valid UTF-8 is
+// assumed to be Windows-1252 encoded, a superset of Latin-1 still common in
+// European authors names.
+func DecodeLicenseData(data []byte) []byte {
+	if bytes.HasPrefix(data, utf8BOM) {
+		data = data[len(utf8BOM):]
+	} else if bytes.HasPrefix(data, utf16LEBOM) || bytes.HasPrefix(data, utf16BEBOM) {
+		var order binary.ByteOrder = binary.LittleEndian
+		if data[0] == utf16BEBOM[0] {
+			order = binary.BigEndian
+		}
+		units := make([]uint16, 0, len(data)/2)
+		for i := 2; i+1 < len(data); i += 2 {
+			units = append(units, order.Uint16(data[i:]))
+		}
+		return []byte(string(utf16.Decode(units)))
+	}
+	if utf8.Valid(data) {
+		return data
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, len(data)+len(data)/8))
+	for _, c := range data {
+		r := rune(c)
+		if c >= 0x80 && c < 0xa0 && cp1252[c-0x80] != 0 {
+			r = cp1252[c-0x80]
+		}
+		buf.WriteRune(r)
+	}
+	return buf.Bytes()
+}
+
+// maxGzipSize is the maximum size of decompressed license data.
+const maxGzipSize = 1 << 20
+
+// gunzipLicenseData returns gzip compressed data decompressed, and other data
+// unchanged.
+func gunzipLicenseData(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	data, err = ioutil.ReadAll(io.LimitReader(r, maxGzipSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxGzipSize {
+		return nil, fmt.Errorf("decompressed license exceeds %d bytes", maxGzipSize)
+	}
+	return data, nil
+}
+
+// ReadLicenseData returns the content of a license, decompressed if it is
+// gzip compressed, like LICENSE.gz files, and converted to UTF-8 by
+// DecodeLicenseData.
+func ReadLicenseData(r io.Reader) ([]byte, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data, err = gunzipLicenseData(data)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeLicenseData(data), nil
+}
+
+// ReadLicenseFile returns the content of a license file, see ReadLicenseData,
+// without markup if it is a Markdown or HTML file, see StripMarkup.
+func ReadLicenseFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := ReadLicenseData(f)
+	if err != nil {
+		return nil, err
+	}
+	return StripMarkup(path, data), nil
+}
@@ -0,0 +1,68 @@
+package licensecheck
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+)
+
+func TestDecodeLicenseData(t *testing.T) {
+	tests := []struct {
+		Data   string
+		Wanted string
+	}{
+		{"\xef\xbb\xbfcopyright ©", "copyright ©"},
+		{"\xff\xfec\x00\xa9\x00", "c©"},
+		{"\xfe\xff\x00c\x00\xa9", "c©"},
+		{"Copyright \xa9 \x93M\xe9zard\x94", "Copyright © “Mézard”"},
+		{"Copyright \xa9 M\xe9zard", "Copyright © Mézard"},
+	}
+	for _, test := range tests {
+		got := string(DecodeLicenseData([]byte(test.Data)))
+		if got != test.Wanted {
+			t.Errorf("%q decoded to %q, wanted %q", test.Data, got, test.Wanted)
+		}
+	}
+}
+
+func gzipData(t *testing.T, data []byte) []byte {
+	buf := &bytes.Buffer{}
+	w := gzip.NewWriter(buf)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadLicenseData(t *testing.T) {
+	tests := []struct {
+		Data   []byte
+		Wanted string
+	}{
+		{[]byte("Copyright \xa9 M\xe9zard"), "Copyright © Mézard"},
+		{gzipData(t, []byte("Copyright \xa9 M\xe9zard")), "Copyright © Mézard"},
+	}
+	for _, test := range tests {
+		got, err := ReadLicenseData(bytes.NewReader(test.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.Wanted {
+			t.Errorf("%q read as %q, wanted %q", test.Data, got, test.Wanted)
+		}
+	}
+
+	// Truncated and oversized archives are rejected
+	data := gzipData(t, []byte("MIT License"))
+	if _, err := ReadLicenseData(bytes.NewReader(data[:len(data)-4])); err == nil {
+		t.Error("truncated archive was accepted")
+	}
+	data = gzipData(t, []byte(strings.Repeat(" ", maxGzipSize+1)))
+	if _, err := ReadLicenseData(bytes.NewReader(data)); err == nil {
+		t.Error("oversized archive was accepted")
+	}
+}
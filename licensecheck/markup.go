@@ -0,0 +1,65 @@
+package licensecheck
+
+import (
+	"html"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	// reHTMLHidden matches HTML comments, scripts and style sheets, whose
+	// content is not displayed.
+	reHTMLHidden = regexp.MustCompile(`(?is)<!--.*?-->|<script\b.*?</script\s*>|` +
+		`<style\b.*?</style\s*>`)
+	// reHTMLTag matches HTML opening and closing tags, with their attributes.
+	// Only common elements are matched, licenses using angle brackets for
+	// placeholders like "<insert your license name here>".
+	reHTMLTag = regexp.MustCompile(`(?i)</?(?:a|abbr|article|b|blockquote|body|br|` +
+		`center|code|dd|details|div|dl|dt|em|font|footer|h[1-6]|head|header|hr|` +
+		`html|i|img|li|link|main|meta|nav|ol|p|pre|section|small|span|strong|` +
+		`sub|summary|sup|table|tbody|td|th|thead|title|tr|tt|u|ul)` +
+		`(?:\s[^<>]*)?/?>`)
+	// reMarkdownFence matches Markdown code fence lines, with their info
+	// string, like "```text".
+	reMarkdownFence = regexp.MustCompile("(?m)^[ \t]*(?:```|~~~).*$")
+	// reMarkdownLink matches Markdown links and images and captures their
+	// text.
+	reMarkdownLink = regexp.MustCompile(`!?\[([^\]]*)\]\([^)\s]*(?:\s+"[^"]*")?\)`)
+	// reMarkdownHeading matches the markers of Markdown ATX headings.
+	reMarkdownHeading = regexp.MustCompile(`(?m)^[ \t]{0,3}#{1,6}[ \t]+`)
+	// reEmphasisStart and reEmphasisEnd match the underscores surrounding
+	// emphasized words, like "_Software_". Other markers, like "*", are not
+	// word characters and do not alter tokenization.
+	reEmphasisStart = regexp.MustCompile(`(?m)(^|[^\w])_+([^\W_])`)
+	reEmphasisEnd   = regexp.MustCompile(`(?m)([^\W_])_+([^\w]|$)`)
+)
+
+// isMarkupFile returns true if name is the name of a Markdown or HTML file,
+// possibly gzip compressed.
+func isMarkupFile(name string) bool {
+	name = strings.TrimSuffix(strings.ToLower(name), ".gz")
+	switch filepath.Ext(name) {
+	case ".md", ".markdown", ".htm", ".html":
+		return true
+	}
+	return false
+}
+
+// StripMarkup returns the data of the license file name without its Markdown
+// or HTML markup, like code fences, heading markers or tags, which would
+// otherwise be matched as extra words. HTML entities are decoded. Data of
+// other files is returned unchanged.
+func StripMarkup(name string, data []byte) []byte {
+	if !isMarkupFile(name) {
+		return data
+	}
+	s := reHTMLHidden.ReplaceAllString(string(data), "")
+	s = reHTMLTag.ReplaceAllString(s, "")
+	s = reMarkdownFence.ReplaceAllString(s, "")
+	s = reMarkdownLink.ReplaceAllString(s, "$1")
+	s = reMarkdownHeading.ReplaceAllString(s, "")
+	s = reEmphasisStart.ReplaceAllString(s, "$1$2")
+	s = reEmphasisEnd.ReplaceAllString(s, "$1$2")
+	return []byte(html.UnescapeString(s))
+}
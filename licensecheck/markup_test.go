@@ -0,0 +1,31 @@
+package licensecheck
+
+import (
+	"testing"
+)
+
+func TestStripMarkup(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Data   string
+		Wanted string
+	}{
+		{"LICENSE", "# <b>_MIT_</b>", "# <b>_MIT_</b>"},
+		{"LICENSE.md", "# MIT License\n```text\nsome_words\n```\n", "MIT License\n\nsome_words\n\n"},
+		{"LICENSE.md", "_Software_ and __substantial__", "Software and substantial"},
+		{"LICENSE.md", "See [the license](https://example.com \"title\")", "See the license"},
+		{"license.markdown.gz", "<p align=\"center\">MIT</p>", "MIT"},
+		{"LICENSE.html", "<html><head><style>p { margin: 0 }</style></head>" +
+			"<body><!-- MIT --><p>&quot;AS IS&quot;</p></body></html>", `"AS IS"`},
+		{"LICENSE.htm", "<script>var x;</script>A &amp; B", "A & B"},
+		{"LICENSE.md", "<br/>Licensed under <insert your license name here>",
+			"Licensed under <insert your license name here>"},
+	}
+	for _, test := range tests {
+		got := string(StripMarkup(test.Name, []byte(test.Data)))
+		if got != test.Wanted {
+			t.Errorf("%s: %q stripped to %q, wanted %q", test.Name, test.Data, got,
+				test.Wanted)
+		}
+	}
+}
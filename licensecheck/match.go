@@ -0,0 +1,834 @@
+// Package licensecheck matches license texts against a set of well-known
+// license templates. It also scores file names to tell license files apart,
+// and decodes their content. The licenses command builds on it to report the
+// licenses of Go packages.
+package licensecheck
+
+import (
+	"bytes"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Word is a distinct word of a license or template text. Pos is the index of
+// its first occurrence among the text words, once cleaned by
+// CleanLicenseData.
+type Word struct {
+	Text string
+	Pos  int
+}
+
+type sortedWords []Word
+
+func (s sortedWords) Len() int {
+	return len(s)
+}
+
+func (s sortedWords) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s sortedWords) Less(i, j int) bool {
+	return s[i].Pos < s[j].Pos
+}
+
+// MatchResult describes how well a license text matches a template.
+type MatchResult struct {
+	Template     *Template
+	Score        float64
+	ExtraWords   []string
+	MissingWords []string
+	// Extra and Missing are ExtraWords and MissingWords with their position in
+	// the license and template texts respectively, to locate differences.
+	// Multi-licensed files only have them in Parts, positions being relative
+	// to each part text.
+	Extra   []Word
+	Missing []Word
+	// HeaderWords lists the words of the license header missing from the
+	// template, and the words of the template header missing from the
+	// license. See licenseHeader.
+	HeaderWords []string
+	// Parts holds the matches of each license of a multi-licensed file, in
+	// order of appearance. Template and Score are then the ones of the first
+	// part and the lowest part score.
+	Parts []MatchResult
+	// OrLater is true if the license text allows using any later version of
+	// a versioned template, like "GNU GPL version 3 or (at your option) any
+	// later version". See isOrLater.
+	OrLater bool
+	// Common is the number of distinct words shared by the license and the
+	// template, out of LicenseWords and TemplateWords distinct words. Score
+	// derives from them.
+	Common        int
+	LicenseWords  int
+	TemplateWords int
+	// URL is the well-known license URL the template was identified from,
+	// for short files referring to their license instead of quoting it, like
+	// "Licensed under https://www.apache.org/licenses/LICENSE-2.0". It is
+	// empty when the license text itself matched. See matchLicenseURL.
+	URL string
+}
+
+// SPDX returns the SPDX license expression of the match, made of the parts
+// identifiers for multi-licensed files. It returns an empty string if the
+// template or any part has no SPDX identifier.
+func (m MatchResult) SPDX() string {
+	if m.Template == nil {
+		return ""
+	}
+	if len(m.Parts) == 0 {
+		if m.OrLater {
+			return strings.TrimSuffix(m.Template.SPDX, "-only") + "-or-later"
+		}
+		return m.Template.SPDX
+	}
+	ids := []string{}
+	for _, p := range m.Parts {
+		if p.Template.SPDX == "" {
+			return ""
+		}
+		ids = append(ids, p.SPDX())
+	}
+	return strings.Join(ids, " OR ")
+}
+
+// sortAndReturnWords sorts words by position, in place, and returns their
+// texts.
+func sortAndReturnWords(words []Word) []string {
+	sort.Sort(sortedWords(words))
+	tokens := []string{}
+	for _, w := range words {
+		tokens = append(tokens, w.Text)
+	}
+	return tokens
+}
+
+// diffWords compares license and template word sets. It returns the words
+// appearing in license but not in template, the words appearing in template
+// but not in license and the number of common words.
+func diffWords(words, templ map[string]int) ([]Word, []Word, int) {
+	extra := []Word{}
+	missing := []Word{}
+	common := 0
+	for w, pos := range words {
+		_, ok := templ[w]
+		if ok {
+			common++
+		} else {
+			extra = append(extra, Word{
+				Text: w,
+				Pos:  pos,
+			})
+		}
+	}
+	for w, pos := range templ {
+		if _, ok := words[w]; !ok {
+			missing = append(missing, Word{
+				Text: w,
+				Pos:  pos,
+			})
+		}
+	}
+	return extra, missing, common
+}
+
+// familyEpsilon is the maximum score difference under which the best two
+// templates of a family are disambiguated with their distinguishing words.
+const familyEpsilon = 0.05
+
+// templateFamily returns the template family, derived from the first word of
+// its nickname, like "GNU". It returns an empty string if the template has no
+// nickname.
+func templateFamily(t *Template) string {
+	fields := strings.Fields(t.Nickname)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// distinguishingRatio returns the fraction of words appearing in templ but
+// not in other which are found in words.
+func distinguishingRatio(words, templ, other map[string]int) float64 {
+	found, total := 0, 0
+	for w := range templ {
+		if _, ok := other[w]; ok {
+			continue
+		}
+		total++
+		if _, ok := words[w]; ok {
+			found++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(found) / float64(total)
+}
+
+const (
+	// shortLicenseMinWords and shortLicenseMaxWords bound the number of
+	// distinct words of license files checked for being short public domain
+	// dedications.
+	shortLicenseMinWords = 8
+	shortLicenseMaxWords = 60
+	// shortLicenseMinScore is the containment score above which a short
+	// license file matches a dedication template.
+	shortLicenseMinScore = 0.9
+	// shortLicenseMaxScore caps containment scores, short files are never
+	// exact copies of their template.
+	shortLicenseMaxScore = 0.99
+)
+
+// dedicationTemplates lists the SPDX identifiers of public domain dedications,
+// often quoted as a single sentence instead of in full.
+var dedicationTemplates = map[string]bool{
+	"Unlicense": true,
+	"CC0-1.0":   true,
+}
+
+// containmentScore returns the fraction of words found in templ. Each word is
+// weighted by its inverse frequency across indexed templates, so rare words
+// like "unencumbered" matter more than ubiquitous ones like "software".
+func containmentScore(words map[string]int, templ *Template,
+	index *templateIndex) float64 {
+
+	n := float64(len(index.Templates))
+	found, total := 0., 0.
+	for w := range words {
+		count := len(index.Postings[w])
+		weight := math.Log((n + 1) / float64(count+1))
+		total += weight
+		if _, ok := templ.Words[w]; ok {
+			found += weight
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return found / total
+}
+
+// matchDedication returns the dedication template containing most of the
+// words of short license files, and its containment score, or a nil template
+// if there is none. Dice scores penalize them for being much shorter than
+// their template.
+func matchDedication(words map[string]int, index *templateIndex) (*Template,
+	float64) {
+
+	if len(words) < shortLicenseMinWords || len(words) >= shortLicenseMaxWords {
+		return nil, 0
+	}
+	var best *Template
+	bestScore := 0.
+	for _, t := range index.Templates {
+		if !dedicationTemplates[t.SPDX] {
+			continue
+		}
+		score := containmentScore(words, t, index)
+		if score >= shortLicenseMinScore && isBetterMatch(t, score, best, bestScore) {
+			best, bestScore = t, score
+		}
+	}
+	return best, math.Min(bestScore, shortLicenseMaxScore)
+}
+
+// templateIndex is an inverted index of template words, to count the words a
+// license shares with every template in a single pass over its words.
+type templateIndex struct {
+	Templates []*Template
+	// Postings maps words to the indices of the templates containing them.
+	Postings map[string][]int
+	// Words maps templates to their word sets, without stopwords if
+	// Stopwords is set.
+	Words map[*Template]map[string]int
+	// Stopwords is true if stopwords are ignored, see ignoreStopwords.
+	Stopwords bool
+	// IDF holds the inverse document frequency of template words, see
+	// inverseFrequencies.
+	IDF map[string]float64
+}
+
+func newTemplateIndex(templates []*Template) *templateIndex {
+	index := &templateIndex{
+		Templates: templates,
+		Postings:  map[string][]int{},
+		Words:     map[*Template]map[string]int{},
+		IDF:       inverseFrequencies(templates),
+	}
+	for i, t := range templates {
+		index.Words[t] = t.Words
+		for w := range t.Words {
+			index.Postings[w] = append(index.Postings[w], i)
+		}
+	}
+	return index
+}
+
+// ignoreStopwords removes stopwords from indexed templates, so they are
+// ignored when matching licenses, see removeStopwords.
+func (index *templateIndex) ignoreStopwords() {
+	index.Stopwords = true
+	for w := range stopwords {
+		delete(index.Postings, w)
+	}
+	for t, words := range index.Words {
+		index.Words[t] = removeStopwords(words)
+	}
+}
+
+// commonWords returns the number of words shared by supplied word set and
+// each indexed template.
+func (index *templateIndex) commonWords(words map[string]int) []int {
+	common := make([]int, len(index.Templates))
+	for w := range words {
+		for _, i := range index.Postings[w] {
+			common[i]++
+		}
+	}
+	return common
+}
+
+// isBetterMatch returns true if template t with score should be preferred
+// over best with bestScore. Ties are broken by TemplateKey, so results do not
+// depend on the templates order.
+func isBetterMatch(t *Template, score float64, best *Template, bestScore float64) bool {
+	if best == nil || score != bestScore {
+		return score > bestScore
+	}
+	return TemplateKey(t) < TemplateKey(best)
+}
+
+type scoredTemplate struct {
+	Template *Template
+	Score    float64
+	Extra    []Word
+	Missing  []Word
+	Common   int
+}
+
+type sortedScoredTemplates []scoredTemplate
+
+func (s sortedScoredTemplates) Len() int {
+	return len(s)
+}
+
+func (s sortedScoredTemplates) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s sortedScoredTemplates) Less(i, j int) bool {
+	return isBetterMatch(s[i].Template, s[i].Score, s[j].Template, s[j].Score)
+}
+
+// matchTemplatesN returns the n license templates best matching supplied
+// data, by decreasing score. Each result has its score between 0 and 1 and
+// the list of words appearing in license but not in the template. When the
+// best two templates belong to the same family and have close scores, like
+// GPL versions sharing most of their text, the one whose distinguishing words
+// are the most present in license comes first. Multi-licensed files are not
+// detected, see matchTemplates.
+func matchTemplatesN(license []byte, index *templateIndex, n int) []MatchResult {
+	words := makeWordSet(license)
+	if index.Stopwords {
+		words = removeStopwords(words)
+	}
+	scored := []scoredTemplate{}
+	for i, common := range index.commonWords(words) {
+		t := index.Templates[i]
+		score := 2 * float64(common) / (float64(len(words)) + float64(len(index.Words[t])))
+		scored = append(scored, scoredTemplate{Template: t, Score: score})
+	}
+	sort.Stable(sortedScoredTemplates(scored))
+	if len(scored) > 1 && scored[0].Score-scored[1].Score < familyEpsilon {
+		best, second := scored[0], scored[1]
+		family := templateFamily(best.Template)
+		if family != "" && family == templateFamily(second.Template) {
+			a := distinguishingRatio(words, index.Words[best.Template],
+				index.Words[second.Template])
+			b := distinguishingRatio(words, index.Words[second.Template],
+				index.Words[best.Template])
+			if b > a {
+				scored[0], scored[1] = second, best
+			}
+		}
+	}
+	dedication, score := matchDedication(words, index)
+	if dedication != nil && score > scored[0].Score {
+		kept := []scoredTemplate{{Template: dedication, Score: score}}
+		for _, s := range scored {
+			if s.Template != dedication {
+				kept = append(kept, s)
+			}
+		}
+		scored = kept
+	}
+	if len(scored) > n {
+		scored = scored[:n]
+	}
+	// Only list words differences of reported templates
+	for i := range scored {
+		s := &scored[i]
+		s.Extra, s.Missing, s.Common = diffWords(words, index.Words[s.Template])
+		if s.Template == dedication {
+			// Short dedications only quote part of the template, missing
+			// words are irrelevant.
+			s.Missing = nil
+		}
+	}
+	// Title, copyright notices and authors names in license or template
+	// headers are not substantive differences, report them separately.
+	licenseHeaderWords := makeWordSet(licenseHeader(license))
+	results := []MatchResult{}
+	for _, s := range scored {
+		extra, licenseHeader := splitHeaderWords(s.Extra, licenseHeaderWords)
+		missing, templateHeader := splitHeaderWords(s.Missing,
+			s.Template.HeaderWords)
+		results = append(results, MatchResult{
+			Template:      s.Template,
+			Score:         s.Score,
+			ExtraWords:    sortAndReturnWords(extra),
+			MissingWords:  sortAndReturnWords(missing),
+			Extra:         extra,
+			Missing:       missing,
+			HeaderWords:   sortAndReturnWords(append(licenseHeader, templateHeader...)),
+			Common:        s.Common,
+			LicenseWords:  len(words),
+			TemplateWords: len(index.Words[s.Template]),
+		})
+	}
+	return results
+}
+
+// matchTemplate returns the best license template matching supplied data, see
+// matchTemplatesN.
+func matchTemplate(license []byte, index *templateIndex) MatchResult {
+	results := matchTemplatesN(license, index, 1)
+	if len(results) == 0 {
+		return MatchResult{}
+	}
+	return results[0]
+}
+
+const (
+	// dualMinExtraRatio is the ratio of extra words to license words above
+	// which a license is checked for being made of multiple license texts.
+	// Being relative, it applies to short and long templates alike. Most
+	// license texts share many words, so few of the second one are extra.
+	dualMinExtraRatio = 0.01
+	// dualMinScore is the minimum score of each part of a multi-licensed
+	// file.
+	dualMinScore = 0.9
+)
+
+// splitBlocks splits data into blank-line separated blocks.
+func splitBlocks(data []byte) [][]byte {
+	blocks := [][]byte{}
+	block := []byte{}
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			if len(block) > 0 {
+				blocks = append(blocks, block)
+				block = []byte{}
+			}
+			continue
+		}
+		block = append(block, line...)
+	}
+	if len(block) > 0 {
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// prefixScores returns, for every i in [1, len(blocks)], the best score the
+// first i blocks reach against any template. The words shared with every
+// template are updated block after block, so all prefixes are scored in a
+// single pass over the text. Scores are those of matchTemplate, without
+// computing words differences.
+func prefixScores(blocks [][]byte, index *templateIndex) []float64 {
+	scores := make([]float64, len(blocks)+1)
+	words := map[string]int{}
+	common := make([]int, len(index.Templates))
+	for i, block := range blocks {
+		blockWords := makeWordSet(block)
+		if index.Stopwords {
+			blockWords = removeStopwords(blockWords)
+		}
+		for w := range blockWords {
+			if _, ok := words[w]; ok {
+				continue
+			}
+			words[w] = len(words)
+			for _, j := range index.Postings[w] {
+				common[j]++
+			}
+		}
+		best := 0.
+		for j, t := range index.Templates {
+			score := 2 * float64(common[j]) /
+				(float64(len(words)) + float64(len(index.Words[t])))
+			best = math.Max(best, score)
+		}
+		if _, score := matchDedication(words, index); score > best {
+			best = score
+		}
+		scores[i+1] = best
+	}
+	return scores
+}
+
+// dualBoundaries returns the indices of the blocks license can be split
+// before, so that both parts score above dualMinScore on their own.
+func dualBoundaries(blocks [][]byte, index *templateIndex) []int {
+	reversed := make([][]byte, len(blocks))
+	for i, block := range blocks {
+		reversed[len(blocks)-1-i] = block
+	}
+	firstScores := prefixScores(blocks, index)
+	lastScores := prefixScores(reversed, index)
+	boundaries := []int{}
+	for i := 1; i < len(blocks); i++ {
+		if firstScores[i] >= dualMinScore && lastScores[len(blocks)-i] >= dualMinScore {
+			boundaries = append(boundaries, i)
+		}
+	}
+	return boundaries
+}
+
+// matchDualLicense tries to split license in two consecutive sets of blocks
+// each matching a different template. It returns the match result of the
+// best split, with both parts score above dualMinScore, and true, or false if
+// there is none. Only the boundaries returned by dualBoundaries are fully
+// matched.
+func matchDualLicense(license []byte, index *templateIndex) (MatchResult, bool) {
+	blocks := splitBlocks(license)
+	best := MatchResult{}
+	found := false
+	for _, i := range dualBoundaries(blocks, index) {
+		firstText := bytes.Join(blocks[:i], []byte("\n"))
+		first := matchTemplate(firstText, index)
+		if first.Score < dualMinScore {
+			continue
+		}
+		secondText := bytes.Join(blocks[i:], []byte("\n"))
+		second := matchTemplate(secondText, index)
+		if second.Score < dualMinScore || second.Template == first.Template {
+			continue
+		}
+		first.OrLater = isOrLater(firstText, first.Template)
+		second.OrLater = isOrLater(secondText, second.Template)
+		score := math.Min(first.Score, second.Score)
+		if !found || score > best.Score {
+			found = true
+			best = MatchResult{
+				Template:      first.Template,
+				Score:         score,
+				ExtraWords:    append(first.ExtraWords, second.ExtraWords...),
+				MissingWords:  append(first.MissingWords, second.MissingWords...),
+				HeaderWords:   append(first.HeaderWords, second.HeaderWords...),
+				Parts:         []MatchResult{first, second},
+				Common:        first.Common + second.Common,
+				LicenseWords:  first.LicenseWords + second.LicenseWords,
+				TemplateWords: first.TemplateWords + second.TemplateWords,
+			}
+		}
+	}
+	return best, found
+}
+
+// matchTemplates is like matchTemplate but also detects files made of two
+// license texts, when the best template leaves many words unexplained. Such
+// files are usually licensed under either license, like "MIT OR Apache-2.0".
+// Files matching no template well are identified by the well-known license
+// URL they contain, if any.
+func matchTemplates(license []byte, index *templateIndex) MatchResult {
+	m := matchTemplate(license, index)
+	if m.Score < noLicenseMaxScore && isNoLicense(license) {
+		if t := findNoLicense(index.Templates); t != nil {
+			return MatchResult{
+				Template:     t,
+				Score:        1,
+				LicenseWords: len(makeWordSet(license)),
+			}
+		}
+	}
+	if m.Score < licenseURLMaxScore {
+		if t, url := matchLicenseURL(license, index.Templates); t != nil {
+			return MatchResult{
+				Template:     t,
+				Score:        licenseURLScore,
+				LicenseWords: len(makeWordSet(license)),
+				URL:          url,
+			}
+		}
+	}
+	if m.LicenseWords > 0 &&
+		float64(len(m.ExtraWords))/float64(m.LicenseWords) >= dualMinExtraRatio {
+		if dual, ok := matchDualLicense(license, index); ok {
+			return dual
+		}
+	}
+	m.OrLater = isOrLater(license, m.Template)
+	return m
+}
+
+// NoLicenseTitle is the title of the template matched by files reserving all
+// rights without granting any, see isNoLicense.
+const NoLicenseTitle = "No License"
+
+const (
+	// noLicenseMaxScore is the score under which a license is checked for
+	// granting no rights.
+	noLicenseMaxScore = 0.5
+	// noLicenseMaxWords is the maximum number of distinct words of files
+	// granting no rights. Longer ones are likely unknown licenses.
+	noLicenseMaxWords = 80
+)
+
+var (
+	reAllRightsReserved = regexp.MustCompile(`(?i)\ball\W+rights\W+reserved\b`)
+	// reGrant matches the phrases granting rights in license texts.
+	reGrant = regexp.MustCompile(`(?i)\b(?:permission is hereby granted|` +
+		`hereby grants?|licen[sc]ed under|redistribution and use|free software|` +
+		`public domain|permitted provided|you may (?:use|copy|modify|distribute|` +
+		`redistribute))\b`)
+)
+
+// isNoLicense returns true if license is a short text reserving all rights,
+// like "Copyright 2020 ACME. All rights reserved.", without granting any.
+func isNoLicense(license []byte) bool {
+	return reAllRightsReserved.Match(license) && !reGrant.Match(license) &&
+		len(makeWordSet(license)) <= noLicenseMaxWords
+}
+
+// findNoLicense returns the template of files granting no rights, see
+// NoLicenseTitle, or nil if there is none.
+func findNoLicense(templates []*Template) *Template {
+	for _, t := range templates {
+		if t.Title == NoLicenseTitle {
+			return t
+		}
+	}
+	return nil
+}
+
+// reOrLater matches the phrases granting the use of later versions of a
+// license, possibly broken by comment markers.
+var reOrLater = regexp.MustCompile(`(?i)\bany\W+later\W+version\b|\bor\W+later\b`)
+
+// isOrLater returns true if t identifier ends with "-only", like GPL ones,
+// and license grants the use of later versions. The templates texts include
+// such phrases in their instructions, so only additional occurrences count.
+// Bare copies of these templates do not tell which versions the source grants,
+// they are not reported as allowing later ones.
+func isOrLater(license []byte, t *Template) bool {
+	if t == nil || !strings.HasSuffix(t.SPDX, "-only") {
+		return false
+	}
+	n := len(reOrLater.FindAllIndex(license, -1))
+	return n > len(reOrLater.FindAllStringIndex(t.Text, -1))
+}
+
+// inverseFrequencies returns the inverse document frequency of template
+// words, that is the log of the inverse fraction of templates containing them.
+// Words common to all templates have a null weight.
+func inverseFrequencies(templates []*Template) map[string]float64 {
+	docs := map[string]int{}
+	for _, t := range templates {
+		for w := range t.Words {
+			docs[w]++
+		}
+	}
+	idf := map[string]float64{}
+	for w, n := range docs {
+		idf[w] = math.Log(float64(len(templates)+1) / float64(n))
+	}
+	return idf
+}
+
+// cosineSimilarity returns the cosine of the angle between supplied word
+// frequency vectors, each word being weighted by idf.
+func cosineSimilarity(a, b map[string]int, idf map[string]float64) float64 {
+	dot, na, nb := 0., 0., 0.
+	for w, n := range a {
+		x := float64(n) * idf[w]
+		dot += x * float64(b[w]) * idf[w]
+		na += x * x
+	}
+	for w, n := range b {
+		y := float64(n) * idf[w]
+		nb += y * y
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// matchTemplatesCosine is like matchTemplates but scores templates with the
+// cosine similarity of words frequencies, weighted by their inverse frequency
+// across templates, instead of the Dice coefficient of word sets. Repeated
+// and distinctive words weigh more, which helps separating licenses sharing
+// most of their vocabulary, like GPL versions, when only part of the text is
+// available.
+func matchTemplatesCosine(license []byte, index *templateIndex) MatchResult {
+	bestScore := float64(-1)
+	var bestTemplate *Template
+	counts := makeWordCounts(license)
+	for _, t := range index.Templates {
+		score := cosineSimilarity(counts, t.Counts, index.IDF)
+		if isBetterMatch(t, score, bestTemplate, bestScore) {
+			bestScore = score
+			bestTemplate = t
+		}
+	}
+	r := MatchResult{
+		Template: bestTemplate,
+		Score:    bestScore,
+	}
+	if bestTemplate != nil {
+		words := makeWordSet(license)
+		extra, missing, common := diffWords(words, bestTemplate.Words)
+		r.ExtraWords = sortAndReturnWords(extra)
+		r.MissingWords = sortAndReturnWords(missing)
+		r.Extra = extra
+		r.Missing = missing
+		r.Common = common
+		r.LicenseWords = len(words)
+		r.TemplateWords = len(bestTemplate.Words)
+	}
+	return r
+}
+
+// Matcher matches license data against a set of templates. It can be reused
+// across calls to avoid parsing the templates again.
+type Matcher struct {
+	templates []*Template
+	index     *templateIndex
+	// copyrights lists additional copyright patterns, see
+	// AddCopyrightPattern.
+	copyrights []*regexp.Regexp
+}
+
+// NewMatcher returns a Matcher using the embedded license templates.
+func NewMatcher() (*Matcher, error) {
+	templates, err := LoadTemplates()
+	if err != nil {
+		return nil, err
+	}
+	return &Matcher{
+		templates: templates,
+		index:     newTemplateIndex(templates),
+	}, nil
+}
+
+// NewMatcherWithDirs returns a Matcher using the embedded license templates
+// and the templates of supplied directories, see LoadTemplateDir. User
+// templates replace embedded ones with the same nickname, and later
+// directories take precedence over earlier ones. Templates with the same
+// words as another are dropped, see dedupeTemplates.
+func NewMatcherWithDirs(dirs []string) (*Matcher, error) {
+	templates, err := LoadTemplates()
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		extra, err := LoadTemplateDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		templates = mergeTemplates(templates, extra)
+	}
+	templates = dedupeTemplates(templates)
+	return &Matcher{
+		templates: templates,
+		index:     newTemplateIndex(templates),
+	}, nil
+}
+
+// AddCopyrightPattern makes the matcher strip the text matched by re from
+// license data, in addition to the copyright notices recognized by default.
+// It supports unusual notices, like "Copyright Authors of X" or bespoke file
+// headers, which would otherwise lower the scores. re is applied before the
+// data is lowercased.
+func (m *Matcher) AddCopyrightPattern(re *regexp.Regexp) {
+	m.copyrights = append(m.copyrights, re)
+}
+
+// IgnoreStopwords makes the matcher ignore very common English words, like
+// "the" or "of", when scoring licenses with Match or MatchN. Found in nearly
+// every license, they hardly tell templates apart and only add noise to
+// extra and missing words.
+func (m *Matcher) IgnoreStopwords() {
+	m.index.ignoreStopwords()
+}
+
+// IgnoresStopwords returns true if IgnoreStopwords was called.
+func (m *Matcher) IgnoresStopwords() bool {
+	return m.index.Stopwords
+}
+
+// CopyrightPatterns returns the patterns added with AddCopyrightPattern.
+func (m *Matcher) CopyrightPatterns() []*regexp.Regexp {
+	return m.copyrights
+}
+
+// stripCopyrights returns license without the text matching the additional
+// copyright patterns. Matches are replaced with a space so surrounding words
+// are not glued together.
+func (m *Matcher) stripCopyrights(license []byte) []byte {
+	for _, re := range m.copyrights {
+		license = re.ReplaceAllLiteral(license, []byte(" "))
+	}
+	return license
+}
+
+// Match returns the template best matching supplied license data.
+func (m *Matcher) Match(license []byte) MatchResult {
+	return matchTemplates(m.stripCopyrights(license), m.index)
+}
+
+// MatchN returns the n templates best matching license, by decreasing score,
+// see matchTemplatesN.
+func (m *Matcher) MatchN(license []byte, n int) []MatchResult {
+	return matchTemplatesN(m.stripCopyrights(license), m.index, n)
+}
+
+// MatchCosine is like Match but scores templates with the cosine similarity
+// of words frequencies, see matchTemplatesCosine.
+func (m *Matcher) MatchCosine(license []byte) MatchResult {
+	return matchTemplatesCosine(m.stripCopyrights(license), m.index)
+}
+
+// Templates returns the templates of the matcher, which must not be modified.
+func (m *Matcher) Templates() []*Template {
+	return m.templates
+}
+
+var (
+	defaultMatcherOnce sync.Once
+	defaultMatcher     *Matcher
+	defaultMatcherErr  error
+)
+
+// Detect returns the embedded template best matching supplied license text.
+// Multi-licensed texts are detected, see MatchResult.Parts.
+func Detect(text []byte) (MatchResult, error) {
+	defaultMatcherOnce.Do(func() {
+		defaultMatcher, defaultMatcherErr = NewMatcher()
+	})
+	if defaultMatcherErr != nil {
+		return MatchResult{}, defaultMatcherErr
+	}
+	return defaultMatcher.Match(text), nil
+}
+
+// DetectWith is like Detect but matches text against supplied templates. Use
+// a Matcher to match several texts against the same templates.
+func DetectWith(text []byte, templates []*Template) MatchResult {
+	return matchTemplates(text, newTemplateIndex(templates))
+}
@@ -0,0 +1,671 @@
+package licensecheck
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/pmezard/licenses/assets"
+)
+
+func TestMatcher(t *testing.T) {
+	m1, err := NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m2, err := NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m1.templates) == 0 {
+		t.Fatal("no template loaded")
+	}
+	// dev builds reload templates on every call
+	if !devAssets && &m1.templates[0] != &m2.templates[0] {
+		t.Fatalf("templates are not shared between matchers")
+	}
+	data, err := ioutil.ReadFile(filepath.Join("..", "testdata", "src", "colors", "red",
+		"LICENSE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []*Matcher{m1, m2} {
+		r := m.Match(data)
+		if r.Template == nil || r.Template.Title != "MIT License" ||
+			int(100*r.Score) != 98 {
+			t.Fatalf("unexpected match: %+v", r)
+		}
+	}
+}
+
+func TestMatchCosine(t *testing.T) {
+	m, err := NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Drop the appendix of GPL texts. Both scoring methods find the right
+	// version, but the cosine similarity is more confident.
+	tests := []struct {
+		Name  string
+		Title string
+	}{
+		{"gpl_2.0.txt", "GNU General Public License v2.0"},
+		{"gpl_3.0.txt", "GNU General Public License v3.0"},
+	}
+	for _, test := range tests {
+		text := templateText(t, test.Name)
+		text = text[:strings.Index(text, "END OF TERMS AND CONDITIONS")]
+		dice := m.Match([]byte(text))
+		cosine := m.MatchCosine([]byte(text))
+		if dice.Template.Title != test.Title || cosine.Template.Title != test.Title {
+			t.Fatalf("unexpected match for %s: %s, %s", test.Name,
+				dice.Template.Title, cosine.Template.Title)
+		}
+		if cosine.Score < 0.9 || cosine.Score <= dice.Score {
+			t.Fatalf("cosine similarity is not more confident for %s: %v <= %v",
+				test.Name, cosine.Score, dice.Score)
+		}
+	}
+	// Truncated licenses extending a close one, like MS-RL adding reciprocity
+	// to MS-PL, have most words in common with it. The Dice coefficient picks
+	// the shorter license, the cosine similarity the right one.
+	for _, test := range []struct {
+		Name     string
+		Fraction float64
+		SPDX     string
+		Dice     string
+	}{
+		{"ms_rl.txt", 0.5, "MS-RL", "MS-PL"},
+		{"osl_3.0.txt", 0.7, "OSL-3.0", "AFL-3.0"},
+	} {
+		text := templateText(t, test.Name)
+		text = text[:int(float64(len(text))*test.Fraction)]
+		dice := m.Match([]byte(text))
+		cosine := m.MatchCosine([]byte(text))
+		if dice.Template.SPDX != test.Dice || cosine.Template.SPDX != test.SPDX {
+			t.Fatalf("unexpected matches for %s: dice %s, cosine %s", test.Name,
+				dice.Template.SPDX, cosine.Template.SPDX)
+		}
+	}
+}
+
+func TestIgnoreStopwords(t *testing.T) {
+	m, err := NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// MIT license without "the" and with extra "a" and "of"
+	text := regexp.MustCompile(`(?i)\bthe\b`).ReplaceAllString(
+		templateText(t, "mit.txt"), "") + "\nA copy of it, forever.\n"
+	hasStopword := func(r MatchResult) bool {
+		for _, words := range [][]string{r.ExtraWords, r.MissingWords} {
+			for _, w := range words {
+				if stopwords[w] {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	r := m.Match([]byte(text))
+	if !hasStopword(r) {
+		t.Fatalf("stopwords are not reported by default: %+v", r)
+	}
+	m.IgnoreStopwords()
+	if !m.IgnoresStopwords() {
+		t.Fatal("stopwords are not ignored")
+	}
+	r = m.Match([]byte(text))
+	if r.Template == nil || r.Template.SPDX != "MIT" || hasStopword(r) {
+		t.Fatalf("unexpected match: %+v", r)
+	}
+	if strings.Join(r.ExtraWords, " ") != "forever" {
+		t.Fatalf("unexpected extra words: %v", r.ExtraWords)
+	}
+	// Templates still match themselves exactly
+	for _, templ := range m.Templates() {
+		for _, r := range m.MatchN([]byte(templ.Text), 3) {
+			if hasStopword(r) {
+				t.Fatalf("%s matched with stopwords: %+v", templ.Title, r)
+			}
+		}
+	}
+}
+
+func TestMatchWordCounts(t *testing.T) {
+	m, err := NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join("..", "testdata", "src", "colors", "red",
+		"LICENSE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := m.Match(data)
+	if r.Common != 91 || r.LicenseWords != 91 || r.TemplateWords != 93 {
+		t.Fatalf("unexpected word counts: %d common, %d license, %d template",
+			r.Common, r.LicenseWords, r.TemplateWords)
+	}
+	for _, r := range []MatchResult{r, m.MatchCosine(data)} {
+		if len(r.ExtraWords) != r.LicenseWords-r.Common ||
+			len(r.MissingWords)+len(r.HeaderWords) != r.TemplateWords-r.Common {
+			t.Fatalf("word counts do not match word lists: %+v", r)
+		}
+	}
+	// Dice scores derive from word counts
+	score := 2 * float64(r.Common) / float64(r.LicenseWords+r.TemplateWords)
+	if score != r.Score {
+		t.Fatalf("score %v does not match word counts: %v", r.Score, score)
+	}
+
+	// Multi-licensed files add up their parts counts
+	text := templateText(t, "mit.txt") + "\n\n" + templateText(t, "apache_2.0.txt")
+	r = m.Match([]byte(text))
+	if len(r.Parts) != 2 {
+		t.Fatalf("dual license not detected: %+v", r.Template)
+	}
+	if r.Common != r.Parts[0].Common+r.Parts[1].Common ||
+		r.TemplateWords != r.Parts[0].TemplateWords+r.Parts[1].TemplateWords {
+		t.Fatalf("unexpected dual license word counts: %+v", r)
+	}
+}
+
+func TestNoLicense(t *testing.T) {
+	m, err := NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		Text      string
+		NoLicense bool
+	}{
+		{"Copyright (c) 2020 ACME. All rights reserved.", true},
+		{`Copyright 2020 ACME, all rights reserved.
+
+No permission is granted to copy or distribute this software.`, true},
+		// Grants and unrelated texts are not "no license" files
+		{`Copyright 2020 ACME. All rights reserved.
+Licensed under the MIT license, see LICENSE.`, false},
+		{"Copyright 2020 ACME", false},
+		{templateText(t, "bsd_3_clause.txt"), false},
+	}
+	for _, test := range tests {
+		r := m.Match([]byte(test.Text))
+		noLicense := r.Template != nil && r.Template.Title == NoLicenseTitle
+		if noLicense != test.NoLicense {
+			t.Errorf("unexpected match for %q: %s (%v)", test.Text, r.Template.Title,
+				r.Score)
+		}
+	}
+}
+
+func TestMatchFamilies(t *testing.T) {
+	m, err := NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	terms := func(text string) string {
+		return text[strings.Index(text, "TERMS AND CONDITIONS"):strings.Index(text,
+			"END OF TERMS AND CONDITIONS")]
+	}
+	appendix := func(text string) string {
+		return text[strings.Index(text, "END OF TERMS AND CONDITIONS"):]
+	}
+	full := func(text string) string {
+		return text
+	}
+	tests := []struct {
+		Name    string
+		Extract func(string) string
+		Title   string
+	}{
+		{"gpl_2.0.txt", full, "GNU General Public License v2.0"},
+		{"gpl_3.0.txt", full, "GNU General Public License v3.0"},
+		{"lgpl_2.1.txt", full, "GNU Lesser General Public License v2.1"},
+		{"lgpl_3.0.txt", full, "GNU Lesser General Public License v3.0"},
+		{"agpl_3.0.txt", full, "GNU Affero General Public License v3.0"},
+		// GPL v3.0 terms are closer to the AGPL v3.0 ones, which only add a
+		// section, but the distinguishing words break the tie.
+		{"gpl_3.0.txt", terms, "GNU General Public License v3.0"},
+		{"agpl_3.0.txt", terms, "GNU Affero General Public License v3.0"},
+		{"lgpl_2.1.txt", appendix, "GNU Lesser General Public License v2.1"},
+	}
+	for _, test := range tests {
+		text := test.Extract(templateText(t, test.Name))
+		r := m.Match([]byte(text))
+		if r.Template.Title != test.Title {
+			t.Fatalf("unexpected match for %s: %s (%v) != %s", test.Name,
+				r.Template.Title, r.Score, test.Title)
+		}
+	}
+}
+
+func TestHeaderWords(t *testing.T) {
+	m, err := NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join("..", "testdata", "src", "colors", "red",
+		"LICENSE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := m.Match(data)
+	if len(r.MissingWords) != 0 {
+		t.Fatalf("unexpected missing words: %v", r.MissingWords)
+	}
+	if got := strings.Join(r.HeaderWords, ","); got != "mit,license" {
+		t.Fatalf("unexpected header words: %s", got)
+	}
+
+	// Authors listed below the copyright notice are not extra words.
+	data = append([]byte(`Copyright (c) 2015 The Red Authors
+    Jane Doe <jane@example.com>
+    https://example.com/red
+`), data[bytes.IndexByte(data, '\n'):]...)
+	r = m.Match(data)
+	if len(r.ExtraWords) != 0 {
+		t.Fatalf("unexpected extra words: %v", r.ExtraWords)
+	}
+	for _, w := range []string{"jane", "doe", "example.com", "https"} {
+		if !strings.Contains(","+strings.Join(r.HeaderWords, ",")+",", ","+w+",") {
+			t.Fatalf("%s not in header words: %v", w, r.HeaderWords)
+		}
+	}
+}
+
+// textWords returns the words of data, as indexed by Word.Pos.
+func textWords(data []byte) []string {
+	words := []string{}
+	for _, m := range reWords.FindAll(CleanLicenseData(data), -1) {
+		words = append(words, string(m))
+	}
+	return words
+}
+
+func TestISCReference(t *testing.T) {
+	m, err := NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// SPDX reference text, the ISC template differs by the optional title
+	// and the copyright holder replacing "the author".
+	license := `ISC License
+
+Copyright (c) 2004-2010 by Internet Systems Consortium, Inc. ("ISC")
+Copyright (c) 1995-2003 by Internet Software Consortium
+
+Permission to use, copy, modify, and/or distribute this software for any
+purpose with or without fee is hereby granted, provided that the above
+copyright notice and this permission notice appear in all copies.
+
+THE SOFTWARE IS PROVIDED "AS IS" AND ISC DISCLAIMS ALL WARRANTIES WITH REGARD
+TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY AND
+FITNESS. IN NO EVENT SHALL ISC BE LIABLE FOR ANY SPECIAL, DIRECT, INDIRECT, OR
+CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM LOSS OF USE, DATA
+OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS
+ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS
+SOFTWARE.
+`
+	r := m.Match([]byte(license))
+	if r.Template == nil || r.Template.SPDX != "ISC" || len(r.ExtraWords) != 0 ||
+		strings.Join(r.MissingWords, " ") != "author" {
+		t.Fatalf("unexpected match: %+v", r)
+	}
+}
+
+func TestWordPositions(t *testing.T) {
+	m, err := NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join("..", "testdata", "src", "colors", "red",
+		"LICENSE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = bytes.Replace(data, []byte("furnished"), []byte("purple furnished"), 1)
+	data = bytes.Replace(data, []byte("substantial"), []byte("large"), 1)
+	data = append(data, []byte("Handle wisely.\n")...)
+	r := m.Match(data)
+	check := func(name string, words []Word, text []string, wanted string) {
+		got := []string{}
+		for i, w := range words {
+			got = append(got, w.Text)
+			if i > 0 && w.Pos <= words[i-1].Pos {
+				t.Errorf("%s words are not sorted: %v", name, words)
+			}
+			if w.Pos >= len(text) || text[w.Pos] != w.Text {
+				t.Errorf("%s word %q is not at %d", name, w.Text, w.Pos)
+			}
+		}
+		if strings.Join(got, ",") != wanted {
+			t.Errorf("unexpected %s words: %v", name, got)
+		}
+	}
+	check("extra", r.Extra, textWords(data), strings.Join(r.ExtraWords, ","))
+	check("extra", r.Extra, textWords(data), "purple,large,handle,wisely")
+	check("missing", r.Missing, textWords([]byte(r.Template.Text)), "substantial")
+}
+
+func TestVersionWords(t *testing.T) {
+	words := makeWordSet([]byte("Apache License, Version 2.0, see " +
+		"http://www.apache.org/licenses/LICENSE-2.0."))
+	for _, w := range []string{"2.0", "www.apache.org", "license"} {
+		if _, ok := words[w]; !ok {
+			t.Fatalf("%s not in words: %v", w, words)
+		}
+	}
+	for _, w := range []string{"2", "0", "apache.org"} {
+		if _, ok := words[w]; ok {
+			t.Fatalf("%s in words: %v", w, words)
+		}
+	}
+	// Sentences missing a space after their period are not joined
+	words = makeWordSet([]byte("Use of this software.Permission is granted"))
+	for _, w := range []string{"software", "permission"} {
+		if _, ok := words[w]; !ok {
+			t.Fatalf("%s not in words: %v", w, words)
+		}
+	}
+
+	// Versions are reported as differing words, instead of fragments
+	// shared by other numbers of the text.
+	m, err := NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var apache *Template
+	for _, templ := range m.templates {
+		if templ.SPDX == "Apache-2.0" {
+			apache = templ
+		}
+	}
+	if apache == nil {
+		t.Fatal("Apache-2.0 template not found")
+	}
+	older, err := ParseTemplate("---\ntitle: Apache License 1.1\n---\n" +
+		strings.Replace(apache.Text, "2.0", "1.1", -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := matchTemplatesN([]byte(apache.Text),
+		newTemplateIndex([]*Template{older, apache}), 2)
+	if len(results) != 2 || results[0].Template != apache || results[0].Score != 1 {
+		t.Fatalf("Apache-2.0 did not match itself: %+v", results)
+	}
+	r := results[1]
+	if r.Score >= 1 || strings.Join(r.ExtraWords, ",") != "2.0" ||
+		strings.Join(r.MissingWords, ",") != "1.1" {
+		t.Fatalf("unexpected version differences: %v +%v -%v", r.Score,
+			r.ExtraWords, r.MissingWords)
+	}
+}
+
+func TestApacheVersions(t *testing.T) {
+	// The Apache License 1.1 is a BSD-like text sharing little more than its
+	// name and domain with the 2.0 one.
+	m, err := NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var apache *Template
+	for _, templ := range m.templates {
+		if templ.SPDX == "Apache-2.0" {
+			apache = templ
+		}
+	}
+	if apache == nil {
+		t.Fatal("Apache-2.0 template not found")
+	}
+	data, err := ioutil.ReadFile(filepath.Join("..", "testdata", "licenses",
+		"apache_1.1.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	older, err := ParseTemplate("---\ntitle: Apache License 1.1\n" +
+		"spdx: Apache-1.1\n---\n" + string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := newTemplateIndex([]*Template{older, apache})
+	for _, templ := range []*Template{older, apache} {
+		results := matchTemplatesN([]byte(templ.Text), index, 2)
+		if len(results) != 2 || results[0].Template != templ ||
+			results[0].Score != 1 {
+			t.Fatalf("%s did not match itself: %+v", templ.Title, results)
+		}
+		if r := results[1]; r.Score >= 0.5 {
+			t.Fatalf("%s matches %s at %v", templ.Title, r.Template.Title,
+				r.Score)
+		}
+	}
+}
+
+func TestDualLicenseOrLater(t *testing.T) {
+	m, err := NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mit := templateText(t, "mit.txt")
+	gpl := templateText(t, "gpl_3.0.txt")
+	tests := []struct {
+		License string
+		SPDX    string
+	}{
+		{mit + "\n" + gpl, "MIT OR GPL-3.0-only"},
+		{mit + "\nLicensed under version 3 of the GNU General Public License, or\n" +
+			"(at your option) any later version.\n" + gpl, "MIT OR GPL-3.0-or-later"},
+	}
+	for i, test := range tests {
+		r := m.Match([]byte(test.License))
+		if len(r.Parts) != 2 || r.SPDX() != test.SPDX {
+			t.Errorf("%d: unexpected SPDX expression: %q != %q", i, r.SPDX(), test.SPDX)
+		}
+	}
+}
+
+func TestDualBoundaries(t *testing.T) {
+	m, err := NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mit := templateText(t, "mit.txt")
+	gpl := templateText(t, "gpl_3.0.txt")
+	// Trailing words unknown to the template make it checked for being
+	// multi-licensed.
+	notice := "\nZorblax converts JPEG, PNG and WebP thumbnails quickly via " +
+		"multithreaded SIMD kernels, see docs/tuning.md for benchmarks.\n"
+	tests := []struct {
+		Name    string
+		License string
+		Dual    bool
+	}{
+		{"gpl", gpl, false},
+		{"gpl+notice", gpl + notice, false},
+		{"mit+gpl", mit + "\n" + gpl, true},
+	}
+	for _, test := range tests {
+		blocks := splitBlocks([]byte(test.License))
+		boundaries := dualBoundaries(blocks, m.index)
+		if dual := len(boundaries) > 0; dual != test.Dual {
+			t.Errorf("%s: unexpected split boundaries: %v", test.Name, boundaries)
+		}
+	}
+}
+
+func TestMatchTies(t *testing.T) {
+	parse := func(title, text string) *Template {
+		templ, err := ParseTemplate("---\ntitle: " + title + "\n---\n" + text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return templ
+	}
+	text := "Permission is granted to use this software for any purpose."
+	zeta := parse("Zeta License", text)
+	alpha := parse("Alpha License", text)
+	license := []byte(text + " Really.")
+	for _, templates := range [][]*Template{
+		{zeta, alpha},
+		{alpha, zeta},
+	} {
+		m := &Matcher{
+			templates: templates,
+			index:     newTemplateIndex(templates),
+		}
+		r := m.Match(license)
+		if r.Template != alpha || r.Score == 1 {
+			t.Fatalf("unexpected match: %+v", r)
+		}
+		results := m.MatchN(license, 2)
+		if len(results) != 2 || results[0].Template != alpha ||
+			results[0].Score != results[1].Score {
+			t.Fatalf("unexpected matches: %+v", results)
+		}
+		if r := m.MatchCosine(license); r.Template != alpha {
+			t.Fatalf("unexpected cosine match: %+v", r)
+		}
+	}
+}
+
+func TestIsOrLater(t *testing.T) {
+	gpl := &Template{
+		SPDX: "GPL-2.0-only",
+		Text: "either version 2, or (at your option) any later version.",
+	}
+	mit := &Template{SPDX: "MIT"}
+	tests := []struct {
+		Template *Template
+		License  string
+		OrLater  bool
+	}{
+		// Bare copies do not tell which versions are granted
+		{gpl, gpl.Text, false},
+		{gpl, "Copyright 2020 ACME.\n" + gpl.Text, false},
+		{gpl, "Licensed under version 2 of the GNU General Public License.\n" +
+			gpl.Text, false},
+		{gpl, "SPDX-License-Identifier: GPL-2.0-only\n" + gpl.Text, false},
+		{gpl, "// Licensed under the GPL version 2, or (at your option) any\n" +
+			"// later version.\n" + gpl.Text, true},
+		{gpl, "GPL version 2 or later. " + gpl.Text, true},
+		{mit, "any later version", false},
+		{nil, "any later version", false},
+	}
+	for _, test := range tests {
+		if got := isOrLater([]byte(test.License), test.Template); got != test.OrLater {
+			t.Errorf("%q: expected %v, got %v", test.License, test.OrLater, got)
+		}
+	}
+}
+
+// BenchmarkMatch matches testdata licenses against the full template set.
+func BenchmarkMatch(b *testing.B) {
+	m, err := NewMatcher()
+	if err != nil {
+		b.Fatal(err)
+	}
+	licenses := [][]byte{}
+	for _, pkg := range []string{"red", "blue", "yellow", "black", "khaki"} {
+		dir := filepath.Join("..", "testdata", "src", "colors", pkg)
+		for _, name := range []string{"LICENSE", "COPYRIGHT"} {
+			data, err := ioutil.ReadFile(filepath.Join(dir, name))
+			if err == nil {
+				licenses = append(licenses, data)
+			}
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, data := range licenses {
+			m.Match(data)
+		}
+	}
+}
+
+// BenchmarkMatchGPL matches a GPL-3.0 copy followed by a project notice, whose
+// extra words make it checked for being multi-licensed.
+func BenchmarkMatchGPL(b *testing.B) {
+	m, err := NewMatcher()
+	if err != nil {
+		b.Fatal(err)
+	}
+	var gpl string
+	for _, a := range assets.Assets {
+		if a.Name == "gpl_3.0.txt" {
+			gpl = strings.SplitN(a.Content, "---", 3)[2]
+		}
+	}
+	license := []byte(gpl + "\nZorblax converts JPEG, PNG and WebP thumbnails " +
+		"quickly via multithreaded SIMD kernels, see docs/tuning.md for " +
+		"benchmarks.\n")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Match(license)
+	}
+}
+
+func TestDocumentationTemplates(t *testing.T) {
+	// Creative Commons legalese must not be confused with GPL texts.
+	m, err := NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bySPDX := map[string]*Template{}
+	for _, templ := range m.templates {
+		bySPDX[templ.SPDX] = templ
+	}
+	files := map[string]string{
+		"CC-BY-4.0":    "cc_by_4.0.txt",
+		"CC-BY-SA-4.0": "cc_by_sa_4.0.txt",
+		"GPL-2.0-only": "gpl_2.0.txt",
+		"GPL-3.0-only": "gpl_3.0.txt",
+	}
+	for _, cc := range []string{"CC-BY-4.0", "CC-BY-SA-4.0"} {
+		for _, gpl := range []string{"GPL-2.0-only", "GPL-3.0-only"} {
+			ccText := []byte(templateText(t, files[cc]))
+			gplText := []byte(templateText(t, files[gpl]))
+			r := matchTemplate(ccText, newTemplateIndex([]*Template{bySPDX[gpl]}))
+			if r.Score > 0.5 {
+				t.Fatalf("%s scores %f against %s", cc, r.Score, gpl)
+			}
+			r = matchTemplate(gplText, newTemplateIndex([]*Template{bySPDX[cc]}))
+			if r.Score > 0.5 {
+				t.Fatalf("%s scores %f against %s", gpl, r.Score, cc)
+			}
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("..", "testdata", "src", "colors",
+		"black", "LICENSE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := Detect(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.SPDX() != "MIT OR Apache-2.0" {
+		t.Fatalf("unexpected match: %s", r.SPDX())
+	}
+	templates, err := LoadTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mit *Template
+	for _, templ := range templates {
+		if templ.SPDX == "MIT" {
+			mit = templ
+		}
+	}
+	r = DetectWith(data, []*Template{mit})
+	if r.Template != mit || len(r.Parts) != 0 || r.Score >= 0.9 {
+		t.Fatalf("unexpected match against MIT only: %+v", r)
+	}
+}
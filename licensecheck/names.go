@@ -0,0 +1,198 @@
+package licensecheck
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TemplateNames returns the lowercased names supplied template can be referred
+// by, like in license policies. SPDX identifiers are also available without
+// their "-only" or "-or-later" suffix.
+func TemplateNames(t *Template) []string {
+	names := []string{}
+	for _, n := range []string{t.Title, t.Nickname, t.SPDX} {
+		if n != "" {
+			names = append(names, strings.ToLower(n))
+		}
+	}
+	if t.SPDX != "" {
+		spdx := strings.ToLower(t.SPDX)
+		for _, suffix := range []string{"-only", "-or-later"} {
+			if strings.HasSuffix(spdx, suffix) {
+				names = append(names, strings.TrimSuffix(spdx, suffix))
+			}
+		}
+	}
+	return names
+}
+
+// MatchNames returns true if supplied template can be referred to by any of
+// names, case-insensitively, see TemplateNames.
+func MatchNames(t *Template, names []string) bool {
+	for _, n := range TemplateNames(t) {
+		for _, name := range names {
+			if n == strings.ToLower(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FindTemplate returns the template referred to by name, as SPDX identifier,
+// nickname or title, case-insensitively, or nil if there is none.
+func FindTemplate(templates []*Template, name string) *Template {
+	for _, t := range templates {
+		if MatchNames(t, []string{name}) {
+			return t
+		}
+	}
+	return nil
+}
+
+var (
+	reLicense = regexp.MustCompile(`(?i)^(?:` +
+		`((?:un)?licen[sc]e)|` +
+		`((?:un)?licen[sc]e\.(?:md|markdown|txt))|` +
+		`(copy(?:ing|right)(?:\.[^.]+)?)|` +
+		`(licen[sc]e\.[^.]+)` +
+		`)(?:\.gz)?$`)
+)
+
+var (
+	reNonAlnum      = regexp.MustCompile(`[^a-z0-9]+`)
+	reVersionedName = regexp.MustCompile(`^([a-z]+)-(\d+)\.(\d+)$`)
+
+	licenseNamesOnce sync.Once
+	licenseNames     map[string]bool
+)
+
+// normalizeLicenseName returns supplied license name lowercased and without
+// punctuation, so "Apache-2.0" and "apache_2.0" are the same.
+func normalizeLicenseName(name string) string {
+	return reNonAlnum.ReplaceAllString(strings.ToLower(name), "")
+}
+
+// loadLicenseNames returns the normalized names of embedded templates, see
+// TemplateNames, and their common spellings, like "GPLv2" for "GPL-2.0".
+func loadLicenseNames() map[string]bool {
+	licenseNamesOnce.Do(func() {
+		licenseNames = map[string]bool{}
+		templates, err := LoadTemplates()
+		if err != nil {
+			return
+		}
+		for _, t := range templates {
+			for _, name := range TemplateNames(t) {
+				licenseNames[normalizeLicenseName(name)] = true
+				m := reVersionedName.FindStringSubmatch(name)
+				if m == nil {
+					continue
+				}
+				licenseNames[m[1]+m[2]] = true
+				licenseNames[m[1]+"v"+m[2]] = true
+				if m[3] != "0" {
+					licenseNames[m[1]+"v"+m[2]+m[3]] = true
+				}
+			}
+		}
+	})
+	return licenseNames
+}
+
+// isLicenseName returns true if filename, without .txt or .md extension, is
+// the SPDX identifier, nickname or title of an embedded template, like
+// "Apache-2.0.txt" or "GPLv2".
+func isLicenseName(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	switch ext {
+	case ".txt", ".md", ".markdown":
+		filename = filename[:len(filename)-len(ext)]
+	case "":
+	default:
+		if _, err := strconv.ParseFloat(ext[1:], 64); err != nil {
+			// Not a version suffix, like "Apache-2.0"
+			return false
+		}
+	}
+	name := normalizeLicenseName(filename)
+	return name != "" && loadLicenseNames()[name]
+}
+
+// NameKind tells why a file name looks like a license file name, see
+// MatchLicenseName.
+type NameKind int
+
+const (
+	// NotLicenseName is the kind of names unlikely to be license files.
+	NotLicenseName NameKind = iota
+	// LicenseName is the kind of LICENSE, LICENCE and UNLICENSE, in any case.
+	LicenseName
+	// LicenseDocName is the kind of license names with a .md, .markdown or
+	// .txt extension, like LICENSE.md.
+	LicenseDocName
+	// CopyingName is the kind of COPYING and COPYRIGHT, possibly with an
+	// extension, like COPYING.LESSER.
+	CopyingName
+	// LicenseExtName is the kind of license names with another extension,
+	// like LICENSE.apache.
+	LicenseExtName
+	// TemplateName is the kind of names of embedded templates, like MIT or
+	// Apache-2.0.txt.
+	TemplateName
+)
+
+var nameKinds = []string{"none", "license", "license document", "copying",
+	"license with extension", "template name"}
+
+func (k NameKind) String() string {
+	if k < 0 || int(k) >= len(nameKinds) {
+		return "unknown"
+	}
+	return nameKinds[k]
+}
+
+// nameScores maps name kinds to their ScoreLicenseName score.
+var nameScores = []float64{0, 1.0, 0.9, 0.8, 0.7, 0.6}
+
+// MatchLicenseName returns the kind of supplied file name, telling why it
+// looks like a license file name, and its score, see ScoreLicenseName. Names
+// may have a trailing .gz extension.
+func MatchLicenseName(name string) (NameKind, float64) {
+	kind := NotLicenseName
+	m := reLicense.FindStringSubmatch(name)
+	switch {
+	case m == nil:
+		if isLicenseName(name) {
+			kind = TemplateName
+		}
+	case m[1] != "":
+		kind = LicenseName
+	case m[2] != "":
+		kind = LicenseDocName
+	case m[3] != "":
+		kind = CopyingName
+	case m[4] != "":
+		kind = LicenseExtName
+	}
+	return kind, nameScores[kind]
+}
+
+// ScoreLicenseName returns a factor between 0 and 1 weighting how likely
+// supplied filename is a license file, case-insensitively:
+//
+//   - 1.0 for LICENSE, LICENCE and UNLICENSE
+//   - 0.9 for the same with .md, .markdown or .txt extension, like LICENSE.md
+//   - 0.8 for COPYING and COPYRIGHT, with any extension, like COPYING.LESSER
+//   - 0.7 for LICENSE with another extension, like LICENSE.apache
+//   - 0.6 for files named after a template, like MIT or Apache-2.0.txt
+//   - 0 otherwise
+//
+// The kind of name is returned by MatchLicenseName.
+func ScoreLicenseName(name string) float64 {
+	_, score := MatchLicenseName(name)
+	return score
+}
@@ -0,0 +1,55 @@
+package licensecheck
+
+import (
+	"testing"
+)
+
+func TestScoreLicenseName(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Kind  NameKind
+		Score float64
+	}{
+		{"LICENSE", LicenseName, 1.0},
+		{"license", LicenseName, 1.0},
+		{"LICENCE", LicenseName, 1.0},
+		{"UNLICENSE", LicenseName, 1.0},
+		{"Unlicence", LicenseName, 1.0},
+		{"LICENSE.md", LicenseDocName, 0.9},
+		{"licence.txt", LicenseDocName, 0.9},
+		{"LICENSE.markdown", LicenseDocName, 0.9},
+		{"unlicense.md", LicenseDocName, 0.9},
+		{"COPYING", CopyingName, 0.8},
+		{"COPYRIGHT", CopyingName, 0.8},
+		{"COPYING.LESSER", CopyingName, 0.8},
+		{"copying.txt", CopyingName, 0.8},
+		{"Copyright.md", CopyingName, 0.8},
+		{"LICENSE.apache", LicenseExtName, 0.7},
+		{"LICENCE.BSD", LicenseExtName, 0.7},
+		{"LICENSE.gz", LicenseName, 1.0},
+		{"license.txt.gz", LicenseDocName, 0.9},
+		{"COPYING.gz", CopyingName, 0.8},
+		{"MIT", TemplateName, 0.6},
+		{"Apache-2.0", TemplateName, 0.6},
+		{"Apache-2.0.txt", TemplateName, 0.6},
+		{"BSD-3-Clause", TemplateName, 0.6},
+		{"GPLv2", TemplateName, 0.6},
+		{"gpl-3.0.md", TemplateName, 0.6},
+		{"LGPLv2.1", TemplateName, 0.6},
+		{"mit.go", NotLicenseName, 0},
+		{"Makefile", NotLicenseName, 0},
+		{"README.md", NotLicenseName, 0},
+		{"2.0.txt", NotLicenseName, 0},
+		{"LICENSE-MIT", NotLicenseName, 0},
+		{"LICENSE.txt.bak.old", NotLicenseName, 0},
+		{"COPYING.LESSER.txt", NotLicenseName, 0},
+	}
+	for _, test := range tests {
+		if score := ScoreLicenseName(test.Name); score != test.Score {
+			t.Errorf("%s scored %v, wanted %v", test.Name, score, test.Score)
+		}
+		if kind, _ := MatchLicenseName(test.Name); kind != test.Kind {
+			t.Errorf("%s is a %s name, wanted %s", test.Name, kind, test.Kind)
+		}
+	}
+}
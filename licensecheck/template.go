@@ -0,0 +1,241 @@
+package licensecheck
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Template is a well-known license text, and its word sets.
+type Template struct {
+	Title    string
+	Nickname string
+	// SPDX is the template SPDX license identifier, empty if unknown.
+	SPDX string
+	// Category is the license family, like "GPL" or "LGPL", empty if unknown.
+	Category string
+	// OSIApproved is true if the license is approved by the Open Source
+	// Initiative, as declared by the template.
+	OSIApproved bool
+	// Permissions, Conditions and Limitations are the license rules, like
+	// "commercial-use", "disclose-source" or "no-liability". Templates from
+	// choosealicense.com list them as "permitted", "required" and "forbidden".
+	Permissions []string
+	Conditions  []string
+	Limitations []string
+	Words       map[string]int
+	// Counts maps template words to their number of occurrences.
+	Counts map[string]int
+	// HeaderWords is the word set of the template header, see licenseHeader.
+	HeaderWords map[string]int
+	// Text is the template license text following the front matter,
+	// verbatim. Embedded templates texts weigh less than 300KB together.
+	Text string
+}
+
+// newlineReplacer converts CRLF and CR line endings to LF ones.
+var newlineReplacer = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// ParseTemplate parses a license template made of a front matter block,
+// delimited by "---" lines and defining the title, nickname and spdx keys,
+// followed by the license text. The optional category and osiApproved keys,
+// and the permissions, conditions and limitations lists of "- rule" lines, are
+// parsed too, other keys are ignored. A leading byte order mark is ignored
+// and line endings are normalized, so templates edited on Windows parse the
+// same.
+func ParseTemplate(content string) (*Template, error) {
+	t := Template{}
+	text := []byte{}
+	state := 0
+	// list points to the rules list being parsed, if any
+	var list *[]string
+	content = newlineReplacer.Replace(strings.TrimPrefix(content, "\ufeff"))
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if state == 0 {
+			if line == "---" {
+				state = 1
+			}
+		} else if state == 1 {
+			if line == "---" {
+				state = 2
+			} else if strings.HasPrefix(line, "- ") {
+				if list != nil {
+					*list = append(*list, strings.TrimSpace(line[2:]))
+				}
+			} else if line != "" {
+				list = nil
+				if strings.HasPrefix(line, "title:") {
+					t.Title = strings.TrimSpace(line[len("title:"):])
+				} else if strings.HasPrefix(line, "nickname:") {
+					t.Nickname = strings.TrimSpace(line[len("nickname:"):])
+				} else if strings.HasPrefix(line, "spdx:") {
+					t.SPDX = strings.TrimSpace(line[len("spdx:"):])
+				} else if strings.HasPrefix(line, "category:") {
+					t.Category = strings.TrimSpace(line[len("category:"):])
+				} else if strings.HasPrefix(line, "osiApproved:") {
+					value := strings.TrimSpace(line[len("osiApproved:"):])
+					approved, err := strconv.ParseBool(value)
+					if err != nil {
+						return nil, fmt.Errorf("invalid osiApproved value: %q", value)
+					}
+					t.OSIApproved = approved
+				} else if line == "permissions:" || line == "permitted:" {
+					list = &t.Permissions
+				} else if line == "conditions:" || line == "required:" {
+					list = &t.Conditions
+				} else if line == "limitations:" || line == "forbidden:" {
+					list = &t.Limitations
+				}
+			}
+		} else if state == 2 {
+			text = append(text, scanner.Bytes()...)
+			text = append(text, []byte("\n")...)
+		}
+	}
+	t.Words = makeWordSet(text)
+	t.Counts = makeWordCounts(text)
+	t.Text = string(text)
+	t.HeaderWords = makeWordSet(licenseHeader(text))
+	return &t, scanner.Err()
+}
+
+func parseAssets() ([]*Template, error) {
+	contents, err := readAssets()
+	if err != nil {
+		return nil, err
+	}
+	templates := []*Template{}
+	for _, content := range contents {
+		templ, err := ParseTemplate(content)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, templ)
+	}
+	return dedupeTemplates(templates), nil
+}
+
+// wordsKey returns a string identifying a template word set.
+func wordsKey(t *Template) string {
+	words := make([]string, 0, len(t.Words))
+	for w := range t.Words {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+// isCanonical returns true if a should be kept over b when both templates
+// have the same words: templates with an SPDX identifier first, then with a
+// nickname, then by TemplateKey.
+func isCanonical(a, b *Template) bool {
+	if (a.SPDX != "") != (b.SPDX != "") {
+		return a.SPDX != ""
+	}
+	if (a.Nickname != "") != (b.Nickname != "") {
+		return a.Nickname != ""
+	}
+	return TemplateKey(a) < TemplateKey(b)
+}
+
+// dedupeTemplates returns templates without the ones having the same words as
+// another, which would tie when matching and make the result depend on the
+// templates order. The canonical template of each group is kept, see
+// isCanonical, at the position of the first one.
+func dedupeTemplates(templates []*Template) []*Template {
+	kept := []*Template{}
+	seen := map[string]int{}
+	for _, t := range templates {
+		key := wordsKey(t)
+		if i, ok := seen[key]; ok {
+			if isCanonical(t, kept[i]) {
+				kept[i] = t
+			}
+			continue
+		}
+		seen[key] = len(kept)
+		kept = append(kept, t)
+	}
+	return kept
+}
+
+var (
+	templatesOnce sync.Once
+	templates     []*Template
+	templatesErr  error
+)
+
+// LoadTemplates returns the embedded license templates. They are parsed once
+// and shared by all callers, which must not modify them. Builds with the dev
+// tag parse the assets files from disk on every call instead.
+func LoadTemplates() ([]*Template, error) {
+	if devAssets {
+		return parseAssets()
+	}
+	templatesOnce.Do(func() {
+		templates, templatesErr = parseAssets()
+	})
+	return templates, templatesErr
+}
+
+// LoadTemplateDir parses the *.txt template files of supplied directory,
+// sorted by name.
+func LoadTemplateDir(dir string) ([]*Template, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	templates := []*Template{}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".txt" {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		templ, err := ParseTemplate(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse template %s: %s", path, err)
+		}
+		if templ.Title == "" {
+			return nil, fmt.Errorf("template %s has no title", path)
+		}
+		templates = append(templates, templ)
+	}
+	return templates, nil
+}
+
+// TemplateKey returns the name identifying a template when merging template
+// sets, its nickname or its title.
+func TemplateKey(t *Template) string {
+	if t.Nickname != "" {
+		return strings.ToLower(t.Nickname)
+	}
+	return strings.ToLower(t.Title)
+}
+
+// mergeTemplates returns base templates followed by extra ones. Extra
+// templates replace base templates with the same nickname, or title if they
+// have none.
+func mergeTemplates(base, extra []*Template) []*Template {
+	replaced := map[string]bool{}
+	for _, t := range extra {
+		replaced[TemplateKey(t)] = true
+	}
+	merged := []*Template{}
+	for _, t := range base {
+		if !replaced[TemplateKey(t)] {
+			merged = append(merged, t)
+		}
+	}
+	return append(merged, extra...)
+}
@@ -0,0 +1,237 @@
+package licensecheck
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pmezard/licenses/assets"
+)
+
+func TestParseTemplateSPDX(t *testing.T) {
+	templ, err := ParseTemplate(`---
+title: Some License
+nickname: Some
+spdx: Some-1.0
+---
+
+Some license text.
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if templ.Title != "Some License" || templ.Nickname != "Some" ||
+		templ.SPDX != "Some-1.0" {
+		t.Fatalf("unexpected template header: %+v", templ)
+	}
+	templ, err = ParseTemplate(`---
+title: Other License
+---
+
+Other license text.
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if templ.SPDX != "" {
+		t.Fatalf("unexpected SPDX identifier: %q", templ.SPDX)
+	}
+}
+
+func TestParseTemplateMetadata(t *testing.T) {
+	templ, err := ParseTemplate(`---
+title: Some License
+spdx: Some-1.0
+category: Copyleft
+osiApproved: true
+source: https://example.com/some-license
+permissions:
+  - commercial-use
+  - modifications
+
+conditions:
+  - disclose-source
+limitations:
+  - no-liability
+unknown:
+  - ignored
+---
+
+Some license text.
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if templ.Title != "Some License" || templ.SPDX != "Some-1.0" ||
+		templ.Category != "Copyleft" || !templ.OSIApproved {
+		t.Fatalf("unexpected template header: %+v", templ)
+	}
+	lists := [][]string{templ.Permissions, templ.Conditions, templ.Limitations}
+	wanted := [][]string{
+		{"commercial-use", "modifications"},
+		{"disclose-source"},
+		{"no-liability"},
+	}
+	if !reflect.DeepEqual(lists, wanted) {
+		t.Fatalf("unexpected rules: %q != %q", lists, wanted)
+	}
+	if templ.Text != "\nSome license text.\n" {
+		t.Fatalf("unexpected template text: %q", templ.Text)
+	}
+
+	// choosealicense.com rule keys
+	templates, err := LoadTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mit := FindTemplate(templates, "MIT")
+	if mit == nil {
+		t.Fatal("MIT template is missing")
+	}
+	if !reflect.DeepEqual(mit.Conditions, []string{"include-copyright"}) ||
+		len(mit.Permissions) != 5 ||
+		!reflect.DeepEqual(mit.Limitations, []string{"no-liability"}) {
+		t.Fatalf("unexpected MIT rules: %+v", mit)
+	}
+	gpl := FindTemplate(templates, "GPL-3.0-only")
+	if gpl == nil || gpl.Category != "GPL" {
+		t.Fatalf("unexpected GPL-3.0 template: %+v", gpl)
+	}
+
+	_, err = ParseTemplate("---\ntitle: Some License\nosiApproved: maybe\n---\n")
+	if err == nil {
+		t.Fatal("invalid osiApproved value was accepted")
+	}
+}
+
+func TestParseTemplateCRLF(t *testing.T) {
+	content := templateText(t, "mit.txt")
+	wanted, err := ParseTemplate("---\ntitle: MIT License\nspdx: MIT\n---\n" + content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Byte order mark, CRLF front matter and mixed line endings text
+	crlf := strings.Replace(content, "\n", "\r\n", 3)
+	templ, err := ParseTemplate("\ufeff---\r\ntitle: MIT License\r\nspdx: MIT\r\n" +
+		"---\r\n" + crlf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if templ.Title != "MIT License" || templ.SPDX != "MIT" {
+		t.Fatalf("unexpected template header: %+v", templ)
+	}
+	if templ.Text != wanted.Text || !reflect.DeepEqual(templ.Words, wanted.Words) {
+		t.Fatalf("unexpected template text:\n%q\n!=\n%q", templ.Text, wanted.Text)
+	}
+	// CRLF template files in directories
+	dir := t.TempDir()
+	err = ioutil.WriteFile(filepath.Join(dir, "mit-crlf.txt"), []byte("\ufeff---\r\n"+
+		"title: MIT CRLF\r\nnickname: CRLF\r\n---\r\n"+crlf), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates, err := LoadTemplateDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 1 || templates[0].Title != "MIT CRLF" ||
+		templates[0].Nickname != "CRLF" {
+		t.Fatalf("unexpected templates: %+v", templates)
+	}
+}
+
+func TestTemplateText(t *testing.T) {
+	var content string
+	for _, a := range assets.Assets {
+		if a.Name == "mit.txt" {
+			content = a.Content
+		}
+	}
+	// Text is everything after the front matter closing line.
+	body := content[strings.Index(content[4:], "\n---\n")+4+len("\n---\n"):]
+	templ, err := ParseTemplate(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if templ.Text != body {
+		t.Fatalf("template text does not match:\n%q\n!=\n%q", templ.Text, body)
+	}
+	if !strings.Contains(templ.Text, "Permission is hereby granted, free of charge") {
+		t.Fatalf("unexpected MIT text: %q", templ.Text)
+	}
+	reparsed, err := ParseTemplate("---\ntitle: MIT\n---\n" + templ.Text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reparsed.Text != templ.Text {
+		t.Fatalf("template text does not round-trip:\n%q\n!=\n%q",
+			reparsed.Text, templ.Text)
+	}
+}
+
+// templateText returns the body of the named template asset.
+func templateText(t *testing.T, name string) string {
+	for _, a := range assets.Assets {
+		if a.Name == name {
+			return strings.SplitN(a.Content, "---", 3)[2]
+		}
+	}
+	t.Fatalf("unknown asset: %s", name)
+	return ""
+}
+
+func TestMergeTemplates(t *testing.T) {
+	base := []*Template{
+		{Title: "MIT License"},
+		{Title: "GNU General Public License v3.0", Nickname: "GNU GPLv3"},
+	}
+	extra := []*Template{
+		{Title: "Custom GPL", Nickname: "gnu gplv3"},
+		{Title: "ACME"},
+	}
+	titles := []string{}
+	for _, t := range mergeTemplates(base, extra) {
+		titles = append(titles, t.Title)
+	}
+	if got := strings.Join(titles, ","); got != "MIT License,Custom GPL,ACME" {
+		t.Fatalf("unexpected merged templates: %s", got)
+	}
+}
+
+func TestDedupeTemplates(t *testing.T) {
+	parse := func(front, text string) *Template {
+		templ, err := ParseTemplate("---\n" + front + "\n---\n" + text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return templ
+	}
+	mit := parse("title: MIT License\nnickname: MIT\nspdx: MIT", "Permission granted.")
+	copied := parse("title: Copied License", "Permission granted.")
+	other := parse("title: Other License", "Permission denied.")
+	for _, templates := range [][]*Template{
+		{mit, copied, other},
+		{copied, mit, other},
+	} {
+		titles := []string{}
+		for _, t := range dedupeTemplates(templates) {
+			titles = append(titles, t.Title)
+		}
+		if got := strings.Join(titles, ","); got != "MIT License,Other License" {
+			t.Fatalf("unexpected deduplicated templates: %s", got)
+		}
+	}
+	contents, err := readAssets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates, err := LoadTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != len(contents) {
+		t.Fatal("embedded templates contain duplicates")
+	}
+}
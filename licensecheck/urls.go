@@ -0,0 +1,125 @@
+package licensecheck
+
+import (
+	"regexp"
+	"strings"
+)
+
+// licenseURLs maps well-known license URLs, trimmed with trimURL and
+// lowercased, to the SPDX identifier of their template.
+var licenseURLs = map[string]string{
+	"apache.org/licenses/license-2.0":                     "Apache-2.0",
+	"opensource.org/licenses/apache-2.0":                  "Apache-2.0",
+	"opensource.org/licenses/mit":                         "MIT",
+	"opensource.org/licenses/mit-license":                 "MIT",
+	"opensource.org/licenses/bsd-2-clause":                "BSD-2-Clause",
+	"opensource.org/licenses/bsd-license":                 "BSD-2-Clause",
+	"opensource.org/licenses/bsd-3-clause":                "BSD-3-Clause",
+	"opensource.org/licenses/isc":                         "ISC",
+	"opensource.org/licenses/isc-license":                 "ISC",
+	"opensource.org/licenses/mpl-2.0":                     "MPL-2.0",
+	"mozilla.org/mpl/2.0":                                 "MPL-2.0",
+	"eclipse.org/legal/epl-v10":                           "EPL-1.0",
+	"gnu.org/licenses/gpl-2.0":                            "GPL-2.0-only",
+	"gnu.org/licenses/old-licenses/gpl-2.0":               "GPL-2.0-only",
+	"gnu.org/licenses/gpl-3.0":                            "GPL-3.0-only",
+	"gnu.org/licenses/old-licenses/lgpl-2.1":              "LGPL-2.1-only",
+	"gnu.org/licenses/lgpl-3.0":                           "LGPL-3.0-only",
+	"gnu.org/licenses/agpl-3.0":                           "AGPL-3.0-only",
+	"creativecommons.org/licenses/by/4.0":                 "CC-BY-4.0",
+	"creativecommons.org/licenses/by-sa/4.0":              "CC-BY-SA-4.0",
+	"creativecommons.org/publicdomain/zero/1.0":           "CC0-1.0",
+	"creativecommons.org/publicdomain/zero/1.0/legalcode": "CC0-1.0",
+	"unlicense.org":                                       "Unlicense",
+	"wtfpl.net":                                           "WTFPL",
+}
+
+var (
+	// reURL matches http and https URLs.
+	reURL = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"'()\[\]]+`)
+	// reURLSuffix matches the trailing parts of URLs not telling licenses
+	// apart: punctuation ending sentences, slashes and page extensions.
+	reURLSuffix = regexp.MustCompile(`(?:[.,;:!?]|/|\.txt|\.html?|\.php|\.json)+$`)
+)
+
+// trimURL returns the host and path of rawURL, without scheme, "www." prefix,
+// query, fragment and reURLSuffix suffix.
+func trimURL(rawURL string) string {
+	u := rawURL
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+	}
+	if i := strings.IndexAny(u, "?#"); i >= 0 {
+		u = u[:i]
+	}
+	if strings.HasPrefix(strings.ToLower(u), "www.") {
+		u = u[4:]
+	}
+	return reURLSuffix.ReplaceAllString(u, "")
+}
+
+// urlLicense returns the SPDX identifier of the license at rawURL, or an empty
+// string if it is not a well-known license URL. Besides licenseURLs, SPDX
+// license list pages, like "https://spdx.org/licenses/MIT.html", refer to
+// their identifier.
+func urlLicense(rawURL string) string {
+	u := trimURL(rawURL)
+	if spdx, ok := licenseURLs[strings.ToLower(u)]; ok {
+		return spdx
+	}
+	const spdxPrefix = "spdx.org/licenses/"
+	if strings.HasPrefix(strings.ToLower(u), spdxPrefix) {
+		id := u[len(spdxPrefix):]
+		if id != "" && !strings.Contains(id, "/") {
+			return id
+		}
+	}
+	return ""
+}
+
+const (
+	// licenseURLMaxScore is the score under which license files are checked
+	// for referring to a well-known license URL instead of quoting its text.
+	licenseURLMaxScore = 0.5
+	// licenseURLMaxWords is the maximum number of distinct words of license
+	// files identified by URL. Longer ones are likely unknown licenses
+	// mentioning another one.
+	licenseURLMaxWords = shortLicenseMaxWords
+	// licenseURLScore is the score of licenses identified by URL. It passes
+	// the default confidence threshold, but files are not copies of their
+	// template.
+	licenseURLScore = 0.95
+)
+
+// matchLicenseURL returns the template of the well-known license URL found in
+// license, and the URL, or a nil template if there is none. Short files only
+// are considered, see licenseURLMaxWords, and files referring to several
+// licenses are ambiguous.
+func matchLicenseURL(license []byte, templates []*Template) (*Template, string) {
+	if len(makeWordSet(license)) > licenseURLMaxWords {
+		return nil, ""
+	}
+	var found *Template
+	foundURL := ""
+	for _, u := range reURL.FindAllString(string(license), -1) {
+		spdx := urlLicense(u)
+		if spdx == "" {
+			continue
+		}
+		var t *Template
+		for _, candidate := range templates {
+			if strings.EqualFold(candidate.SPDX, spdx) {
+				t = candidate
+				break
+			}
+		}
+		if t == nil {
+			continue
+		}
+		if found != nil && found != t {
+			return nil, ""
+		}
+		found, foundURL = t, strings.TrimRight(u, ".,;:!?")
+	}
+	return found, foundURL
+}
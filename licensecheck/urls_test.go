@@ -0,0 +1,75 @@
+package licensecheck
+
+import (
+	"testing"
+)
+
+func TestURLLicense(t *testing.T) {
+	tests := []struct {
+		URL  string
+		SPDX string
+	}{
+		{"http://www.apache.org/licenses/LICENSE-2.0", "Apache-2.0"},
+		{"https://www.apache.org/licenses/LICENSE-2.0.txt", "Apache-2.0"},
+		{"https://apache.org/licenses/LICENSE-2.0.html#apply", "Apache-2.0"},
+		{"https://opensource.org/licenses/MIT/", "MIT"},
+		{"https://www.gnu.org/licenses/gpl-3.0.html.", "GPL-3.0-only"},
+		{"https://spdx.org/licenses/ISC.html", "ISC"},
+		{"https://www.apache.org/licenses/", ""},
+		{"https://example.com/LICENSE", ""},
+	}
+	for _, test := range tests {
+		spdx := urlLicense(test.URL)
+		if spdx != test.SPDX {
+			t.Errorf("%s: got %q, wanted %q", test.URL, spdx, test.SPDX)
+		}
+	}
+}
+
+func TestMatchLicenseURL(t *testing.T) {
+	templates, err := LoadTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := newTemplateIndex(templates)
+	tests := []struct {
+		License string
+		SPDX    string
+		URL     string
+	}{
+		{
+			"Licensed under https://www.apache.org/licenses/LICENSE-2.0.",
+			"Apache-2.0",
+			"https://www.apache.org/licenses/LICENSE-2.0",
+		},
+		{
+			"MIT, see <https://opensource.org/licenses/MIT>",
+			"MIT",
+			"https://opensource.org/licenses/MIT",
+		},
+		// Ambiguous
+		{
+			"See https://opensource.org/licenses/MIT and " +
+				"https://www.apache.org/licenses/LICENSE-2.0",
+			"", "",
+		},
+		// Unknown URL
+		{"See https://example.com/LICENSE", "", ""},
+	}
+	for _, test := range tests {
+		m := matchTemplates([]byte(test.License), index)
+		spdx := ""
+		if m.URL != "" {
+			spdx = m.Template.SPDX
+		}
+		if spdx != test.SPDX || m.URL != test.URL {
+			t.Errorf("%q: got %q %q, wanted %q %q", test.License, spdx, m.URL,
+				test.SPDX, test.URL)
+		}
+	}
+	// Full texts are matched by text, even if they refer to a license URL
+	m := matchTemplates([]byte(templateText(t, "apache_2.0.txt")), index)
+	if m.URL != "" || m.Score < 0.99 {
+		t.Fatalf("full text matched by URL: %q %.2f", m.URL, m.Score)
+	}
+}
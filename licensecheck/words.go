@@ -0,0 +1,223 @@
+package licensecheck
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+var (
+	// reWords matches words. Version numbers like "2.0" and domains like
+	// "www.apache.org" are kept as single words, so they tell versioned
+	// licenses apart. Other dotted sequences are split, they are usually
+	// sentences missing a space after their period.
+	reWords = regexp.MustCompile(`\d+(?:\.\d+)+|` +
+		`(?:\w+\.)+(?:org|com|net|io)\b|[\w']+`)
+	// reCopyright matches copyright lines and captures the year or year
+	// ranges, and the holder.
+	reCopyright = regexp.MustCompile(
+		`(?i)\s*Copyright (?:©|\(c\)|\xC2\xA9)?\s*` +
+			`(\d{4}(?:[ \t]*[-,][ \t]*\d{4})*|\[year\])[ \t,]*(.*)`)
+	// reLicenseStart matches the phrases license terms usually start with.
+	// They end copyright notices when license texts lack line breaks, like
+	// minified or one-line licenses.
+	reLicenseStart = regexp.MustCompile(`(?i)\b(?:permission is hereby granted|` +
+		`permission to use, copy|redistribution and use|licensed under|` +
+		`this (?:software|program|library) is)\b`)
+)
+
+// findCopyrights returns the start and end offsets of the copyright notices
+// of data. A notice runs until the end of its line, or until the license terms
+// if they follow on the same line.
+func findCopyrights(data []byte) [][]int {
+	locs := reCopyright.FindAllIndex(data, -1)
+	for _, loc := range locs {
+		if m := reLicenseStart.FindIndex(data[loc[0]:loc[1]]); m != nil {
+			loc[1] = loc[0] + m[0]
+		}
+	}
+	return locs
+}
+
+// ExtractCopyrights returns the copyright lines of supplied license data, in
+// order of appearance and without duplicates.
+func ExtractCopyrights(data []byte) []string {
+	copyrights := []string{}
+	seen := map[string]bool{}
+	for _, loc := range findCopyrights(data) {
+		s := strings.TrimSpace(string(data[loc[0]:loc[1]]))
+		if !seen[s] {
+			seen[s] = true
+			copyrights = append(copyrights, s)
+		}
+	}
+	return copyrights
+}
+
+// ParseCopyright returns the years and holder of a copyright line, or empty
+// strings if it is not one.
+func ParseCopyright(line string) (string, string) {
+	m := reCopyright.FindStringSubmatch(line)
+	if m == nil {
+		return "", ""
+	}
+	return m[1], strings.TrimSpace(m[2])
+}
+
+// typographyReplacer converts typographic quotes, dashes and spaces, common in
+// license texts copied from web pages, to their ASCII equivalent.
+var typographyReplacer = strings.NewReplacer(
+	"\u2018", "'", "\u2019", "'", "\u201a", "'", "\u201b", "'", "\u2032", "'",
+	"\u201c", `"`, "\u201d", `"`, "\u201e", `"`, "\u201f", `"`, "\u2033", `"`,
+	"\u2010", "-", "\u2011", "-", "\u2012", "-", "\u2013", "-", "\u2014", "-",
+	"\u2015", "-",
+	"\u00a0", " ", "\u2002", " ", "\u2003", " ", "\u2009", " ", "\u202f", " ",
+)
+
+// normalizeTypography returns data with typographic characters replaced by
+// ASCII ones, see typographyReplacer. Otherwise, reWords would split or drop
+// words containing them.
+func normalizeTypography(data []byte) []byte {
+	for _, c := range data {
+		if c >= 0x80 {
+			return []byte(typographyReplacer.Replace(string(data)))
+		}
+	}
+	return data
+}
+
+// CleanLicenseData normalizes license data before splitting it into words. It
+// is lowercased, and typography and copyright notices are removed.
+func CleanLicenseData(data []byte) []byte {
+	data = normalizeTypography(data)
+	data = bytes.ToLower(data)
+	locs := findCopyrights(data)
+	if len(locs) == 0 {
+		return data
+	}
+	cleaned := make([]byte, 0, len(data))
+	start := 0
+	for _, loc := range locs {
+		cleaned = append(cleaned, data[start:loc[0]]...)
+		if loc[1] < len(data) && data[loc[1]] != '\n' {
+			// Do not glue the words around notices ending mid-line
+			cleaned = append(cleaned, ' ')
+		}
+		start = loc[1]
+	}
+	return append(cleaned, data[start:]...)
+}
+
+// headerMaxBlocks is the number of leading blocks of a license text searched
+// for copyright notices by licenseHeader.
+const headerMaxBlocks = 3
+
+// licenseHeader returns the leading blank-line separated blocks of a license
+// text up to the last one containing a copyright notice, among the first
+// headerMaxBlocks ones. They usually hold the license title, the copyright
+// notices and the authors names, emails or URLs on the following lines. It
+// returns nil if there is no such copyright notice.
+func licenseHeader(data []byte) []byte {
+	blocks := splitBlocks(data)
+	if len(blocks) > headerMaxBlocks {
+		blocks = blocks[:headerMaxBlocks]
+	}
+	for i := len(blocks) - 1; i >= 0; i-- {
+		if reCopyright.Match(blocks[i]) {
+			return bytes.Join(blocks[:i+1], []byte("\n"))
+		}
+	}
+	return nil
+}
+
+// splitHeaderWords returns the words of supplied list not in header, then the
+// ones in header.
+func splitHeaderWords(words []Word, header map[string]int) ([]Word, []Word) {
+	kept := []Word{}
+	removed := []Word{}
+	for _, w := range words {
+		if _, ok := header[w.Text]; ok {
+			removed = append(removed, w)
+		} else {
+			kept = append(kept, w)
+		}
+	}
+	return kept, removed
+}
+
+// stopwords lists very common English words, found in nearly every license.
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "in": true, "is": true,
+	"it": true, "of": true, "on": true, "or": true, "that": true, "the": true,
+	"this": true, "to": true, "with": true,
+}
+
+// removeStopwords returns supplied word set without stopwords.
+func removeStopwords(words map[string]int) map[string]int {
+	kept := make(map[string]int, len(words))
+	for w, pos := range words {
+		if !stopwords[w] {
+			kept[w] = pos
+		}
+	}
+	return kept
+}
+
+func makeWordSet(data []byte) map[string]int {
+	words := map[string]int{}
+	data = CleanLicenseData(data)
+	matches := reWords.FindAll(data, -1)
+	for i, m := range matches {
+		s := string(m)
+		if _, ok := words[s]; !ok {
+			// Non-matching words are likely in the license header, to mention
+			// copyrights and authors. Try to preserve the initial sequences,
+			// to display them later.
+			words[s] = i
+		}
+	}
+	return words
+}
+
+// makeWordCounts returns the number of occurrences of each word of data.
+func makeWordCounts(data []byte) map[string]int {
+	counts := map[string]int{}
+	data = CleanLicenseData(data)
+	for _, m := range reWords.FindAll(data, -1) {
+		counts[string(m)]++
+	}
+	return counts
+}
+
+// stubMaxWords is the number of words above which license files are not
+// considered pointer stubs, see IsPointerStub.
+const stubMaxWords = 40
+
+var (
+	// reStubVerb matches the words pointer stubs use to redirect readers.
+	reStubVerb = regexp.MustCompile(`(?i)\b(?:see|refer|found|located)\b`)
+	// reStubTarget matches what pointer stubs redirect to: URLs, other
+	// license files or parent directories.
+	reStubTarget = regexp.MustCompile(`(?i)https?://|\b(?:(?:un)?licen[sc]e|` +
+		`copying|root|parent|top[- ]level)\b`)
+)
+
+// IsPointerStub returns true if license data is a short note redirecting to
+// the actual license, like "See the COPYING file in the root directory" or
+// "See https://example.com/license". Notes referring to a well-known license
+// URL declare their license, see matchLicenseURL, and are not stubs.
+func IsPointerStub(data []byte) bool {
+	if len(reWords.FindAll(data, stubMaxWords)) >= stubMaxWords {
+		return false
+	}
+	if !reStubVerb.Match(data) || !reStubTarget.Match(data) {
+		return false
+	}
+	for _, u := range reURL.FindAllString(string(data), -1) {
+		if urlLicense(u) != "" {
+			return false
+		}
+	}
+	return true
+}
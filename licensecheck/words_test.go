@@ -0,0 +1,151 @@
+package licensecheck
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCleanLicenseData(t *testing.T) {
+	data := `The MIT License (MIT)
+
+	Copyright (c) 2013 Ben Johnson
+	
+	Some other lines.
+	And more.
+	`
+	cleaned := string(CleanLicenseData([]byte(data)))
+	wanted := "the mit license (mit)\n\t\n\tsome other lines.\n\tand more.\n\t"
+	if wanted != cleaned {
+		t.Fatalf("license data mismatch: %q\n!=\n%q", cleaned, wanted)
+	}
+}
+
+func TestExtractCopyrights(t *testing.T) {
+	data := `Some License
+
+Copyright (C) 2010-2018 Foo, Bar
+Copyright © 2015 Zoé Smith
+  copyright 2009, 2011 The Authors. All rights reserved.
+Copyright (C) 2010-2018 Foo, Bar
+
+Permission is granted.
+`
+	wanted := []struct {
+		Text   string
+		Years  string
+		Holder string
+	}{
+		{"Copyright (C) 2010-2018 Foo, Bar", "2010-2018", "Foo, Bar"},
+		{"Copyright © 2015 Zoé Smith", "2015", "Zoé Smith"},
+		{"copyright 2009, 2011 The Authors. All rights reserved.", "2009, 2011",
+			"The Authors. All rights reserved."},
+	}
+	copyrights := ExtractCopyrights([]byte(data))
+	if len(copyrights) != len(wanted) {
+		t.Fatalf("unexpected copyrights: %q", copyrights)
+	}
+	for i, w := range wanted {
+		if copyrights[i] != w.Text {
+			t.Fatalf("unexpected copyright: %q != %q", copyrights[i], w.Text)
+		}
+		years, holder := ParseCopyright(copyrights[i])
+		if years != w.Years || holder != w.Holder {
+			t.Fatalf("unexpected copyright parts for %q: %q, %q", copyrights[i],
+				years, holder)
+		}
+	}
+}
+
+func TestTypography(t *testing.T) {
+	m, err := NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mit, err := ioutil.ReadFile(filepath.Join("..", "testdata", "src", "colors", "red",
+		"LICENSE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := []string{string(mit)}
+	for _, templ := range m.templates {
+		if templ.SPDX == "Apache-2.0" {
+			texts = append(texts, templ.Text)
+		}
+	}
+	if len(texts) != 2 {
+		t.Fatal("Apache-2.0 template not found")
+	}
+	smart := strings.NewReplacer("'", "’", `"AS IS"`, "“AS IS”",
+		"-", "–", " of ", " of ")
+	for _, text := range texts {
+		plain := m.Match([]byte(text))
+		if plain.Template == nil {
+			t.Fatal("text did not match")
+		}
+		got := m.Match([]byte(smart.Replace(text)))
+		if got.Template != plain.Template || got.Score != plain.Score {
+			t.Fatalf("typography changed match: %s %f != %s %f",
+				got.Template.Title, got.Score, plain.Template.Title, plain.Score)
+		}
+	}
+}
+
+func TestOneLineLicense(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("..", "testdata", "src", "colors",
+		"azure", "LICENSE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleaned := string(CleanLicenseData(data))
+	if strings.Contains(cleaned, "jane") || strings.Contains(cleaned, "2016") {
+		t.Fatalf("copyright was not stripped: %q", cleaned)
+	}
+	if !strings.HasPrefix(cleaned, " permission is hereby granted") {
+		t.Fatalf("license terms were stripped: %q", cleaned)
+	}
+	copyrights := ExtractCopyrights(data)
+	if len(copyrights) != 1 || copyrights[0] != "Copyright (c) 2016 Jane Doe" {
+		t.Fatalf("unexpected copyrights: %q", copyrights)
+	}
+
+	m, err := NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := m.Match(data)
+	if r.Template == nil || r.Template.SPDX != "MIT" || r.Score < 0.95 {
+		t.Fatalf("one-line license did not match MIT: %+v", r)
+	}
+	if len(r.ExtraWords) != 0 {
+		t.Fatalf("unexpected extra words: %v", r.ExtraWords)
+	}
+}
+
+func TestIsPointerStub(t *testing.T) {
+	mit, err := ioutil.ReadFile(filepath.Join("..", "testdata", "src", "colors", "red",
+		"LICENSE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		Text string
+		Stub bool
+	}{
+		{"See the COPYING file in the root directory.", true},
+		{"See https://example.com/license for licensing terms.", true},
+		{"The license can be found in the parent directory.", true},
+		{"Licensed under MIT, refer to LICENSE.txt.", true},
+		{"This is free and unencumbered software.", false},
+		{"See you later.", false},
+		{"Licensed under the Apache License, Version 2.0. See " +
+			"http://www.apache.org/licenses/LICENSE-2.0 for details.", false},
+		{string(mit), false},
+	}
+	for _, test := range tests {
+		if got := IsPointerStub([]byte(test.Text)); got != test.Stub {
+			t.Errorf("%q: expected %v, got %v", test.Text, test.Stub, got)
+		}
+	}
+}
@@ -1,403 +1,989 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"sort"
 	"strings"
-	"text/tabwriter"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/pmezard/licenses/assets"
+	"github.com/pmezard/licenses/licensecheck"
 )
 
-type Template struct {
-	Title    string
-	Nickname string
-	Words    map[string]int
-}
-
-func parseTemplate(content string) (*Template, error) {
-	t := Template{}
-	text := []byte{}
-	state := 0
-	scanner := bufio.NewScanner(strings.NewReader(content))
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if state == 0 {
-			if line == "---" {
-				state = 1
-			}
-		} else if state == 1 {
-			if line == "---" {
-				state = 2
-			} else {
-				if strings.HasPrefix(line, "title:") {
-					t.Title = strings.TrimSpace(line[len("title:"):])
-				} else if strings.HasPrefix(line, "nickname:") {
-					t.Nickname = strings.TrimSpace(line[len("nickname:"):])
-				}
-			}
-		} else if state == 2 {
-			text = append(text, scanner.Bytes()...)
-			text = append(text, []byte("\n")...)
+// goEnv configures the go commands listing packages.
+type goEnv struct {
+	// GOPATH, GOOS and GOARCH override the process environment when set.
+	GOPATH string
+	GOOS   string
+	GOARCH string
+	// GOWORK overrides the go.work file of the process environment, or the
+	// one found in the current directory and its parents, when set. "off"
+	// disables workspace mode.
+	GOWORK string
+	// Tags lists additional build tags.
+	Tags []string
+	// Warnings receives the standard error output of go commands which
+	// succeeded, like module download notes. It is discarded when nil.
+	Warnings io.Writer
+	// Retries is the number of times go commands failing with transient
+	// errors are run again, see isTransientError.
+	Retries int
+}
+
+// fixEnv returns a copy of the process environment where GOPATH, GOOS, GOARCH
+// and GOWORK are adjusted to supplied values. It returns nil if none is set.
+// Other variables, like GOFLAGS, are left unchanged.
+func fixEnv(env goEnv) []string {
+	vars := map[string]string{
+		"GOPATH": env.GOPATH,
+		"GOOS":   env.GOOS,
+		"GOARCH": env.GOARCH,
+		"GOWORK": env.GOWORK,
+	}
+	kept := []string{}
+	for _, name := range []string{"GOPATH", "GOOS", "GOARCH", "GOWORK"} {
+		if vars[name] != "" {
+			kept = append(kept, name+"="+vars[name])
+		} else {
+			delete(vars, name)
 		}
 	}
-	t.Words = makeWordSet(text)
-	return &t, scanner.Err()
+	if len(kept) == 0 {
+		return nil
+	}
+	for _, e := range os.Environ() {
+		if _, ok := vars[strings.SplitN(e, "=", 2)[0]]; !ok {
+			kept = append(kept, e)
+		}
+	}
+	return kept
 }
 
-func loadTemplates() ([]*Template, error) {
-	templates := []*Template{}
-	for _, a := range assets.Assets {
-		templ, err := parseTemplate(a.Content)
-		if err != nil {
-			return nil, err
-		}
-		templates = append(templates, templ)
+// goBin is the go command executable, replaced by tests.
+var goBin = "go"
+
+// goCommands counts the go commands created by goCommand, to keep track of
+// how many subprocesses listing licenses requires.
+var goCommands int32
+
+// goCommand returns a go command running supplied subcommand and arguments in
+// env. Build tags are passed right after the subcommand. The command is killed
+// when ctx is done.
+func goCommand(ctx context.Context, env goEnv, args ...string) *exec.Cmd {
+	if len(env.Tags) > 0 && len(args) > 0 {
+		args = append([]string{args[0], "-tags", strings.Join(env.Tags, ",")},
+			args[1:]...)
 	}
-	return templates, nil
+	atomic.AddInt32(&goCommands, 1)
+	cmd := exec.CommandContext(ctx, goBin, args...)
+	cmd.Env = fixEnv(env)
+	return cmd
 }
 
-var (
-	reWords     = regexp.MustCompile(`[\w']+`)
-	reCopyright = regexp.MustCompile(
-		`(?i)\s*Copyright (?:©|\(c\)|\xC2\xA9)?\s*(?:\d{4}|\[year\]).*`)
-)
+// MissingError is returned when listed packages cannot be found or have no
+// buildable Go source files.
+type MissingError struct {
+	Err string
+}
+
+func (err *MissingError) Error() string {
+	return err.Err
+}
+
+// BuildError is returned when listed packages exist but cannot be loaded, for
+// instance because of syntax errors or import cycles.
+type BuildError struct {
+	Package string
+	Err     string
+}
+
+func (err *BuildError) Error() string {
+	return fmt.Sprintf("cannot load %s: %s", err.Package, err.Err)
+}
+
+type PkgError struct {
+	Err string
+}
+
+// ModuleInfo describes the module containing a package, in module mode.
+type ModuleInfo struct {
+	Path    string
+	Version string
+	Dir     string
+	Main    bool
+	// Replace is the module replacing this one according to go.mod replace
+	// directives, if any. Its Path is a directory for local replacements.
+	Replace *ModuleInfo
+}
 
-func cleanLicenseData(data []byte) []byte {
-	data = bytes.ToLower(data)
-	data = reCopyright.ReplaceAll(data, nil)
-	return data
+// replacement returns the effective module of a replaced package module, as
+// a "path@version" string or a directory, or an empty string.
+func replacement(info *PkgInfo) string {
+	if info.Module == nil || info.Module.Replace == nil {
+		return ""
+	}
+	r := info.Module.Replace
+	if r.Version == "" {
+		return r.Path
+	}
+	return r.Path + "@" + r.Version
 }
 
-func makeWordSet(data []byte) map[string]int {
-	words := map[string]int{}
-	data = cleanLicenseData(data)
-	matches := reWords.FindAll(data, -1)
-	for i, m := range matches {
-		s := string(m)
-		if _, ok := words[s]; !ok {
-			// Non-matching words are likely in the license header, to mention
-			// copyrights and authors. Try to preserve the initial sequences,
-			// to display them later.
-			words[s] = i
+// collapseModules returns infos with the packages of every module replaced by
+// a single entry named after the module, whose license is looked up in the
+// module root directory only. Packages outside of modules, like GOPATH or
+// standard ones, and packages which failed to load are left unchanged.
+func collapseModules(infos []*PkgInfo) []*PkgInfo {
+	collapsed := []*PkgInfo{}
+	seen := map[string]bool{}
+	for _, info := range infos {
+		m := info.Module
+		if info.Error != nil || m == nil || m.Dir == "" {
+			collapsed = append(collapsed, info)
+			continue
+		}
+		key := m.Path + "@" + m.Version
+		if seen[key] {
+			continue
 		}
+		seen[key] = true
+		collapsed = append(collapsed, &PkgInfo{
+			Name:       m.Path,
+			Dir:        m.Dir,
+			Root:       info.Root,
+			ImportPath: m.Path,
+			DepOnly:    info.DepOnly,
+			Module:     m,
+		})
 	}
-	return words
+	sort.Sort(sortedPkgInfos(collapsed))
+	return collapsed
 }
 
-type Word struct {
-	Text string
-	Pos  int
+// modulePath returns the path of the module of a package, or an empty string
+// in GOPATH mode.
+func modulePath(info *PkgInfo) string {
+	if info.Module == nil {
+		return ""
+	}
+	return info.Module.Path
 }
 
-type sortedWords []Word
+// moduleVersion returns the version of the module of a package, or an empty
+// string in GOPATH mode or for the main module.
+func moduleVersion(info *PkgInfo) string {
+	if info.Module == nil {
+		return ""
+	}
+	return info.Module.Version
+}
 
-func (s sortedWords) Len() int {
+type PkgInfo struct {
+	Name         string
+	Dir          string
+	Root         string
+	ImportPath   string
+	GoFiles      []string
+	CgoFiles     []string
+	Deps         []string
+	Standard     bool
+	DepOnly      bool
+	TestImports  []string
+	XTestImports []string
+	Module       *ModuleInfo
+	Error        *PkgError
+}
+
+type sortedPkgInfos []*PkgInfo
+
+func (s sortedPkgInfos) Len() int {
 	return len(s)
 }
 
-func (s sortedWords) Swap(i, j int) {
+func (s sortedPkgInfos) Swap(i, j int) {
 	s[i], s[j] = s[j], s[i]
 }
 
-func (s sortedWords) Less(i, j int) bool {
-	return s[i].Pos < s[j].Pos
-}
-
-type MatchResult struct {
-	Template     *Template
-	Score        float64
-	ExtraWords   []string
-	MissingWords []string
-}
-
-func sortAndReturnWords(words []Word) []string {
-	sort.Sort(sortedWords(words))
-	tokens := []string{}
-	for _, w := range words {
-		tokens = append(tokens, w.Text)
-	}
-	return tokens
-}
-
-// matchTemplates returns the best license template matching supplied data,
-// its score between 0 and 1 and the list of words appearing in license but not
-// in the matched template.
-func matchTemplates(license []byte, templates []*Template) MatchResult {
-	bestScore := float64(-1)
-	var bestTemplate *Template
-	bestExtra := []Word{}
-	bestMissing := []Word{}
-	words := makeWordSet(license)
-	for _, t := range templates {
-		extra := []Word{}
-		missing := []Word{}
-		common := 0
-		for w, pos := range words {
-			_, ok := t.Words[w]
-			if ok {
-				common++
-			} else {
-				extra = append(extra, Word{
-					Text: w,
-					Pos:  pos,
-				})
-			}
-		}
-		for w, pos := range t.Words {
-			if _, ok := words[w]; !ok {
-				missing = append(missing, Word{
-					Text: w,
-					Pos:  pos,
-				})
-			}
-		}
-		score := 2 * float64(common) / (float64(len(words)) + float64(len(t.Words)))
-		if score > bestScore {
-			bestScore = score
-			bestTemplate = t
-			bestMissing = missing
-			bestExtra = extra
-		}
+func (s sortedPkgInfos) Less(i, j int) bool {
+	return s[i].ImportPath < s[j].ImportPath
+}
+
+// packageError returns the typed error of a package listed by goList, or nil.
+// Packages without directory or Go files are missing, the others are broken.
+func packageError(info *PkgInfo) error {
+	if info.Error == nil {
+		return nil
 	}
-	return MatchResult{
-		Template:     bestTemplate,
-		Score:        bestScore,
-		ExtraWords:   sortAndReturnWords(bestExtra),
-		MissingWords: sortAndReturnWords(bestMissing),
+	if info.Dir == "" || len(info.GoFiles)+len(info.CgoFiles) == 0 {
+		return &MissingError{Err: info.Error.Err}
 	}
+	return &BuildError{Package: info.ImportPath, Err: info.Error.Err}
 }
 
-// fixEnv returns a copy of the process environment where GOPATH is adjusted to
-// supplied value. It returns nil if gopath is empty.
-func fixEnv(gopath string) []string {
-	if gopath == "" {
-		return nil
+// retryDelay is the delay before retrying a failed go command, doubled after
+// every attempt. It is replaced by tests.
+var retryDelay = time.Second
+
+// reTransientError matches go command errors likely to succeed when retried,
+// like network failures while downloading modules. It matches the error
+// phrases of the net packages and proxy status lines only, as package paths
+// and versions like "github.com/x/timeout" or "v1.502.0" may contain any word.
+var reTransientError = regexp.MustCompile(`\bdial tcp\b|\bi/o timeout\b|` +
+	`TLS handshake timeout|connection reset by peer|connection refused|` +
+	`broken pipe|unexpected EOF|[Tt]emporary failure in name resolution|` +
+	`\b50(?:2 Bad Gateway|3 Service Unavailable|4 Gateway Timeout)\b`)
+
+// isTransientError returns true if supplied go command error output looks
+// like a transient failure, like network errors or proxy 5xx responses,
+// rather than a deterministic one, like a missing package.
+func isTransientError(stderr string) bool {
+	return reTransientError.MatchString(stderr)
+}
+
+// goList runs "go list -e -json" with additional flags on supplied packages or
+// package expressions and returns their descriptions. Package errors are
+// reported in the PkgInfo Error field, dependency errors in the Error field of
+// the listed dependencies. The returned error is only set when go list itself
+// fails, or ctx error if it is done. Transient failures are retried
+// env.Retries times. Standard error output is kept apart from the JSON stream
+// and forwarded to env.Warnings.
+func goList(ctx context.Context, env goEnv, flags, pkgs []string) ([]*PkgInfo,
+	error) {
+
+	args := []string{"list", "-e", "-json"}
+	args = append(args, flags...)
+	args = append(args, pkgs...)
+	var out []byte
+	stderr := &bytes.Buffer{}
+	for attempt := 0; ; attempt++ {
+		cmd := goCommand(ctx, env, args...)
+		stderr.Reset()
+		cmd.Stderr = stderr
+		var err error
+		out, err = cmd.Output()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err == nil {
+			break
+		}
+		if attempt >= env.Retries || !isTransientError(stderr.String()) {
+			return nil, fmt.Errorf("'go %s' failed with:\n%s",
+				strings.Join(args, " "), stderr.String())
+		}
+		delay := retryDelay << uint(attempt)
+		if env.Warnings != nil {
+			fmt.Fprintf(env.Warnings, "'go list' failed, retrying in %s:\n%s",
+				delay, stderr.String())
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
 	}
-	kept := []string{
-		"GOPATH=" + gopath,
+	if env.Warnings != nil && stderr.Len() > 0 {
+		env.Warnings.Write(stderr.Bytes())
 	}
-	for _, env := range os.Environ() {
-		if !strings.HasPrefix(env, "GOPATH=") {
-			kept = append(kept, env)
+	infos := []*PkgInfo{}
+	decoder := json.NewDecoder(bytes.NewBuffer(out))
+	for {
+		info := &PkgInfo{}
+		err := decoder.Decode(info)
+		if err == io.EOF {
+			break
 		}
+		if err != nil {
+			return nil, fmt.Errorf("could not decode 'go %s' output: %s",
+				strings.Join(args, " "), err)
+		}
+		infos = append(infos, info)
 	}
-	return kept
+	return infos, nil
 }
 
-type MissingError struct {
-	Err string
-}
-
-func (err *MissingError) Error() string {
-	return err.Err
+// listRoots lists packages matching supplied package expressions and returns
+// the first error of the ones which cannot be loaded.
+func listRoots(ctx context.Context, env goEnv, pkgs []string) ([]*PkgInfo, error) {
+	infos, err := goList(ctx, env, nil, pkgs)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		if err := packageError(info); err != nil {
+			return nil, err
+		}
+	}
+	return infos, nil
 }
 
 // expandPackages takes a list of package or package expressions and invoke go
 // list to expand them to packages. In particular, it handles things like "..."
 // and ".".
-func expandPackages(gopath string, pkgs []string) ([]string, error) {
-	args := []string{"list"}
-	args = append(args, pkgs...)
-	cmd := exec.Command("go", args...)
-	cmd.Env = fixEnv(gopath)
-	out, err := cmd.CombinedOutput()
+func expandPackages(ctx context.Context, env goEnv, pkgs []string) ([]string,
+	error) {
+
+	infos, err := listRoots(ctx, env, pkgs)
 	if err != nil {
-		output := string(out)
-		if strings.Contains(output, "cannot find package") ||
-			strings.Contains(output, "no buildable Go source files") {
-			return nil, &MissingError{Err: output}
-		}
-		return nil, fmt.Errorf("'go %s' failed with:\n%s",
-			strings.Join(args, " "), output)
+		return nil, err
 	}
 	names := []string{}
-	for _, s := range strings.Split(string(out), "\n") {
-		s = strings.TrimSpace(s)
-		if s != "" {
-			names = append(names, s)
-		}
+	for _, info := range infos {
+		names = append(names, info.ImportPath)
 	}
 	return names, nil
 }
 
-func listPackagesAndDeps(gopath string, pkgs []string) ([]string, error) {
-	pkgs, err := expandPackages(gopath, pkgs)
+// listPackagesAndDeps returns the sorted list of supplied packages and their
+// dependencies. With tests, packages imported by the tests of supplied
+// packages and their dependencies are included too. Errors of dependencies
+// are ignored, they are reported by getPackagesInfo.
+func listPackagesAndDeps(ctx context.Context, env goEnv, pkgs []string,
+	tests bool) ([]string, error) {
+
+	infos, err := listRoots(ctx, env, pkgs)
 	if err != nil {
 		return nil, err
 	}
-	args := []string{"list", "-f", "{{range .Deps}}{{.}}|{{end}}"}
-	args = append(args, pkgs...)
-	cmd := exec.Command("go", args...)
-	cmd.Env = fixEnv(gopath)
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		output := string(out)
-		if strings.Contains(output, "cannot find package") ||
-			strings.Contains(output, "no buildable Go source files") {
-			return nil, &MissingError{Err: output}
-		}
-		return nil, fmt.Errorf("'go %s' failed with:\n%s",
-			strings.Join(args, " "), output)
-	}
-	deps := []string{}
 	seen := map[string]bool{}
-	for _, s := range strings.Split(string(out), "|") {
-		s = strings.TrimSpace(s)
-		if s != "" && !seen[s] {
-			deps = append(deps, s)
-			seen[s] = true
+	deps := []string{}
+	add := func(infos []*PkgInfo) {
+		for _, info := range infos {
+			for _, pkg := range append([]string{info.ImportPath}, info.Deps...) {
+				if !seen[pkg] {
+					seen[pkg] = true
+					deps = append(deps, pkg)
+				}
+			}
 		}
 	}
-	for _, pkg := range pkgs {
-		if !seen[pkg] {
-			seen[pkg] = true
-			deps = append(deps, pkg)
+	add(infos)
+	if tests {
+		imports := []string{}
+		for _, info := range infos {
+			imports = append(imports, info.TestImports...)
+			imports = append(imports, info.XTestImports...)
+		}
+		if len(imports) > 0 {
+			testInfos, err := goList(ctx, env, nil, imports)
+			if err != nil {
+				return nil, err
+			}
+			add(testInfos)
 		}
 	}
 	sort.Strings(deps)
 	return deps, nil
 }
 
-func listStandardPackages(gopath string) ([]string, error) {
-	return expandPackages(gopath, []string{"std", "cmd"})
+// matchPattern returns a function matching import paths against supplied
+// pattern, following go tooling conventions: "..." matches any string,
+// including empty strings and slashes, and a trailing "/..." also matches the
+// prefix itself, so "net/..." matches both "net" and its subpackages.
+func matchPattern(pattern string) func(string) bool {
+	re := regexp.QuoteMeta(pattern)
+	re = strings.Replace(re, `\.\.\.`, `.*`, -1)
+	if strings.HasSuffix(re, `/.*`) {
+		re = strings.TrimSuffix(re, `/.*`) + `(/.*)?`
+	}
+	reg := regexp.MustCompile(`^` + re + `$`)
+	return reg.MatchString
 }
 
-type PkgError struct {
-	Err string
+// excludePackages returns pkgs without the ones matching any of the patterns.
+func excludePackages(pkgs []string, patterns []string) []string {
+	if len(patterns) == 0 {
+		return pkgs
+	}
+	matchers := []func(string) bool{}
+	for _, p := range patterns {
+		matchers = append(matchers, matchPattern(p))
+	}
+	kept := []string{}
+	for _, pkg := range pkgs {
+		excluded := false
+		for _, m := range matchers {
+			if m(pkg) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			kept = append(kept, pkg)
+		}
+	}
+	return kept
 }
 
-type PkgInfo struct {
-	Name       string
-	Dir        string
-	Root       string
-	ImportPath string
-	Error      *PkgError
+// stdPackages memoizes listStandardPackages results by stdPackagesKey, the
+// standard packages of a toolchain never changing.
+var stdPackages = struct {
+	sync.Mutex
+	byKey map[string][]string
+}{byKey: map[string][]string{}}
+
+// stdPackagesKey identifies the toolchain and build context of go commands run
+// in env: the go executable, with its modification time so upgrades are
+// noticed, and the variables selecting the toolchain or the listed packages.
+func stdPackagesKey(env goEnv) string {
+	bin, err := exec.LookPath(goBin)
+	if err != nil {
+		bin = goBin
+	} else if fi, err := os.Stat(bin); err == nil {
+		bin += "@" + fi.ModTime().String()
+	}
+	parts := []string{bin, strings.Join(env.Tags, ",")}
+	for _, v := range []struct {
+		Name  string
+		Value string
+	}{
+		{"GOROOT", ""},
+		{"GOTOOLCHAIN", ""},
+		{"GOFLAGS", ""},
+		{"GOPATH", env.GOPATH},
+		{"GOOS", env.GOOS},
+		{"GOARCH", env.GOARCH},
+	} {
+		if v.Value == "" {
+			v.Value = os.Getenv(v.Name)
+		}
+		parts = append(parts, v.Name+"="+v.Value)
+	}
+	return strings.Join(parts, "\n")
 }
 
-func getPackagesInfo(gopath string, pkgs []string) ([]*PkgInfo, error) {
-	args := []string{"list", "-e", "-json"}
-	// TODO: split the list for platforms which do not support massive argument
-	// lists.
-	args = append(args, pkgs...)
-	cmd := exec.Command("go", args...)
-	cmd.Env = fixEnv(gopath)
-	out, err := cmd.CombinedOutput()
+// listStandardPackages returns the standard packages and commands of the
+// toolchain used in env. Results are memoized, see stdPackagesKey.
+func listStandardPackages(ctx context.Context, env goEnv) ([]string, error) {
+	key := stdPackagesKey(env)
+	stdPackages.Lock()
+	std, ok := stdPackages.byKey[key]
+	stdPackages.Unlock()
+	if ok {
+		return std, nil
+	}
+	std, err := expandPackages(ctx, env, []string{"std", "cmd"})
 	if err != nil {
-		return nil, fmt.Errorf("go %s failed with:\n%s",
-			strings.Join(args, " "), string(out))
+		return nil, err
 	}
-	infos := make([]*PkgInfo, 0, len(pkgs))
-	decoder := json.NewDecoder(bytes.NewBuffer(out))
-	for _, pkg := range pkgs {
-		info := &PkgInfo{}
-		err := decoder.Decode(info)
+	stdPackages.Lock()
+	stdPackages.byKey[key] = std
+	stdPackages.Unlock()
+	return std, nil
+}
+
+// maxArgsLength is the maximum length of the package arguments passed to a
+// single go list invocation by getPackagesInfo, separators included. It stays
+// well below the 32767 characters command line limit of Windows.
+var maxArgsLength = 30000
+
+// splitArgs splits args in consecutive batches whose length, separators
+// included, does not exceed maxLength. Longer arguments have their own batch.
+func splitArgs(args []string, maxLength int) [][]string {
+	batches := [][]string{}
+	batch := []string{}
+	length := 0
+	for _, arg := range args {
+		if len(batch) > 0 && length+1+len(arg) > maxLength {
+			batches = append(batches, batch)
+			batch, length = []string{}, 0
+		}
+		if len(batch) > 0 {
+			length++
+		}
+		batch = append(batch, arg)
+		length += len(arg)
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// getPackagesInfo returns information about supplied packages, in order. Long
+// package lists are split in batches listed separately, see maxArgsLength.
+func getPackagesInfo(ctx context.Context, env goEnv, pkgs []string) ([]*PkgInfo,
+	error) {
+
+	infos := []*PkgInfo{}
+	for _, batch := range splitArgs(pkgs, maxArgsLength) {
+		batchInfos, err := goList(ctx, env, nil, batch)
 		if err != nil {
-			return nil, fmt.Errorf("could not retrieve package information for %s", pkg)
+			return nil, err
 		}
-		if pkg != info.ImportPath {
-			return nil, fmt.Errorf("package information mismatch: asked for %s, got %s",
-				pkg, info.ImportPath)
+		if len(batchInfos) != len(batch) {
+			return nil, fmt.Errorf("could not retrieve package information for %s",
+				strings.Join(batch, " "))
 		}
-		if info.Error != nil && info.Name == "" {
-			info.Name = info.ImportPath
+		for i, info := range batchInfos {
+			if batch[i] != info.ImportPath {
+				return nil, fmt.Errorf("package information mismatch: asked for %s, got %s",
+					batch[i], info.ImportPath)
+			}
+			if info.Error != nil && info.Name == "" {
+				info.Name = info.ImportPath
+			}
 		}
-		infos = append(infos, info)
+		infos = append(infos, batchInfos...)
 	}
-	return infos, err
+	return infos, nil
 }
 
-var (
-	reLicense = regexp.MustCompile(`(?i)^(?:` +
-		`((?:un)?licen[sc]e)|` +
-		`((?:un)?licen[sc]e\.(?:md|markdown|txt))|` +
-		`(copy(?:ing|right)(?:\.[^.]+)?)|` +
-		`(licen[sc]e\.[^.]+)` +
-		`)$`)
-)
+// regularFile returns the information of the regular file described by fi, in
+// dir, and true. Symbolic links are followed, license files being sometimes
+// shared by linking them, like in monorepos. It returns false for other files,
+// broken links and link loops.
+func regularFile(dir string, fi os.FileInfo) (os.FileInfo, bool) {
+	if fi.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Stat(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return nil, false
+		}
+		fi = target
+	}
+	return fi, fi.Mode().IsRegular()
+}
 
-// scoreLicenseName returns a factor between 0 and 1 weighting how likely
-// supplied filename is a license file.
-func scoreLicenseName(name string) float64 {
-	m := reLicense.FindStringSubmatch(name)
-	switch {
-	case m == nil:
-		break
-	case m[1] != "":
-		return 1.0
-	case m[2] != "":
-		return 0.9
-	case m[3] != "":
-		return 0.8
-	case m[4] != "":
-		return 0.7
-	}
-	return 0.
-}
-
-// findLicense looks for license files in package import path, and down to
-// parent directories until a file is found or $GOPATH/src is reached. It
-// returns the path and score of the best entry, an empty string if none was
-// found.
-func findLicense(info *PkgInfo) (string, error) {
+type licenseFile struct {
+	Name  string
+	Score float64
+	Size  int64
+	// Vendored is true for files marked as vendored, see markVendoredFiles.
+	Vendored bool
+}
+
+type sortedLicenseFiles []licenseFile
+
+func (s sortedLicenseFiles) Len() int {
+	return len(s)
+}
+
+func (s sortedLicenseFiles) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s sortedLicenseFiles) Less(i, j int) bool {
+	if s[i].Vendored != s[j].Vendored {
+		return !s[i].Vendored
+	}
+	return s[i].Score > s[j].Score
+}
+
+// reLesser matches the names of LGPL license files, distributed along the GPL
+// they build upon.
+var reLesser = regexp.MustCompile(`(?i)^copying\.(?:lesser|lib)(?:\.(?:md|txt))?$`)
+
+// sortLicenseFiles sorts license files of a directory by decreasing name
+// score, vendored ones last. LGPL licensed projects ship the GPL as COPYING
+// and the LGPL as COPYING.LESSER, the latter is then moved first since it is
+// the license of the project.
+func sortLicenseFiles(files []licenseFile) {
+	sort.Stable(sortedLicenseFiles(files))
+	if len(files) < 2 || reLesser.MatchString(files[0].Name) {
+		return
+	}
+	kind, _ := licensecheck.MatchLicenseName(files[0].Name)
+	if kind != licensecheck.CopyingName {
+		return
+	}
+	for i, f := range files {
+		if reLesser.MatchString(f.Name) && f.Vendored == files[0].Vendored {
+			copy(files[1:i+1], files[:i])
+			files[0] = f
+			return
+		}
+	}
+}
+
+// findLicenses looks for license files in package directory, and up to parent
+// directories until a file is found or the package root is reached. The root
+// is $GOPATH/src in GOPATH mode and the module directory in module mode.
+// Vendored packages stop at the vendor directory, so they are not attributed
+// the license of the vendoring project, and so do directories containing a
+// go.mod file, even in GOPATH mode. If maxWalk is positive, at most maxWalk
+// parent directories are inspected. License files smaller than minBytes are
+// ignored, tiny ones being usually stubs pointing elsewhere. Synthetic
+// packages without Root, outside of modules, only have their own directory
+// inspected. It returns the license files of the first directory containing
+// any, sorted by sortLicenseFiles, as paths made of the import path of the
+// directory and the file names, and as filesystem paths. Directories whose
+// license files are all pointer stubs, like "See the COPYING file in the root
+// directory", are skipped, and only reported if no other license file is
+// found. Standard packages have the LICENSE file at the root of the Go
+// distribution, reported as "$GOROOT/LICENSE".
+func findLicenses(info *PkgInfo, maxWalk, minBytes int) ([]string, []string, error) {
+	if info.Dir == "" {
+		return nil, nil, fmt.Errorf("cannot look for %s licenses: package has no directory",
+			info.ImportPath)
+	}
+	if info.Standard && info.Root != "" {
+		fpath := filepath.Join(info.Root, "LICENSE")
+		if _, err := os.Stat(fpath); err != nil {
+			return nil, nil, nil
+		}
+		return []string{"$GOROOT/LICENSE"}, []string{fpath}, nil
+	}
+	// top is the first directory not to be inspected
+	top := filepath.Join(info.Root, "src")
+	if info.Module != nil && info.Module.Dir != "" {
+		top = filepath.Dir(info.Module.Dir)
+	} else if info.Root == "" {
+		top = filepath.Dir(info.Dir)
+	}
+	dir := info.Dir
 	path := info.ImportPath
-	for ; path != "."; path = filepath.Dir(path) {
-		fis, err := ioutil.ReadDir(filepath.Join(info.Root, "src", path))
+	var stubPaths, stubFPaths []string
+	for level := 0; dir != top && path != "." && filepath.Base(dir) != "vendor" &&
+		(maxWalk <= 0 || level <= maxWalk); level++ {
+		fis, err := ioutil.ReadDir(dir)
 		if err != nil {
-			return "", err
+			return nil, nil, err
 		}
-		bestScore := float64(0)
-		bestName := ""
+		files := []licenseFile{}
+		moduleRoot := false
 		for _, fi := range fis {
-			if !fi.Mode().IsRegular() {
+			name := fi.Name()
+			fi, ok := regularFile(dir, fi)
+			if !ok {
 				continue
 			}
-			score := scoreLicenseName(fi.Name())
-			if score > bestScore {
-				bestScore = score
-				bestName = fi.Name()
+			if name == "go.mod" {
+				moduleRoot = true
+			}
+			score := licensecheck.ScoreLicenseName(name)
+			if score > 0 && fi.Size() >= int64(minBytes) {
+				files = append(files, licenseFile{
+					Name:  name,
+					Score: score,
+					Size:  fi.Size(),
+				})
 			}
 		}
-		if bestName != "" {
-			return filepath.Join(path, bestName), nil
+		if len(files) > 0 {
+			markVendoredFiles(dir, files)
+			sortLicenseFiles(files)
+			paths := []string{}
+			fpaths := []string{}
+			for _, f := range files {
+				paths = append(paths, filepath.Join(path, f.Name))
+				fpaths = append(fpaths, filepath.Join(dir, f.Name))
+			}
+			if !pointerStubs(dir, files) {
+				return paths, fpaths, nil
+			}
+			if stubPaths == nil {
+				stubPaths, stubFPaths = paths, fpaths
+			}
+		}
+		if moduleRoot {
+			break
+		}
+		dir, path = filepath.Dir(dir), filepath.Dir(path)
+	}
+	return stubPaths, stubFPaths, nil
+}
+
+// stubMaxSize is the size above which license files are not read to check
+// whether they are pointer stubs.
+const stubMaxSize = 1024
+
+// pointerStubs returns true if all supplied license files of dir are pointer
+// stubs, see licensecheck.IsPointerStub. Unreadable files are not stubs, so
+// their errors are reported when matching them.
+func pointerStubs(dir string, files []licenseFile) bool {
+	for _, f := range files {
+		if f.Size > stubMaxSize {
+			return false
+		}
+		data, err := licensecheck.ReadLicenseFile(filepath.Join(dir, f.Name))
+		if err != nil || !licensecheck.IsPointerStub(data) {
+			return false
 		}
 	}
-	return "", nil
+	return true
 }
 
 type License struct {
-	Package      string
-	Score        float64
-	Template     *Template
-	Path         string
-	Err          string
-	ExtraWords   []string
-	MissingWords []string
+	Package string
+	licensecheck.MatchResult
+	// Path is the license file path, made of the import path of its directory
+	// and its name. It is empty if no license was found, see Missing.
+	Path string
+	// AbsPath is the filesystem path of the license file.
+	AbsPath string
+	Err     string
+	// ErrCategory classifies Err, it is ErrNone if Err is empty.
+	ErrCategory ErrCategory
+	// NameScore tells how likely the license file name is a license file
+	// name, see licensecheck.ScoreLicenseName. Unlike Score, it does not
+	// depend on the file content. It is zero for source file headers.
+	NameScore float64
+	// Copyright lists the copyright lines of the license file.
+	Copyright []string
+	// Override is true if the match was assigned by an override file instead
+	// of detected.
+	Override bool
+	// Guesses lists the best matching templates of the license file, by
+	// decreasing score, when listOptions.Top is set.
+	Guesses []licensecheck.MatchResult
+	// Replace is the effective module of packages whose module is replaced in
+	// go.mod, see replacement. Their license is looked up in the replacement.
+	Replace string
+	// Version is the version of the package module, when known.
+	Version string
+	// Module is the path of the package module, when known.
+	Module string
+	// Size and Lines are the number of bytes and lines of the decoded license
+	// file. Tiny files are usually stubs pointing to another license.
+	Size  int
+	Lines int
 }
 
-func listLicenses(gopath string, pkgs []string) ([]License, error) {
-	templates, err := loadTemplates()
+// countLines returns the number of lines of data, the last one possibly
+// lacking a line break.
+func countLines(data []byte) int {
+	n := bytes.Count(data, []byte("\n"))
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		n++
+	}
+	return n
+}
+
+// ErrCategory classifies the errors preventing license detection.
+type ErrCategory int
+
+const (
+	// ErrNone packages were loaded successfully.
+	ErrNone ErrCategory = iota
+	// ErrMissing packages cannot be found.
+	ErrMissing
+	// ErrNoGoFiles packages have no buildable Go source file, for instance
+	// because build constraints exclude all of them.
+	ErrNoGoFiles
+	// ErrRead packages directories or license files cannot be read.
+	ErrRead
+	// ErrOther packages fail to load for other reasons.
+	ErrOther
+)
+
+func (c ErrCategory) String() string {
+	switch c {
+	case ErrNone:
+		return ""
+	case ErrMissing:
+		return "missing package"
+	case ErrNoGoFiles:
+		return "no Go files"
+	case ErrRead:
+		return "read error"
+	}
+	return "error"
+}
+
+// pkgErrCategory returns the category of the error of a package listed by go
+// list, see packageError.
+func pkgErrCategory(info *PkgInfo) ErrCategory {
+	switch {
+	case info.Error == nil:
+		return ErrNone
+	case info.Dir == "":
+		return ErrMissing
+	case len(info.GoFiles)+len(info.CgoFiles) == 0:
+		return ErrNoGoFiles
+	}
+	return ErrOther
+}
+
+// Missing returns true if no license file nor source header license was found
+// for the package, as opposed to license files present but unrecognized,
+// which have a path but no template or a low score.
+func (l License) Missing() bool {
+	return l.Err == "" && l.Path == "" && l.Template == nil
+}
+
+// listOptions controls how licenses are collected.
+type listOptions struct {
+	// AllFiles reports every license file of a package as a separate entry,
+	// instead of the best one only.
+	AllFiles bool
+	// Tests includes the packages imported by tests of listed packages, and
+	// their dependencies.
+	Tests bool
+	// Exclude lists import path patterns of packages to ignore. They support
+	// go tooling "..." wildcards.
+	Exclude []string
+	// GOOS, GOARCH and Tags select the target platform and build tags used
+	// to resolve dependencies. They default to the current ones.
+	GOOS   string
+	GOARCH string
+	Tags   []string
+	// TemplateDirs lists directories of additional license templates.
+	TemplateDirs []string
+	// CopyrightRegexp matches additional copyright notices stripped from
+	// license files before matching them, see
+	// licensecheck.Matcher.AddCopyrightPattern. It is ignored if nil.
+	CopyrightRegexp *regexp.Regexp
+	// CacheDir is the directory of the on-disk match results cache, see
+	// matchCache. The cache is disabled if it is empty.
+	CacheDir string
+	// Overrides is the path of a file assigning licenses to packages, see
+	// loadOverrides. Overrides win over detected licenses.
+	Overrides string
+	// MaxWalk is the maximum number of parent directories of a package
+	// searched for license files. Zero means no limit.
+	MaxWalk int
+	// MinBytes is the size under which license files are ignored, see
+	// findLicenses.
+	MinBytes int
+	// Top is the number of best matching templates reported in
+	// License.Guesses. Guesses are not computed if it is zero.
+	Top int
+	// IncludeHidden makes directory scans descend into version control
+	// metadata and vendored trees, see skippedDirs.
+	IncludeHidden bool
+	// Warnings receives go commands warnings, see goEnv.
+	Warnings io.Writer
+	// Stdlib includes standard packages, attributed to the license of the Go
+	// distribution, see findLicenses.
+	Stdlib bool
+	// Retries is the number of times go commands failing with transient
+	// errors are retried, see goEnv.
+	Retries int
+	// Workfile is the go.work file used to resolve packages in workspace
+	// mode, instead of the ambient one. It must be absolute, or "off".
+	Workfile string
+	// Modules reports one entry per module instead of per package, see
+	// collapseModules.
+	Modules bool
+	// IgnoreStopwords ignores very common English words when matching
+	// licenses, see licensecheck.Matcher.IgnoreStopwords.
+	IgnoreStopwords bool
+	// Concurrency is the number of license files read and matched in
+	// parallel, one if it is not positive. go commands always run serially.
+	Concurrency int
+}
+
+// listPackagesDeps returns information about supplied packages and their
+// dependencies, standard packages excepted unless std is set, using a single
+// "go list -deps" invocation, or two with tests. Errors of dependencies are
+// reported in PkgInfo, not returned.
+func listPackagesDeps(ctx context.Context, env goEnv, pkgs []string,
+	tests, std bool) ([]*PkgInfo, error) {
+
+	infos, err := goList(ctx, env, []string{"-deps"}, pkgs)
 	if err != nil {
 		return nil, err
 	}
-	deps, err := listPackagesAndDeps(gopath, pkgs)
+	imports := []string{}
+	for _, info := range infos {
+		if info.DepOnly {
+			continue
+		}
+		if err := packageError(info); err != nil {
+			return nil, err
+		}
+		imports = append(imports, info.TestImports...)
+		imports = append(imports, info.XTestImports...)
+	}
+	if tests && len(imports) > 0 {
+		testInfos, err := goList(ctx, env, []string{"-deps"}, imports)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, testInfos...)
+	}
+	seen := map[string]bool{}
+	kept := []*PkgInfo{}
+	for _, info := range infos {
+		if (info.Standard && !std) || seen[info.ImportPath] {
+			continue
+		}
+		seen[info.ImportPath] = true
+		if info.Error != nil && info.Name == "" {
+			info.Name = info.ImportPath
+		}
+		kept = append(kept, info)
+	}
+	sort.Sort(sortedPkgInfos(kept))
+	return kept, nil
+}
+
+// isDepsUnsupported returns true if err is the goList failure of go versions
+// without "go list -deps".
+func isDepsUnsupported(err error) bool {
+	return strings.Contains(err.Error(), "flag provided but not defined: -deps")
+}
+
+// listPackagesInfo returns information about supplied packages and their
+// dependencies, standard packages unless opts.Stdlib is set and excluded
+// packages excepted, sorted by import path. It relies on listPackagesDeps and
+// falls back to separate calls to list the dependencies, the standard
+// packages and packages information for go versions without "go list -deps"
+// (before 1.11). The fallback runs go list four times, five with tests. Other
+// errors are returned as is.
+func listPackagesInfo(ctx context.Context, env goEnv, pkgs []string,
+	opts listOptions) ([]*PkgInfo, error) {
+
+	infos, err := listPackagesDeps(ctx, env, pkgs, opts.Tests, opts.Stdlib)
 	if err != nil {
-		if _, ok := err.(*MissingError); ok {
+		if !isDepsUnsupported(err) {
+			return nil, err
+		}
+		infos, err = listPackagesInfoFallback(ctx, env, pkgs, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	names := []string{}
+	for _, info := range infos {
+		names = append(names, info.ImportPath)
+	}
+	included := map[string]bool{}
+	for _, name := range excludePackages(names, opts.Exclude) {
+		included[name] = true
+	}
+	kept := []*PkgInfo{}
+	for _, info := range infos {
+		if included[info.ImportPath] {
+			kept = append(kept, info)
+		}
+	}
+	return kept, nil
+}
+
+func listPackagesInfoFallback(ctx context.Context, env goEnv, pkgs []string,
+	opts listOptions) ([]*PkgInfo, error) {
+
+	deps, err := listPackagesAndDeps(ctx, env, pkgs, opts.Tests)
+	if err != nil {
+		switch err.(type) {
+		case *MissingError, *BuildError:
 			return nil, err
 		}
 		return nil, fmt.Errorf("could not list %s dependencies: %s",
 			strings.Join(pkgs, " "), err)
 	}
-	std, err := listStandardPackages(gopath)
+	deps = excludePackages(deps, opts.Exclude)
+	if opts.Stdlib {
+		if len(deps) == 0 {
+			return []*PkgInfo{}, nil
+		}
+		return getPackagesInfo(ctx, env, deps)
+	}
+	std, err := listStandardPackages(ctx, env)
 	if err != nil {
 		return nil, fmt.Errorf("could not list standard packages: %s", err)
 	}
@@ -405,62 +991,230 @@ func listLicenses(gopath string, pkgs []string) ([]License, error) {
 	for _, n := range std {
 		stdSet[n] = true
 	}
-	infos, err := getPackagesInfo(gopath, deps)
+	nonStd := []string{}
+	for _, dep := range deps {
+		if !stdSet[dep] {
+			nonStd = append(nonStd, dep)
+		}
+	}
+	if len(nonStd) == 0 {
+		return []*PkgInfo{}, nil
+	}
+	return getPackagesInfo(ctx, env, nonStd)
+}
+
+// newMatcher returns a matcher using the TemplateDirs templates, the
+// CopyrightRegexp pattern and the IgnoreStopwords setting of supplied options.
+func newMatcher(opts listOptions) (*licensecheck.Matcher, error) {
+	matcher, err := licensecheck.NewMatcherWithDirs(opts.TemplateDirs)
 	if err != nil {
 		return nil, err
 	}
+	if opts.CopyrightRegexp != nil {
+		matcher.AddCopyrightPattern(opts.CopyrightRegexp)
+	}
+	if opts.IgnoreStopwords {
+		matcher.IgnoreStopwords()
+	}
+	return matcher, nil
+}
 
-	// Cache matched licenses by path. Useful for package with a lot of
-	// subpackages like bleve.
-	matched := map[string]MatchResult{}
+// listLicensesStream is like listLicenses but passes licenses to emit as
+// soon as they are resolved, in import path order, instead of returning
+// them. It stops and returns the error of emit if it fails.
+func listLicensesStream(ctx context.Context, gopath string, pkgs []string,
+	opts listOptions, emit func(License) error) error {
 
-	licenses := []License{}
-	for _, info := range infos {
+	matcher, err := newMatcher(opts)
+	if err != nil {
+		return err
+	}
+	if opts.Overrides != "" {
+		o, err := loadOverrides(opts.Overrides, matcher)
+		if err != nil {
+			return err
+		}
+		emitDetected := emit
+		emit = func(l License) error {
+			return emitDetected(o.Apply(l))
+		}
+	}
+	env := goEnv{
+		GOPATH:   gopath,
+		GOOS:     opts.GOOS,
+		GOARCH:   opts.GOARCH,
+		Tags:     opts.Tags,
+		Warnings: opts.Warnings,
+		Retries:  opts.Retries,
+		GOWORK:   opts.Workfile,
+	}
+	infos, err := listPackagesInfo(ctx, env, pkgs, opts)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		return err
+	}
+	if opts.Modules {
+		infos = collapseModules(infos)
+	}
+
+	// Look for license files first, then match them concurrently while
+	// emitting licenses in order. Files are matched once even if several
+	// packages share them, like the subpackages of bleve.
+	type packageFiles struct {
+		Paths  []string
+		FPaths []string
+		Err    error
+	}
+	found := make([]packageFiles, len(infos))
+	allFPaths := []string{}
+	for i, info := range infos {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if info.Error != nil {
-			licenses = append(licenses, License{
-				Package: info.Name,
-				Err:     info.Error.Err,
-			})
 			continue
 		}
-		if stdSet[info.ImportPath] {
+		paths, fpaths, err := findLicenses(info, opts.MaxWalk, opts.MinBytes)
+		if len(paths) > 1 && !opts.AllFiles {
+			paths, fpaths = paths[:1], fpaths[:1]
+		}
+		found[i] = packageFiles{Paths: paths, FPaths: fpaths, Err: err}
+		allFPaths = append(allFPaths, fpaths...)
+	}
+	match := matcher.Match
+	if opts.CacheDir != "" {
+		cache := openMatchCache(opts.CacheDir, matcher)
+		defer cache.Save()
+		match = cache.Match
+	}
+	matched, stop := startFileMatches(allFPaths, opts.Concurrency,
+		func(fpath string) fileMatch {
+			return matchLicenseFile(fpath, match, matcher, opts.Top)
+		})
+	defer stop()
+
+	for i, info := range infos {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if info.Error != nil {
+			err := emit(License{
+				Package:     info.Name,
+				Err:         info.Error.Err,
+				ErrCategory: pkgErrCategory(info),
+			})
+			if err != nil {
+				return err
+			}
 			continue
 		}
-		path, err := findLicense(info)
-		if err != nil {
-			return nil, err
+		paths, fpaths := found[i].Paths, found[i].FPaths
+		if found[i].Err != nil {
+			err := emit(License{
+				Package:     info.ImportPath,
+				Err:         found[i].Err.Error(),
+				ErrCategory: ErrRead,
+			})
+			if err != nil {
+				return err
+			}
+			continue
 		}
-		license := License{
-			Package: info.ImportPath,
-			Path:    path,
+		for j, path := range paths {
+			fpath := fpaths[j]
+			m := matched[fpath].Wait()
+			if m.Err != nil {
+				err := emit(License{
+					Package:     info.ImportPath,
+					Path:        path,
+					AbsPath:     fpath,
+					Err:         m.Err.Error(),
+					ErrCategory: ErrRead,
+				})
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			err := emit(License{
+				Package:     info.ImportPath,
+				MatchResult: m.MatchResult,
+				Path:        path,
+				AbsPath:     fpath,
+				NameScore:   licensecheck.ScoreLicenseName(filepath.Base(fpath)),
+				Copyright:   m.Copyright,
+				Guesses:     m.Guesses,
+				Replace:     replacement(info),
+				Version:     moduleVersion(info),
+				Module:      modulePath(info),
+				Size:        m.Size,
+				Lines:       m.Lines,
+			})
+			if err != nil {
+				return err
+			}
 		}
-		if path != "" {
-			fpath := filepath.Join(info.Root, "src", path)
-			m, ok := matched[fpath]
-			if !ok {
-				data, err := ioutil.ReadFile(fpath)
+		if len(paths) == 0 {
+			// Fallback to license headers in source files
+			path, fpath, m, err := findHeaderLicense(info, matcher)
+			if err != nil {
+				err = emit(License{
+					Package:     info.ImportPath,
+					Err:         err.Error(),
+					ErrCategory: ErrRead,
+				})
 				if err != nil {
-					return nil, err
+					return err
 				}
-				m = matchTemplates(data, templates)
-				matched[fpath] = m
+				continue
+			}
+			err = emit(License{
+				Package:     info.ImportPath,
+				MatchResult: m,
+				Path:        path,
+				AbsPath:     fpath,
+				Replace:     replacement(info),
+				Version:     moduleVersion(info),
+				Module:      modulePath(info),
+			})
+			if err != nil {
+				return err
 			}
-			license.Score = m.Score
-			license.Template = m.Template
-			license.ExtraWords = m.ExtraWords
-			license.MissingWords = m.MissingWords
 		}
-		licenses = append(licenses, license)
+	}
+	return nil
+}
+
+// listLicenses returns the licenses of supplied packages and their
+// dependencies, standard packages excepted. It runs go list once, see
+// listPackagesInfo. It returns ctx error, and no license, if ctx is done
+// before all packages are listed.
+func listLicenses(ctx context.Context, gopath string, pkgs []string,
+	opts listOptions) ([]License, error) {
+
+	licenses := []License{}
+	err := listLicensesStream(ctx, gopath, pkgs, opts, func(l License) error {
+		licenses = append(licenses, l)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return licenses, nil
 }
 
 // longestCommonPrefix returns the longest common prefix over import path
-// components of supplied licenses.
+// components of supplied licenses. A prefix which is itself one of the
+// packages is not extended further, "a/b" and "a/b/c" share "a/b". It returns
+// an empty string if packages diverge at their first component.
 func longestCommonPrefix(licenses []License) string {
 	type Node struct {
 		Name     string
 		Children map[string]*Node
+		// Terminal is true if the node path is a package.
+		Terminal bool
 	}
 	// Build a prefix tree. Not super efficient, but easy to do.
 	root := &Node{
@@ -479,11 +1233,12 @@ func longestCommonPrefix(licenses []License) string {
 			}
 			n = c
 		}
+		n.Terminal = true
 	}
 	n := root
 	prefix := []string{}
 	for {
-		if len(n.Children) != 1 {
+		if len(n.Children) != 1 || n.Terminal {
 			break
 		}
 		for _, c := range n.Children {
@@ -495,18 +1250,21 @@ func longestCommonPrefix(licenses []License) string {
 	return strings.Join(prefix, "/")
 }
 
-// groupLicenses returns the input licenses after grouping them by license path
-// and find their longest import path common prefix. Entries with empty paths
+// groupLicensesBy returns the input licenses after grouping them by key and
+// finding their longest import path common prefix. Entries with empty keys
 // are left unchanged.
-func groupLicenses(licenses []License) ([]License, error) {
-	paths := map[string][]License{}
+func groupLicensesBy(licenses []License, key func(License) string) ([]License,
+	error) {
+
+	groups := map[string][]License{}
 	for _, l := range licenses {
-		if l.Path == "" {
+		k := key(l)
+		if k == "" {
 			continue
 		}
-		paths[l.Path] = append(paths[l.Path], l)
+		groups[k] = append(groups[k], l)
 	}
-	for k, v := range paths {
+	for k, v := range groups {
 		if len(v) <= 1 {
 			continue
 		}
@@ -517,91 +1275,718 @@ func groupLicenses(licenses []License) ([]License, error) {
 		}
 		l := v[0]
 		l.Package = prefix
-		paths[k] = []License{l}
+		groups[k] = []License{l}
 	}
 	kept := []License{}
 	for _, l := range licenses {
-		if l.Path == "" {
+		k := key(l)
+		if k == "" {
 			kept = append(kept, l)
 			continue
 		}
-		if v, ok := paths[l.Path]; ok {
+		if v, ok := groups[k]; ok {
 			kept = append(kept, v[0])
-			delete(paths, l.Path)
+			delete(groups, k)
 		}
 	}
 	return kept, nil
 }
 
-func printLicenses() error {
-	flag.Usage = func() {
-		fmt.Println(`Usage: licenses IMPORTPATH...
+// groupLicenses returns the input licenses after grouping them by license path
+// and find their longest import path common prefix. Entries with empty paths
+// are left unchanged. Overridden licenses are grouped apart from detected
+// ones.
+func groupLicenses(licenses []License) ([]License, error) {
+	return groupLicensesBy(licenses, func(l License) string {
+		if l.Override && l.Path != "" {
+			return "override:" + licensecheck.TemplateKey(l.Template) + ":" + l.Path
+		}
+		return l.Path
+	})
+}
+
+// groupLicensesByTemplate is like groupLicenses but also groups exact matches
+// of the same template, whatever their license path. Packages are grouped
+// separately for each first import path component, like "github.com", so
+// groups always have a common prefix. Other licenses are grouped by path.
+func groupLicensesByTemplate(licenses []License) ([]License, error) {
+	return groupLicensesBy(licenses, func(l License) string {
+		if classify(l, exactScore).Class == Exact && !l.Override {
+			root := strings.SplitN(l.Package, "/", 2)[0]
+			return "template:" + matchName(l.MatchResult) + ":" + root
+		}
+		if l.Override && l.Path != "" {
+			return "override:" + licensecheck.TemplateKey(l.Template) + ":" + l.Path
+		}
+		if l.Path == "" {
+			return ""
+		}
+		return "path:" + l.Path
+	})
+}
+
+// stringsFlag is a flag.Value collecting the values of a repeated flag.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// checkConfidence returns an error if supplied confidence threshold is not in
+// (0, 1].
+func checkConfidence(confidence float64) error {
+	if confidence <= 0 || confidence > 1 {
+		return fmt.Errorf("confidence must be in (0, 1], got %v", confidence)
+	}
+	return nil
+}
+
+// errUsage is returned by printLicenses when command line arguments cannot be
+// parsed. The error and usage were already printed.
+var errUsage = errors.New("invalid usage")
+
+// printLicenses runs licenses with supplied command line arguments, without
+// the program name, and writes its report to stdout.
+func printLicenses(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("licenses", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stdout, `Usage: licenses IMPORTPATH...
+       licenses -dir PATH
+       licenses -file PATH
+       licenses -list-templates
+       licenses -serve ADDR
 
 licenses lists all dependencies of specified packages or commands, excluding
-standard library packages, and prints their licenses. Licenses are detected by
-looking for files named like LICENSE, COPYING, COPYRIGHT and other variants in
-the package directory, and its parent directories until one is found. Files
-content is matched against a set of well-known licenses and the best match is
-displayed along with its score.
+standard library packages unless -stdlib is set, and prints their licenses.
+Licenses are detected by looking for files named like LICENSE, COPYING,
+COPYRIGHT and other variants, or after a license like MIT or Apache-2.0.txt, in
+the package directory, and its parent directories until one is found. Short
+files pointing elsewhere, like "See the COPYING file in the root directory",
+are skipped in favor of the license files of parent directories, if any.
+COPYING.LESSER or COPYING.LIB files are preferred over COPYING ones, the latter
+holding the GPL which LGPL builds upon. Files marked as linguist-vendored,
+linguist-generated or export-ignore by the .gitattributes file of their
+directory, or ignored by its .gitignore file, are considered last. Gzip
+compressed license files, like LICENSE.gz, are decompressed, and the Markdown
+or HTML markup of LICENSE.md files is stripped. Files content is matched
+against a set of well-known licenses and the best match is displayed along with
+its score. If no license file is found, licenses looks for
+SPDX-License-Identifier tags or license comments at the top of package source
+files. Tags holding an expression like "MIT OR Apache-2.0" are reported as
+multi-licensed, expressions combining licenses with AND are reported as
+unknown.
+
+Both GOPATH and module modes are supported. In module mode, license lookup
+starts in the package directory and stops at the module root directory. In
+GOPATH mode, it also stops at directories containing a go.mod file. Modules
+replaced in go.mod have the license of their replacement, which is displayed
+after it. Warnings of the go command, like module download notes, are forwarded
+to stderr.
+With -modules, in module mode, one entry is reported per module instead of per
+package, named after the module path and licensed by the license file of the
+module root directory. Packages outside of modules are reported as usual.
+With -timeout DURATION, like 30s or 2m, listing packages is aborted after
+DURATION, killing running go commands, and nothing is printed. With
+-from-gomod, it also bounds every module archive download.
+In workspace mode, packages and dependencies of all go.work modules are listed,
+each package once. With -workfile FILE, the go.work FILE is used instead of the
+one found in the current directory or set by GOWORK, "off" disabling workspace
+mode. GOFLAGS apply to go commands as usual.
+With -retries N, go commands failing with transient errors, like network
+timeouts or proxy server errors while downloading modules, are retried up to N
+times, waiting 1s, 2s, 4s and so on between attempts. Other failures, like
+missing packages, are reported immediately. It defaults to 0.
+With -max-walk N, at most N parent directories of a package are searched for
+license files, so deeply nested packages are not attributed the license of a
+distant parent. It defaults to 0, without limit; 3 is a sensible value.
+With -min-bytes N, license files smaller than N bytes are ignored and parent
+directories searched instead, like for pointer stubs. Unlike stub detection, it
+does not depend on the files wording. It defaults to 0, keeping all files.
 
 With -a, all individual packages are displayed instead of grouping them by
-license files.
+license files. They are printed as soon as their license is resolved, except
+with -json. Streamed rows are aligned on a fixed column, which import paths
+longer than 46 characters overflow.
+With -group-by template, packages whose license exactly matches the same
+template are grouped too, even if they have different license files. The
+default, -group-by path, only groups packages sharing a license file.
+With -all-files, every license file of a package directory is matched and
+reported, instead of the best one only. It is useful for multi-licensed
+packages.
 With -w, words in package license file not found in the template license are
-displayed. It helps assessing the changes importance.
+displayed. It helps assessing the changes importance. Words of the license
+title and copyright notices, which usually differ without consequences, are
+displayed separately. The number of template words found in inexact license
+files, and of words they add, is displayed too, along with their size, tiny
+ones being usually stubs pointing to another license. License files with less
+common names, like LICENSE.docs or COPYING, have their name score displayed,
+from 1.0 for LICENSE down to 0.6 for files named after a license.
+With -diff, sentences differing between imperfect matches and their template
+are displayed, prefixed with "-" when missing from the license file and "+"
+when added to it. Output is limited to the first 20 differences.
+With -json, licenses are printed as a JSON array, for consumption by other
+tools. Entries have the import path based license file path, "licensePath", and
+its absolute filesystem path, "absPath", to open it. With -from-gomod, the
+latter is a temporary copy removed on exit. "nameScore" is the name score of
+the license file, see -w. "spdxExpression" is NOASSERTION for licenses unknown
+or below -confidence, and NONE for packages without license file.
+With -sbom FORMAT, licenses are printed as an SPDX 2.3 document, in "json" or
+"tag" (tag:value) FORMAT. Each entry is an SPDX package, whose concluded and
+declared licenses are NOASSERTION when unknown or below -confidence, and NONE
+without license file.
+With -cyclonedx, licenses are printed as a CycloneDX JSON BOM. Each entry is a
+library component, with its module version in module mode, and its SPDX license
+identifier or expression, or its license name if it has none. Unknown licenses
+and the ones below -confidence are omitted.
+With -sort, output is sorted by "package" import path, the default, by
+"license" name, unknown ones last, or by increasing "score", to review the
+least reliable matches first. Both text and JSON output are sorted.
+With -summary, a trailing line counts the displayed entries by license, the
+groups unless -a is set. Entries without license or scoring below -confidence
+are counted as unknown. It is ignored with -json.
+With -spdx, only the SPDX identifier of detected licenses is displayed.
+GPL-like license files allowing "any later version" of the license are
+reported with "-or-later" identifiers instead of "-only" ones.
+With -color auto, the default, the licenses of unknown or low-confidence
+entries are displayed in red and exact matches in green when printing to a
+terminal, unless the NO_COLOR environment variable is set. -color always and
+never force or disable colors, whatever the output and NO_COLOR.
+With -c, copyright lines found in license files are displayed.
+With -copyright-regex REGEXP, text matching REGEXP is stripped from license
+files before matching them, like copyright notices, in addition to the
+"Copyright YEAR HOLDER" lines stripped by default. Use "(?m)" to match whole
+lines with "^" and "$", and "(?i)" to ignore case.
+With -ignore-stopwords, very common English words like "the", "of" or "and" are
+ignored when matching licenses. They no longer appear in -w words differences,
+and close templates are slightly better told apart.
+With -o FILE, the report is written to FILE instead of stdout. FILE is replaced
+atomically once the report is complete, and left unchanged on error.
+With -notice FILE, an attribution document is written to FILE. It contains the
+text of each license file, with the packages using it and their copyright
+lines.
+With -tests, packages imported by the tests of specified packages, and their
+dependencies, are listed too. It can substantially expand the reported set,
+test frameworks and fixtures often pulling many packages.
+With -goos, -goarch and -tags, dependencies are resolved for the specified
+platform and comma-separated build tags instead of the current ones, to audit
+cross-compiled binaries.
+With -templates DIR, license templates are also loaded from *.txt files in DIR.
+They use the same front matter as embedded templates, delimited by "---" lines
+and defining at least a "title:" and optionally "nickname:", "spdx:",
+"category:" and "osiApproved:". "permissions:", "conditions:" and
+"limitations:" keys can be followed by "- rule" lines. Other keys are ignored.
+User templates replace embedded ones with the same nickname, or title when they
+have none. The flag can be repeated.
+With -from-gomod FILE, the licenses of the modules required by the FILE go.mod
+are read from the module zip archives served by GOPROXY, without building the
+dependency graph nor needing a local checkout. Only the license file at the
+root of each module is considered. Modules matching GOPRIVATE or GONOPROXY are
+not fetched, these variables being read with "go env" so "go env -w" settings
+apply, and archives are verified against the go.sum file next to FILE, modules
+missing from it being rejected, unless GONOSUMCHECK=1. Network access only
+happens in this mode.
+With -concurrency N, at most N license files are read and matched in parallel.
+It defaults to the number of usable CPUs, and can be lowered on network
+filesystems or constrained machines. go commands always run one at a time.
+License match results are cached by file content in the user cache directory,
+and reused until the templates change. With -no-cache, the cache is neither
+read nor written.
+With -overrides FILE, licenses are assigned to packages by FILE instead of
+being detected, and displayed with "(override)". FILE maps import paths to
+license SPDX identifiers, nicknames or titles, either as a JSON object or a
+flat YAML mapping like "github.com/foo/bar: MIT". Entries apply to the import
+path and its subpackages, the longest one winning. It defaults to
+.licenses.json, .licenses.yaml or .licenses.yml in the current directory, if
+any. Unknown license names are rejected.
+With -dir PATH, the directory tree at PATH is scanned for license files instead
+of Go packages, without running the go command. Results are reported per
+directory containing license files, relative to PATH. It works for non-Go
+source trees as well. .git, .hg, node_modules and testdata directories are
+skipped, unless -include-hidden is set.
+With -file PATH, the license file at PATH is matched and reported alone, which
+helps checking templates changes. Match results are not cached.
+With -list-templates, the title, nickname and SPDX identifier of the license
+templates are printed, sorted by title, including -templates ones. They are
+printed as a JSON array with -json, including their category, OSI approval,
+permissions, conditions and limitations, also reported in -json license
+entries.
+With -serve ADDR, licenses runs an HTTP server listening on ADDR, like ":8080",
+instead of listing packages. POST /match matches the license text of the
+request body and returns a JSON entry like -json ones, the optional "name"
+query parameter being the license file name. GET /templates lists the license
+templates like -list-templates -json, and GET /templates/NAME returns the
+embedded template NAME, like mit.txt. Matches honor -confidence, -top,
+-templates, -copyright-regex and -ignore-stopwords.
+With -exclude PATTERN, packages whose import path matches PATTERN are ignored.
+Patterns support "..." wildcards like go tooling, and the flag can be repeated.
+With -confidence, matches scoring below the threshold are displayed as unknown
+licenses, along with the best guess. It must be in (0, 1] and defaults to 0.9.
+Matches above the threshold adding words to their template, like an extra
+clause, are displayed as "modified" when they make up 6% or more of the license
+words, their terms possibly differing.
+With -top N, the N best matching templates of licenses scoring below
+-confidence are displayed, to help identifying ambiguous license files. They
+are also listed in JSON output.
+With -min-score, matches scoring below the threshold are displayed as
+unrecognized, without best guess. It must be in [0, 1] and defaults to 0,
+displaying all guesses. Packages without license file are displayed as such.
+Short license files reserving all rights without granting any, like
+"Copyright 2020 ACME. All rights reserved.", are displayed as "No License",
+with a "no_license" class in JSON output.
+A warning is printed on stderr for every module whose packages have license
+files matching different templates, like a MIT licensed module with a GPL
+licensed subdirectory. It lists a license file of each license. Outside of
+modules, packages conflict with the ones below the directory of their license
+file, vendored packages excepted.
+Packages which cannot be loaded or read are displayed with their error,
+prefixed with a category like "missing package", "no Go files" or "read error".
+
+With -deny, packages matching any of the comma-separated licenses, referred to
+by SPDX identifier, nickname or title, are reported on stderr and licenses
+exits with status 2. With -allow, any license not in the list is reported
+likewise. Unknown or low-confidence licenses are only reported when
+-deny-unknown is set. Files reserving all rights are reported with any of these
+flags.
+With -exceptions FILE, policy violations of the packages and licenses listed in
+FILE are approved and summarized on stderr, along with their justification,
+instead of failing. FILE is either a JSON array of objects or a YAML sequence
+of mappings, with "package", "license", "note" and "expires" keys. Like
+overrides, exceptions apply to subpackages. They stop applying on their
+"expires" date, formatted like 2030-01-31, and the violation is reported again.
+With -fail-on-unknown, licenses exits with status 2 if any package has no
+license file, an error, a license scoring below -confidence or one reserving
+all rights. They are listed on stderr, once per group unless -a is set.
+With -quiet, only packages with unknown licenses, reserving all rights or
+violating the policy are displayed, nothing if there are none. The summary is
+displayed for them only.
+With -compare REPORT, licenses are compared with the ones of REPORT, saved with
+-json and the same grouping flags, like -a. Packages "added", "removed" or
+whose license "changed", by SPDX identifier or title, are printed instead of
+licenses. Only added and changed packages are checked against the policy and
+-fail-on-unknown, so licenses exits with status 2 if a denied license appeared.
 `)
-		os.Exit(1)
 	}
-	all := flag.Bool("a", false, "display all individual packages")
-	words := flag.Bool("w", false, "display words not matching license template")
-	flag.Parse()
-	if flag.NArg() < 1 {
+	all := fs.Bool("a", false, "display all individual packages")
+	groupBy := fs.String("group-by", "path", "group packages by license path or template")
+	allFiles := fs.Bool("all-files", false, "report all license files of packages")
+	words := fs.Bool("w", false, "display words not matching license template")
+	jsonOutput := fs.Bool("json", false, "print licenses as JSON")
+	sbom := fs.String("sbom", "", "print licenses as an SPDX document, in json or tag format")
+	cyclonedx := fs.Bool("cyclonedx", false, "print licenses as a CycloneDX JSON BOM")
+	spdx := fs.Bool("spdx", false, "display SPDX license identifiers only")
+	copyright := fs.Bool("c", false, "display copyright lines")
+	diff := fs.Bool("diff", false, "display differences with template license")
+	notice := fs.String("notice", "", "write attribution document to file")
+	deny := fs.String("deny", "", "comma-separated list of forbidden licenses")
+	allow := fs.String("allow", "", "comma-separated list of allowed licenses")
+	failOnUnknown := fs.Bool("fail-on-unknown", false,
+		"fail if any package license is missing or unknown")
+	denyUnknown := fs.Bool("deny-unknown", false,
+		"reject unknown or low-confidence licenses")
+	confidence := fs.Float64("confidence", 0.9,
+		"minimum score of confident matches, in (0, 1]")
+	minScore := fs.Float64("min-score", 0,
+		"minimum score of displayed guesses, in [0, 1]")
+	tests := fs.Bool("tests", false, "include test dependencies")
+	goos := fs.String("goos", "", "resolve dependencies for target OS")
+	goarch := fs.String("goarch", "", "resolve dependencies for target architecture")
+	tags := fs.String("tags", "", "comma-separated list of build tags")
+	var exclude, templateDirs stringsFlag
+	fs.Var(&templateDirs, "templates", "load additional templates from directory, can be repeated")
+	fs.Var(&exclude, "exclude", "ignore packages matching pattern, can be repeated")
+	fromGoMod := fs.String("from-gomod", "",
+		"read licenses of go.mod requirements from the module proxy")
+	noCache := fs.Bool("no-cache", false, "do not cache match results on disk")
+	sortKey := fs.String("sort", "package", "sort output by package, license or score")
+	summary := fs.Bool("summary", false, "print the number of packages by license")
+	quiet := fs.Bool("quiet", false, "only display unknown licenses and policy violations")
+	outputPath := fs.String("o", "", "write the report to file instead of stdout")
+	top := fs.Int("top", 0, "display the N best guesses of unknown licenses")
+	dir := fs.String("dir", "", "scan license files of a directory tree instead of packages")
+	file := fs.String("file", "", "match a single license file instead of packages")
+	includeHidden := fs.Bool("include-hidden", false,
+		"scan .git, .hg, node_modules and testdata directories with -dir")
+	minBytes := fs.Int("min-bytes", 0,
+		"ignore license files smaller than N bytes, like stubs")
+	maxWalk := fs.Int("max-walk", 0,
+		"maximum number of parent directories searched for licenses, 0 for no limit")
+	stdlib := fs.Bool("stdlib", false,
+		"include standard packages, attributed to the Go license")
+	overridesPath := fs.String("overrides", "",
+		"read license overrides from file, defaults to .licenses.json or .licenses.yaml")
+	copyrightRegex := fs.String("copyright-regex", "",
+		"strip text matching regular expression from licenses, like copyright notices")
+	timeout := fs.Duration("timeout", 0, "abort listing packages after duration, like 2m")
+	retries := fs.Int("retries", 0, "retry go commands failing with network errors N times")
+	workfile := fs.String("workfile", "", "resolve packages with go.work file, or off")
+	modules := fs.Bool("modules", false, "report one entry per module instead of per package")
+	compare := fs.String("compare", "", "print license changes since a JSON report")
+	listTemplates := fs.Bool("list-templates", false, "list known license templates and exit")
+	ignoreStopwords := fs.Bool("ignore-stopwords", false,
+		"ignore common English words like \"the\" when matching licenses")
+	color := fs.String("color", "auto", "color text output: auto, always or never")
+	serve := fs.String("serve", "", "serve license matching over HTTP on address")
+	exceptionsPath := fs.String("exceptions", "",
+		"approve policy violations listed in JSON or YAML file")
+	concurrency := fs.Int("concurrency", runtime.GOMAXPROCS(0),
+		"number of license files read and matched in parallel")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *fromGoMod != "" && *dir != "" {
+		return fmt.Errorf("-from-gomod and -dir cannot be combined")
+	}
+	if *file != "" && (*fromGoMod != "" || *dir != "") {
+		return fmt.Errorf("-file cannot be combined with -from-gomod or -dir")
+	}
+	if *listTemplates {
+		if fs.NArg() > 0 {
+			return fmt.Errorf("-list-templates does not accept package arguments")
+		}
+		matcher, err := newMatcher(listOptions{TemplateDirs: templateDirs})
+		if err != nil {
+			return err
+		}
+		return writeTemplates(stdout, matcher.Templates(), *jsonOutput)
+	}
+	if *serve != "" && (fs.NArg() > 0 || *fromGoMod != "" || *dir != "" ||
+		*file != "") {
+		return fmt.Errorf("-serve cannot be combined with packages, -from-gomod, " +
+			"-dir or -file")
+	}
+	if fs.NArg() < 1 && *fromGoMod == "" && *dir == "" && *file == "" && *serve == "" {
 		return fmt.Errorf("expect at least one package argument")
 	}
-	pkgs := flag.Args()
-
-	confidence := 0.9
-	licenses, err := listLicenses("", pkgs)
-	if err != nil {
+	if fs.NArg() > 0 && *dir != "" {
+		return fmt.Errorf("-dir does not accept package arguments")
+	}
+	if fs.NArg() > 0 && *file != "" {
+		return fmt.Errorf("-file does not accept package arguments")
+	}
+	if err := checkConfidence(*confidence); err != nil {
 		return err
 	}
-	if !*all {
-		licenses, err = groupLicenses(licenses)
+	if *sbom != "" {
+		if err := checkSBOMFormat(*sbom); err != nil {
+			return err
+		}
+		if *jsonOutput {
+			return fmt.Errorf("-json and -sbom cannot be combined")
+		}
+	}
+	if *cyclonedx && (*jsonOutput || *sbom != "") {
+		return fmt.Errorf("-cyclonedx cannot be combined with -json or -sbom")
+	}
+	if *compare != "" && (*jsonOutput || *sbom != "" || *cyclonedx) {
+		return fmt.Errorf("-compare cannot be combined with -json, -sbom or -cyclonedx")
+	}
+	var report []jsonLicense
+	if *compare != "" {
+		var err error
+		report, err = readJSONReport(*compare)
 		if err != nil {
 			return err
 		}
 	}
-	w := tabwriter.NewWriter(os.Stdout, 1, 4, 2, ' ', 0)
-	for _, l := range licenses {
-		license := "?"
-		if l.Template != nil {
-			if l.Score > .99 {
-				license = fmt.Sprintf("%s", l.Template.Title)
-			} else if l.Score >= confidence {
-				license = fmt.Sprintf("%s (%2d%%)", l.Template.Title, int(100*l.Score))
-				if *words && len(l.ExtraWords) > 0 {
-					license += "\n\t+words: " + strings.Join(l.ExtraWords, ", ")
-				}
-				if *words && len(l.MissingWords) > 0 {
-					license += "\n\t-words: " + strings.Join(l.MissingWords, ", ")
-				}
-			} else {
-				license = fmt.Sprintf("? (%s, %2d%%)", l.Template.Title, int(100*l.Score))
+	if err := checkColor(*color); err != nil {
+		return err
+	}
+	if *groupBy != "path" && *groupBy != "template" {
+		return fmt.Errorf("group-by must be path or template, got %q", *groupBy)
+	}
+	if _, err := sortLicenses(nil, *sortKey); err != nil {
+		return err
+	}
+	if *top < 0 {
+		return fmt.Errorf("top must be positive, got %d", *top)
+	}
+	if *maxWalk < 0 {
+		return fmt.Errorf("max-walk must be positive, got %d", *maxWalk)
+	}
+	if *minBytes < 0 {
+		return fmt.Errorf("min-bytes must be positive, got %d", *minBytes)
+	}
+	if *concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1, got %d", *concurrency)
+	}
+	if *minScore < 0 || *minScore > 1 {
+		return fmt.Errorf("min-score must be in [0, 1], got %v", *minScore)
+	}
+	var excs exceptions
+	if *exceptionsPath != "" {
+		matcher, err := newMatcher(listOptions{TemplateDirs: templateDirs})
+		if err != nil {
+			return err
+		}
+		excs, err = loadExceptions(*exceptionsPath, matcher)
+		if err != nil {
+			return err
+		}
+	}
+	var copyrightRe *regexp.Regexp
+	if *copyrightRegex != "" {
+		re, err := regexp.Compile(*copyrightRegex)
+		if err != nil {
+			return fmt.Errorf("invalid copyright-regex: %s", err)
+		}
+		copyrightRe = re
+	}
+	if *serve != "" {
+		matcher, err := newMatcher(listOptions{
+			TemplateDirs:    templateDirs,
+			CopyrightRegexp: copyrightRe,
+			IgnoreStopwords: *ignoreStopwords,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stderr, "serving license matches on %s\n", *serve)
+		server := newHTTPServer(*serve, newServer(matcher, serverOptions{
+			Confidence: *confidence,
+			Top:        *top,
+		}))
+		return server.ListenAndServe()
+	}
+	if *workfile != "" && *workfile != "off" {
+		path, err := filepath.Abs(*workfile)
+		if err != nil {
+			return err
+		}
+		*workfile = path
+	}
+	pkgs := fs.Args()
+	if *overridesPath == "" {
+		*overridesPath = findOverridesFile(".")
+	}
+
+	textOpts := textOptions{
+		Confidence: *confidence,
+		MinScore:   *minScore,
+		Words:      *words,
+		SPDX:       *spdx,
+		Copyright:  *copyright,
+		Diff:       *diff,
+		Sort:       *sortKey,
+	}
+	// Individual packages are printed as soon as they are resolved, in
+	// package order. Grouping, JSON output and other orders need all of them.
+	// With a timeout, nothing is printed until listing completes.
+	stream := *all && !*jsonOutput && *sbom == "" && !*cyclonedx && *fromGoMod == "" &&
+		*dir == "" && *file == "" && *sortKey == "package" && !*quiet && *timeout <= 0 &&
+		*compare == ""
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	out := stdout
+	var outFile *atomicFile
+	if *outputPath != "" {
+		var err error
+		outFile, err = createAtomic(*outputPath)
+		if err != nil {
+			return err
+		}
+		defer outFile.Abort()
+		out = outFile
+	}
+	textOpts.Color = useColor(*color, isTerminal(out), os.LookupEnv)
+	cacheDir := ""
+	if !*noCache {
+		if userDir, err := os.UserCacheDir(); err == nil {
+			cacheDir = filepath.Join(userDir, "licenses")
+		}
+	}
+	licenses := []License{}
+	var err error
+	if *dir != "" {
+		licenses, err = listDirLicenses(*dir, listOptions{
+			AllFiles:        *allFiles,
+			TemplateDirs:    templateDirs,
+			CopyrightRegexp: copyrightRe,
+			IgnoreStopwords: *ignoreStopwords,
+			CacheDir:        cacheDir,
+			Top:             *top,
+			IncludeHidden:   *includeHidden,
+			Concurrency:     *concurrency,
+		})
+		if err != nil {
+			return err
+		}
+	} else if *file != "" {
+		licenses, err = listFileLicense(*file, listOptions{
+			TemplateDirs:    templateDirs,
+			CopyrightRegexp: copyrightRe,
+			IgnoreStopwords: *ignoreStopwords,
+			Top:             *top,
+		})
+		if err != nil {
+			return err
+		}
+	} else if *fromGoMod != "" {
+		matcher, err := newMatcher(listOptions{
+			TemplateDirs:    templateDirs,
+			CopyrightRegexp: copyrightRe,
+			IgnoreStopwords: *ignoreStopwords,
+		})
+		if err != nil {
+			return err
+		}
+		dir, err := ioutil.TempDir("", "licenses-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		licenses, err = listGoModLicenses(ctx, *fromGoMod, dir, matcher,
+			newProxyClient(*timeout))
+		if err != nil {
+			return err
+		}
+		if *overridesPath != "" {
+			o, err := loadOverrides(*overridesPath, matcher)
+			if err != nil {
+				return err
 			}
-		} else if l.Err != "" {
-			license = strings.Replace(l.Err, "\n", " ", -1)
+			for i, l := range licenses {
+				licenses[i] = o.Apply(l)
+			}
+		}
+	} else {
+		var streamed *textStream
+		if stream {
+			streamed = newTextStream(out, textOpts)
 		}
-		_, err = w.Write([]byte(l.Package + "\t" + license + "\n"))
+		err = listLicensesStream(ctx, "", pkgs, listOptions{
+			AllFiles:        *allFiles,
+			Tests:           *tests,
+			Exclude:         exclude,
+			GOOS:            *goos,
+			GOARCH:          *goarch,
+			Tags:            splitNames(*tags),
+			TemplateDirs:    templateDirs,
+			CacheDir:        cacheDir,
+			Overrides:       *overridesPath,
+			CopyrightRegexp: copyrightRe,
+			IgnoreStopwords: *ignoreStopwords,
+			MaxWalk:         *maxWalk,
+			MinBytes:        *minBytes,
+			Top:             *top,
+			Warnings:        stderr,
+			Stdlib:          *stdlib,
+			Retries:         *retries,
+			Workfile:        *workfile,
+			Modules:         *modules,
+			Concurrency:     *concurrency,
+		}, func(l License) error {
+			licenses = append(licenses, l)
+			if streamed != nil {
+				return streamed.Write(l)
+			}
+			return nil
+		})
 		if err != nil {
 			return err
 		}
 	}
-	return w.Flush()
+	if *notice != "" {
+		err = writeNoticeFile(*notice, licenses)
+		if err != nil {
+			return err
+		}
+	}
+	if !*all {
+		if *groupBy == "template" {
+			licenses, err = groupLicensesByTemplate(licenses)
+		} else {
+			licenses, err = groupLicenses(licenses)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	err = writeConflicts(stderr, findConflicts(licenses, *confidence))
+	if err != nil {
+		return err
+	}
+	p := &policy{
+		Deny:        splitNames(*deny),
+		Allow:       splitNames(*allow),
+		DenyUnknown: *denyUnknown,
+	}
+	displayed := licenses
+	if *quiet {
+		displayed = p.problems(licenses, *confidence)
+	}
+	if *compare != "" {
+		// Only packages added or changed since the report are checked
+		changes := diffReports(report, makeJSONLicenses(licenses, *confidence))
+		licenses = regressions(licenses, changes)
+		err = writeReportDiff(out, changes)
+	} else if *jsonOutput {
+		err = writeJSON(out, displayed, jsonOptions{
+			Sort:       *sortKey,
+			Confidence: *confidence,
+		})
+	} else if *cyclonedx {
+		err = writeCycloneDX(out, displayed, cyclonedxOptions{
+			Confidence: *confidence,
+			Created:    time.Now(),
+		})
+	} else if *sbom != "" {
+		err = writeSBOM(out, displayed, sbomOptions{
+			Format:     *sbom,
+			Confidence: *confidence,
+			Created:    time.Now(),
+		})
+	} else if !stream {
+		err = writeText(out, displayed, textOpts)
+	}
+	if err == nil && *summary && !*jsonOutput && *sbom == "" && !*cyclonedx &&
+		*compare == "" && (!*quiet || len(displayed) > 0) {
+		err = writeSummary(out, displayed, *confidence)
+	}
+	if err == nil && outFile != nil {
+		err = outFile.Commit()
+	}
+	if err != nil {
+		return err
+	}
+	violations := p.check(licenses, *confidence)
+	if len(excs) > 0 {
+		var approved, expired []excepted
+		violations, approved, expired = excs.Filter(violations, time.Now())
+		if err := writeExceptions(stderr, approved, expired); err != nil {
+			return err
+		}
+	}
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	if *failOnUnknown {
+		if unknown := unknownLicenses(licenses, *confidence); len(unknown) > 0 {
+			return &UnknownError{Licenses: unknown}
+		}
+	}
+	return nil
 }
 
 func main() {
-	err := printLicenses()
-	if err != nil {
+	err := printLicenses(os.Args[1:], os.Stdout, os.Stderr)
+	if err == errUsage {
+		os.Exit(1)
+	} else if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		switch err.(type) {
+		case *PolicyError, *UnknownError:
+			os.Exit(2)
+		}
 		os.Exit(1)
 	}
 }
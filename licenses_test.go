@@ -1,10 +1,22 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/pmezard/licenses/licensecheck"
 )
 
 type testResult struct {
@@ -13,15 +25,28 @@ type testResult struct {
 	Score   int
 	Extra   int
 	Missing int
+	Header  int
 	Err     string
 }
 
-func listTestLicenses(pkgs []string) ([]testResult, error) {
+// listTestdataLicensesWith runs listLicenses on supplied packages and
+// options, with testdata as GOPATH.
+func listTestdataLicensesWith(pkgs []string, opts listOptions) ([]License,
+	error) {
+
 	gopath, err := filepath.Abs("testdata")
 	if err != nil {
 		return nil, err
 	}
-	licenses, err := listLicenses(gopath, pkgs)
+	return listLicenses(context.Background(), gopath, pkgs, opts)
+}
+
+func listTestdataLicenses(pkgs []string) ([]License, error) {
+	return listTestdataLicensesWith(pkgs, listOptions{})
+}
+
+func listTestLicenses(pkgs []string) ([]testResult, error) {
+	licenses, err := listTestdataLicenses(pkgs)
 	if err != nil {
 		return nil, err
 	}
@@ -35,10 +60,11 @@ func listTestLicenses(pkgs []string) ([]testResult, error) {
 			r.Score = int(100 * l.Score)
 		}
 		if l.Err != "" {
-			r.Err = "some error"
+			r.Err = l.ErrCategory.String()
 		}
 		r.Extra = len(l.ExtraWords)
 		r.Missing = len(l.MissingWords)
+		r.Header = len(l.HeaderWords)
 		res = append(res, r)
 	}
 	return res, nil
@@ -58,6 +84,9 @@ func compareTestLicenses(pkgs []string, wanted []testResult) error {
 			if r.Missing > 0 {
 				s += fmt.Sprintf(" -%d", r.Missing)
 			}
+			if r.Header > 0 {
+				s += fmt.Sprintf(" ~%d", r.Header)
+			}
 			parts = append(parts, s)
 		}
 		return strings.Join(parts, "\n")
@@ -77,11 +106,23 @@ func compareTestLicenses(pkgs []string, wanted []testResult) error {
 
 func TestNoDependencies(t *testing.T) {
 	err := compareTestLicenses([]string{"colors/red"}, []testResult{
-		{Package: "colors/red", License: "MIT License", Score: 98, Missing: 2},
+		{Package: "colors/red", License: "MIT License", Score: 98, Header: 2},
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
+	licenses, err := listTestdataLicenses([]string{"colors/red"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := licenses[0]
+	if l.Common != 91 || l.LicenseWords != 91 || l.TemplateWords != 93 {
+		t.Fatalf("unexpected word counts: %d/%d template words, %d license words",
+			l.Common, l.TemplateWords, l.LicenseWords)
+	}
+	if got := formatOverlap(l); got != "matched 91/93 template words (0 extra)" {
+		t.Fatalf("unexpected overlap: %s", got)
+	}
 }
 
 func TestMultipleLicenses(t *testing.T) {
@@ -93,6 +134,36 @@ func TestMultipleLicenses(t *testing.T) {
 	}
 }
 
+func TestGzipLicense(t *testing.T) {
+	err := compareTestLicenses([]string{"colors/sienna"}, []testResult{
+		{Package: "colors/sienna", License: "MIT License", Score: 98, Header: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMarkdownLicense(t *testing.T) {
+	// colors/mint holds colors/red license wrapped in Markdown and HTML
+	licenses, err := listTestdataLicenses([]string{"colors/mint", "colors/red"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(licenses) != 2 {
+		t.Fatalf("unexpected licenses: %v", licenses)
+	}
+	md, plain := licenses[0], licenses[1]
+	if md.Template != plain.Template || md.Score != plain.Score ||
+		len(md.ExtraWords) != len(plain.ExtraWords) {
+		t.Fatalf("markdown license does not match like the plain one: %s %f %v != %s %f %v",
+			md.Template.Title, md.Score, md.ExtraWords, plain.Template.Title,
+			plain.Score, plain.ExtraWords)
+	}
+	if strings.Join(md.Copyright, "\n") != strings.Join(plain.Copyright, "\n") {
+		t.Fatalf("unexpected copyrights: %v", md.Copyright)
+	}
+}
+
 func TestNoLicense(t *testing.T) {
 	err := compareTestLicenses([]string{"colors/green"}, []testResult{
 		{Package: "colors/green", License: "", Score: 0},
@@ -106,7 +177,7 @@ func TestMainWithDependencies(t *testing.T) {
 	// It also tests license retrieval in parent directory.
 	err := compareTestLicenses([]string{"colors/cmd/paint"}, []testResult{
 		{Package: "colors/cmd/paint", License: "Academic Free License v3.0", Score: 100},
-		{Package: "colors/red", License: "MIT License", Score: 98, Missing: 2},
+		{Package: "colors/red", License: "MIT License", Score: 98, Header: 2},
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -116,7 +187,7 @@ func TestMainWithDependencies(t *testing.T) {
 func TestMainWithAliasedDependencies(t *testing.T) {
 	err := compareTestLicenses([]string{"colors/cmd/mix"}, []testResult{
 		{Package: "colors/cmd/mix", License: "Academic Free License v3.0", Score: 100},
-		{Package: "colors/red", License: "MIT License", Score: 98, Missing: 2},
+		{Package: "colors/red", License: "MIT License", Score: 98, Header: 2},
 		{Package: "couleurs/red", License: "GNU Lesser General Public License v2.1",
 			Score: 100},
 	})
@@ -138,7 +209,7 @@ func TestMissingPackage(t *testing.T) {
 func TestMismatch(t *testing.T) {
 	err := compareTestLicenses([]string{"colors/yellow"}, []testResult{
 		{Package: "colors/yellow", License: "Microsoft Reciprocal License", Score: 25,
-			Extra: 106, Missing: 131},
+			Extra: 104, Missing: 131},
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -155,11 +226,21 @@ func TestNoBuildableGoSourceFiles(t *testing.T) {
 	}
 }
 
+func TestImportCycle(t *testing.T) {
+	_, err := listTestLicenses([]string{"colors/loop/ouro"})
+	if err == nil {
+		t.Fatal("no error on import cycle")
+	}
+	if e, ok := err.(*BuildError); !ok || e.Package != "colors/loop/ouro" {
+		t.Fatalf("BuildError expected, got %#v", err)
+	}
+}
+
 func TestBroken(t *testing.T) {
 	err := compareTestLicenses([]string{"colors/broken"}, []testResult{
 		{Package: "colors/broken", License: "GNU General Public License v3.0", Score: 100},
-		{Package: "colors/missing", License: "", Score: 0, Err: "some error"},
-		{Package: "colors/red", License: "MIT License", Score: 98, Missing: 2},
+		{Package: "colors/missing", License: "", Score: 0, Err: "missing package"},
+		{Package: "colors/red", License: "MIT License", Score: 98, Header: 2},
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -169,20 +250,77 @@ func TestBroken(t *testing.T) {
 func TestBrokenDependency(t *testing.T) {
 	err := compareTestLicenses([]string{"colors/purple"}, []testResult{
 		{Package: "colors/broken", License: "GNU General Public License v3.0", Score: 100},
-		{Package: "colors/missing", License: "", Score: 0, Err: "some error"},
+		{Package: "colors/missing", License: "", Score: 0, Err: "missing package"},
 		{Package: "colors/purple", License: "", Score: 0},
-		{Package: "colors/red", License: "MIT License", Score: 98, Missing: 2},
+		{Package: "colors/red", License: "MIT License", Score: 98, Header: 2},
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
 }
 
+func TestOrLater(t *testing.T) {
+	licenses, err := listTestdataLicenses([]string{"colors/beige", "colors/plum"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, l := range licenses {
+		got = append(got, l.Package+" "+l.SPDX()+" "+matchName(l.MatchResult))
+	}
+	wanted := []string{
+		"colors/beige GPL-3.0-only GNU General Public License v3.0 [GPL-3.0-only]",
+		"colors/plum GPL-3.0-or-later GNU General Public License v3.0 [GPL-3.0-or-later]",
+	}
+	if strings.Join(got, "\n") != strings.Join(wanted, "\n") {
+		t.Fatalf("unexpected licenses:\n%s\n!=\n%s", strings.Join(got, "\n"),
+			strings.Join(wanted, "\n"))
+	}
+}
+
+func TestErrCategories(t *testing.T) {
+	failed := &PkgError{Err: "failed"}
+	tests := []struct {
+		Info     PkgInfo
+		Category ErrCategory
+	}{
+		{PkgInfo{Dir: "/a"}, ErrNone},
+		{PkgInfo{Error: failed}, ErrMissing},
+		{PkgInfo{Dir: "/a", Error: failed}, ErrNoGoFiles},
+		{PkgInfo{Dir: "/a", GoFiles: []string{"a.go"}, Error: failed}, ErrOther},
+	}
+	for i, test := range tests {
+		if c := pkgErrCategory(&test.Info); c != test.Category {
+			t.Errorf("%d: unexpected category %q != %q", i, c, test.Category)
+		}
+	}
+
+	licenses := []License{{
+		Package:     "a/b",
+		Err:         "open LICENSE:\npermission denied",
+		ErrCategory: ErrRead,
+	}}
+	buf := &bytes.Buffer{}
+	if err := writeText(buf, licenses, textOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "a/b  read error: open LICENSE: permission denied\n" {
+		t.Fatalf("unexpected output: %q", got)
+	}
+	buf.Reset()
+	if err := writeJSON(buf, licenses, jsonOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"errorCategory": "read error"`) {
+		t.Fatalf("error category not in JSON: %s", buf.String())
+	}
+}
+
 func TestPackageExpression(t *testing.T) {
 	err := compareTestLicenses([]string{"colors/cmd/..."}, []testResult{
 		{Package: "colors/cmd/mix", License: "Academic Free License v3.0", Score: 100},
 		{Package: "colors/cmd/paint", License: "Academic Free License v3.0", Score: 100},
-		{Package: "colors/red", License: "MIT License", Score: 98, Missing: 2},
+		{Package: "colors/red", License: "MIT License", Score: 98, Header: 2},
 		{Package: "couleurs/red", License: "GNU Lesser General Public License v2.1",
 			Score: 100},
 	})
@@ -191,24 +329,1401 @@ func TestPackageExpression(t *testing.T) {
 	}
 }
 
-func TestCleanLicenseData(t *testing.T) {
-	data := `The MIT License (MIT)
+func TestStandardPackages(t *testing.T) {
+	err := compareTestLicenses([]string{"encoding/json", "cmd/addr2line"}, []testResult{})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
 
-	Copyright (c) 2013 Ben Johnson
-	
-	Some other lines.
-	And more.
-	`
-	cleaned := string(cleanLicenseData([]byte(data)))
-	wanted := "the mit license (mit)\n\t\n\tsome other lines.\n\tand more.\n\t"
-	if wanted != cleaned {
-		t.Fatalf("license data mismatch: %q\n!=\n%q", cleaned, wanted)
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		Args    []string
+		Max     int
+		Batches string
+	}{
+		{[]string{}, 10, ""},
+		{[]string{"a", "b", "c"}, 10, "a b c"},
+		{[]string{"aaa", "bbb", "ccc"}, 7, "aaa bbb|ccc"},
+		{[]string{"aaa", "bbbbbbbbbb", "c"}, 5, "aaa|bbbbbbbbbb|c"},
+	}
+	for _, test := range tests {
+		batches := []string{}
+		for _, b := range splitArgs(test.Args, test.Max) {
+			batches = append(batches, strings.Join(b, " "))
+		}
+		if got := strings.Join(batches, "|"); got != test.Batches {
+			t.Errorf("unexpected batches for %v: %q != %q", test.Args, got,
+				test.Batches)
+		}
 	}
 }
 
-func TestStandardPackages(t *testing.T) {
-	err := compareTestLicenses([]string{"encoding/json", "cmd/addr2line"}, []testResult{})
+func TestGetPackagesInfoBatches(t *testing.T) {
+	gopath, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GO111MODULE", "off")
+	env := goEnv{GOPATH: gopath}
+	pkgs := []string{"colors/blue", "colors/missing", "colors/red", "colors/yellow",
+		"colors/broken"}
+	wanted, err := getPackagesInfo(context.Background(), env, pkgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := maxArgsLength
+	defer func() { maxArgsLength = old }()
+	maxArgsLength = 20
+	got, err := getPackagesInfo(context.Background(), env, pkgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, wanted) {
+		t.Fatalf("batched infos differ:\n%+v\n!=\n%+v", got, wanted)
+	}
+}
+
+func TestStdlib(t *testing.T) {
+	licenses, err := listTestdataLicensesWith([]string{"encoding/json"},
+		listOptions{Stdlib: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, l := range licenses {
+		if l.Package != "encoding/json" {
+			continue
+		}
+		found = true
+		if l.Path != "$GOROOT/LICENSE" || l.SPDX() != "BSD-3-Clause" || l.Score < 0.9 {
+			t.Fatalf("unexpected encoding/json license: %s %s %f", l.Path, l.SPDX(),
+				l.Score)
+		}
+	}
+	if !found {
+		t.Fatalf("encoding/json was not listed: %+v", licenses)
+	}
+}
+
+// enterModule switches the test to module mode, in supplied testdata module
+// directory.
+func enterModule(t *testing.T, name string) {
+	t.Setenv("GO111MODULE", "on")
+	t.Setenv("GOFLAGS", "")
+	t.Chdir(filepath.Join("testdata", "mod", name))
+}
+
+func TestModule(t *testing.T) {
+	enterModule(t, "shapes")
+	licenses, err := listLicenses(context.Background(), "", []string{"shapes/cmd/draw"},
+		listOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, l := range licenses {
+		s := fmt.Sprintf("%s %s", l.Package, l.Path)
+		if l.Template != nil {
+			s += fmt.Sprintf(" \"%s\" %d%%", l.Template.Title, int(100*l.Score))
+		}
+		got = append(got, s)
+	}
+	wanted := []string{
+		`shapes/circle shapes/LICENSE "BSD 2-clause "Simplified" License" 100%`,
+		`shapes/cmd/draw shapes/LICENSE "BSD 2-clause "Simplified" License" 100%`,
+	}
+	if strings.Join(got, "\n") != strings.Join(wanted, "\n") {
+		t.Fatalf("licenses do not match:\n%s\n!=\n%s",
+			strings.Join(got, "\n"), strings.Join(wanted, "\n"))
+	}
+}
+
+func TestModulesOnly(t *testing.T) {
+	// shapes/circle and shapes/cmd/draw belong to the same module
+	enterModule(t, "shapes")
+	licenses, err := listLicenses(context.Background(), "", []string{"shapes/cmd/draw"},
+		listOptions{Modules: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, l := range licenses {
+		s := fmt.Sprintf("%s %s", l.Package, l.Path)
+		if l.Template != nil {
+			s += " " + l.Template.SPDX
+		}
+		got = append(got, s)
+	}
+	wanted := []string{
+		"shapes shapes/LICENSE BSD-2-Clause",
+	}
+	if strings.Join(got, "\n") != strings.Join(wanted, "\n") {
+		t.Fatalf("licenses do not match:\n%s\n!=\n%s",
+			strings.Join(got, "\n"), strings.Join(wanted, "\n"))
+	}
+}
+
+func TestModuleReplace(t *testing.T) {
+	// example.com/paint does not exist upstream, it is replaced by a local
+	// ISC licensed module.
+	enterModule(t, "canvas")
+	t.Setenv("GOPROXY", "off")
+	licenses, err := listLicenses(context.Background(), "", []string{"canvas/cmd/fill"},
+		listOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, l := range licenses {
+		s := fmt.Sprintf("%s %s", l.Package, l.Path)
+		if l.Template != nil {
+			s += " " + l.Template.SPDX
+		}
+		if l.Replace != "" {
+			s += " => " + l.Replace
+		}
+		got = append(got, s)
+	}
+	wanted := []string{
+		"canvas/cmd/fill canvas/LICENSE BSD-2-Clause",
+		"example.com/paint example.com/paint/LICENSE ISC => ../paint",
+	}
+	if strings.Join(got, "\n") != strings.Join(wanted, "\n") {
+		t.Fatalf("licenses do not match:\n%s\n!=\n%s",
+			strings.Join(got, "\n"), strings.Join(wanted, "\n"))
+	}
+	if !strings.HasSuffix(licenses[1].AbsPath, filepath.Join("paint", "LICENSE")) {
+		t.Fatalf("replacement license was not read: %s", licenses[1].AbsPath)
+	}
+	buf := &bytes.Buffer{}
+	err = writeText(buf, licenses[1:], textOptions{Confidence: 0.9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "example.com/paint  ISC License [ISC] (replaced by ../paint)\n" {
+		t.Fatalf("unexpected output: %q", buf.String())
+	}
+}
+
+func TestWorkspace(t *testing.T) {
+	// Both modules of the workspace and their dependencies are listed, the
+	// local replacement of canvas dependency included.
+	enterModule(t, "workspace")
+	t.Setenv("GOPROXY", "off")
+	t.Setenv("GOWORK", "")
+	wanted := []string{
+		"canvas/cmd/fill canvas/LICENSE BSD-2-Clause",
+		"example.com/paint example.com/paint/LICENSE ISC",
+		"shapes/circle shapes/LICENSE BSD-2-Clause",
+		"shapes/cmd/draw shapes/LICENSE BSD-2-Clause",
+	}
+	list := func(opts listOptions) ([]string, error) {
+		licenses, err := listLicenses(context.Background(), "",
+			[]string{"canvas/...", "shapes/...", "shapes/cmd/draw"}, opts)
+		if err != nil {
+			return nil, err
+		}
+		got := []string{}
+		for _, l := range licenses {
+			s := fmt.Sprintf("%s %s", l.Package, l.Path)
+			if l.Template != nil {
+				s += " " + l.Template.SPDX
+			}
+			got = append(got, s)
+		}
+		return got, nil
+	}
+	got, err := list(listOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, "\n") != strings.Join(wanted, "\n") {
+		t.Fatalf("licenses do not match:\n%s\n!=\n%s",
+			strings.Join(got, "\n"), strings.Join(wanted, "\n"))
+	}
+	workfile, err := filepath.Abs("go.work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := list(listOptions{Workfile: "off"}); err == nil {
+		t.Fatal("packages were listed outside of workspace mode")
+	}
+
+	// Explicit go.work files apply outside of the workspace directory
+	t.Chdir(filepath.Join("..", "shapes"))
+	got, err = list(listOptions{Workfile: workfile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, "\n") != strings.Join(wanted, "\n") {
+		t.Fatalf("licenses do not match with workfile:\n%s\n!=\n%s",
+			strings.Join(got, "\n"), strings.Join(wanted, "\n"))
+	}
+}
+
+func TestCheckConfidence(t *testing.T) {
+	for _, c := range []float64{0.01, 0.5, 0.9, 1} {
+		if err := checkConfidence(c); err != nil {
+			t.Fatalf("unexpected error for %v: %s", c, err)
+		}
+	}
+	for _, c := range []float64{-1, 0, 1.01} {
+		if err := checkConfidence(c); err == nil {
+			t.Fatalf("%v should be rejected", c)
+		}
+	}
+}
+
+func TestVendoredPackages(t *testing.T) {
+	// The vendored packages must not be attributed the shades license.
+	err := compareTestLicenses([]string{"shades/dark"}, []testResult{
+		{Package: "shades/dark", License: "MIT License", Score: 98, Header: 2},
+		{Package: "shades/vendor/bare", License: "", Score: 0},
+		{Package: "shades/vendor/ink/black", License: `BSD 2-clause "Simplified" License`,
+			Score: 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAllFiles(t *testing.T) {
+	licenses, err := listTestdataLicensesWith([]string{"colors/blue"},
+		listOptions{AllFiles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, l := range licenses {
+		got = append(got, fmt.Sprintf("%s %s \"%s\" %d%%", l.Package, l.Path,
+			l.Template.Title, int(100*l.Score)))
+	}
+	wanted := []string{
+		`colors/blue colors/blue/LICENSE "Apache License 2.0" 100%`,
+		`colors/blue colors/blue/COPYING "MIT License" 98%`,
+	}
+	if strings.Join(got, "\n") != strings.Join(wanted, "\n") {
+		t.Fatalf("licenses do not match:\n%s\n!=\n%s",
+			strings.Join(got, "\n"), strings.Join(wanted, "\n"))
+	}
+}
+
+func TestNameScore(t *testing.T) {
+	licenses, err := listTestdataLicensesWith([]string{"colors/peach"},
+		listOptions{AllFiles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, l := range licenses {
+		got = append(got, fmt.Sprintf("%s %.1f", l.Path, l.NameScore))
+	}
+	wanted := []string{
+		"colors/peach/LICENSE 1.0",
+		"colors/peach/LICENSE.2 0.7",
+	}
+	if strings.Join(got, "\n") != strings.Join(wanted, "\n") {
+		t.Fatalf("name scores do not match:\n%s\n!=\n%s",
+			strings.Join(got, "\n"), strings.Join(wanted, "\n"))
+	}
+}
+
+func TestLicenseURL(t *testing.T) {
+	// colors/jade LICENSE only holds the Apache license URL
+	licenses, err := listTestdataLicenses([]string{"colors/jade"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(licenses) != 1 {
+		t.Fatalf("unexpected licenses: %+v", licenses)
+	}
+	l := licenses[0]
+	if l.SPDX() != "Apache-2.0" || classify(l, 0.9).Class != Confident {
+		t.Fatalf("unexpected license: %s %.2f", l.SPDX(), l.Score)
+	}
+	if l.URL != "https://www.apache.org/licenses/LICENSE-2.0" {
+		t.Fatalf("unexpected URL: %q", l.URL)
+	}
+}
+
+func TestVendoredLicense(t *testing.T) {
+	// colors/rose LICENSE is marked as vendored in .gitattributes
+	err := compareTestLicenses([]string{"colors/rose"}, []testResult{
+		{Package: "colors/rose", License: "MIT License", Score: 98, Header: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	licenses, err := listTestdataLicensesWith([]string{"colors/rose"},
+		listOptions{AllFiles: true})
 	if err != nil {
 		t.Fatal(err)
 	}
+	got := []string{}
+	for _, l := range licenses {
+		got = append(got, l.Path)
+	}
+	wanted := []string{
+		"colors/rose/COPYING",
+		"colors/rose/LICENSE",
+	}
+	if strings.Join(got, "\n") != strings.Join(wanted, "\n") {
+		t.Fatalf("licenses do not match:\n%s\n!=\n%s",
+			strings.Join(got, "\n"), strings.Join(wanted, "\n"))
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	pkgs := []string{"colors/cmd/...", "colors/blue", "colors/green", "colors/lavender",
+		"colors/onyx", "colors/peach", "colors/red", "colors/yellow"}
+	sequential, err := listTestdataLicensesWith(pkgs, listOptions{AllFiles: true,
+		Top: 2, Concurrency: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parallel, err := listTestdataLicensesWith(pkgs, listOptions{AllFiles: true,
+		Top: 2, Concurrency: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Templates are loaded again by each call, compare JSON entries
+	wanted, err := json.MarshalIndent(makeJSONLicenses(sequential, 0.9), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.MarshalIndent(makeJSONLicenses(parallel, 0.9), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sequential) < len(pkgs) || string(got) != string(wanted) {
+		t.Fatalf("parallel results differ:\n%s\n!=\n%s", got, wanted)
+	}
+	err = printLicenses([]string{"-concurrency", "0", "colors/red"}, &bytes.Buffer{},
+		&bytes.Buffer{})
+	if err == nil {
+		t.Fatal("null concurrency was accepted")
+	}
+}
+
+func TestSymlinkedLicense(t *testing.T) {
+	// colors/onyx LICENSE links to colors/red one, COPYING is a broken link
+	// and COPYING.loop links to itself.
+	err := compareTestLicenses([]string{"colors/onyx"}, []testResult{
+		{Package: "colors/onyx", License: "MIT License", Score: 98, Header: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	licenses, err := listTestdataLicensesWith([]string{"colors/onyx"},
+		listOptions{AllFiles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(licenses) != 1 || licenses[0].Path != "colors/onyx/LICENSE" {
+		t.Fatalf("unexpected licenses: %+v", licenses)
+	}
+	dirLicenses, err := listDirLicenses(filepath.Join("testdata", "src", "colors", "onyx"),
+		listOptions{AllFiles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirLicenses) != 1 || dirLicenses[0].Path != "LICENSE" {
+		t.Fatalf("unexpected directory licenses: %+v", dirLicenses)
+	}
+}
+
+func TestLesserLicense(t *testing.T) {
+	// COPYING holds the GPL, COPYING.LESSER the LGPL applying to the package
+	err := compareTestLicenses([]string{"colors/lavender"}, []testResult{
+		{Package: "colors/lavender", License: "GNU Lesser General Public License v3.0",
+			Score: 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	licenses, err := listTestdataLicenses([]string{"colors/lavender"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if licenses[0].Path != "colors/lavender/COPYING.LESSER" {
+		t.Fatalf("unexpected license file: %s", licenses[0].Path)
+	}
+
+	licenses, err = listTestdataLicensesWith([]string{"colors/lavender"},
+		listOptions{AllFiles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, l := range licenses {
+		got = append(got, l.Path+" "+l.Template.Title)
+	}
+	wanted := []string{
+		"colors/lavender/COPYING.LESSER GNU Lesser General Public License v3.0",
+		"colors/lavender/COPYING GNU General Public License v3.0",
+	}
+	if strings.Join(got, "\n") != strings.Join(wanted, "\n") {
+		t.Fatalf("licenses do not match:\n%s\n!=\n%s",
+			strings.Join(got, "\n"), strings.Join(wanted, "\n"))
+	}
+}
+
+func TestSortLicenseFiles(t *testing.T) {
+	files := []licenseFile{
+		{Name: "COPYING", Score: 0.8},
+		{Name: "COPYING.LIB", Score: 0.8},
+	}
+	sortLicenseFiles(files)
+	if files[0].Name != "COPYING.LIB" {
+		t.Fatalf("LGPL file was not preferred: %v", files)
+	}
+	// only files named like COPYING give way to LGPL ones
+	files = []licenseFile{
+		{Name: "LICENSE", Score: 1},
+		{Name: "COPYING.LESSER", Score: 0.8},
+	}
+	sortLicenseFiles(files)
+	if files[0].Name != "LICENSE" {
+		t.Fatalf("LICENSE was not preferred: %v", files)
+	}
+}
+
+func TestDualLicense(t *testing.T) {
+	licenses, err := listTestdataLicenses([]string{"colors/black"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(licenses) != 1 {
+		t.Fatalf("expected one license, got %d", len(licenses))
+	}
+	l := licenses[0]
+	if len(l.Parts) != 2 {
+		t.Fatalf("expected two license parts, got %d", len(l.Parts))
+	}
+	name := matchName(l.MatchResult)
+	if name != "MIT License [MIT] OR Apache License 2.0 [Apache-2.0]" {
+		t.Fatalf("unexpected license name: %s", name)
+	}
+	if spdx := formatSPDX(l, 0.9); spdx != "MIT OR Apache-2.0" {
+		t.Fatalf("unexpected SPDX expression: %s", spdx)
+	}
+	if l.Score < 0.9 {
+		t.Fatalf("dual license score is too low: %f", l.Score)
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		Pattern string
+		Path    string
+		Match   bool
+	}{
+		{"colors/red", "colors/red", true},
+		{"colors/red", "colors/redder", false},
+		{"colors/red", "colors/red/dark", false},
+		{"colors/...", "colors", true},
+		{"colors/...", "colors/red", true},
+		{"colors/...", "colors/cmd/mix", true},
+		{"colors/...", "colorsfoo", false},
+		{"colors...", "colorsfoo/bar", true},
+		{"c...s/red", "couleurs/red", true},
+		{"c...s/red", "couleurs/blue", false},
+		{"colors/.../paint", "colors/cmd/paint", true},
+	}
+	for _, test := range tests {
+		if matchPattern(test.Pattern)(test.Path) != test.Match {
+			t.Errorf("%q matching %q should be %v", test.Pattern, test.Path,
+				test.Match)
+		}
+	}
+}
+
+func TestExclude(t *testing.T) {
+	tests := []struct {
+		Pkgs    []string
+		Exclude []string
+		Wanted  string
+	}{
+		// Exact, excluded missing packages are not reported.
+		{[]string{"colors/purple"}, []string{"colors/missing"},
+			"colors/broken,colors/purple,colors/red"},
+		// Prefix
+		{[]string{"colors/cmd/mix"}, []string{"colors/..."}, "couleurs/red"},
+		// Wildcard
+		{[]string{"colors/cmd/mix"}, []string{"c...s/red"}, "colors/cmd/mix"},
+		// Repeated
+		{[]string{"colors/cmd/mix"}, []string{"colors/red", "colors/cmd/..."},
+			"couleurs/red"},
+	}
+	for _, test := range tests {
+		licenses, err := listTestdataLicensesWith(test.Pkgs,
+			listOptions{Exclude: test.Exclude})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, l := range licenses {
+			got = append(got, l.Package)
+		}
+		if strings.Join(got, ",") != test.Wanted {
+			t.Fatalf("unexpected packages excluding %v: %s != %s", test.Exclude,
+				strings.Join(got, ","), test.Wanted)
+		}
+	}
+}
+
+func TestTestDependencies(t *testing.T) {
+	for _, tests := range []bool{false, true} {
+		licenses, err := listTestdataLicensesWith([]string{"colors/white"},
+			listOptions{Tests: tests})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, l := range licenses {
+			got = append(got, l.Package)
+		}
+		wanted := "colors/white"
+		if tests {
+			wanted = "colors/red,colors/white,couleurs/red"
+		}
+		if strings.Join(got, ",") != wanted {
+			t.Fatalf("unexpected packages with tests=%v: %s != %s", tests,
+				strings.Join(got, ","), wanted)
+		}
+	}
+}
+
+func TestGoCommand(t *testing.T) {
+	cmd := goCommand(context.Background(), goEnv{}, "list", "std")
+	if cmd.Env != nil {
+		t.Fatalf("unexpected environment: %v", cmd.Env)
+	}
+	cmd = goCommand(context.Background(), goEnv{
+		GOPATH: "/gopath",
+		GOOS:   "windows",
+		GOARCH: "arm64",
+		GOWORK: "off",
+		Tags:   []string{"a", "b"},
+	}, "list", "-e", "std")
+	if args := strings.Join(cmd.Args, " "); args != "go list -tags a,b -e std" {
+		t.Fatalf("unexpected arguments: %s", args)
+	}
+	found := map[string]int{}
+	for _, e := range cmd.Env {
+		found[e]++
+	}
+	for _, e := range []string{"GOPATH=/gopath", "GOOS=windows", "GOARCH=arm64",
+		"GOWORK=off"} {
+		if found[e] != 1 {
+			t.Fatalf("%s not set once in %v", e, cmd.Env)
+		}
+	}
+	if len(found) != len(cmd.Env) {
+		t.Fatalf("duplicate variables in %v", cmd.Env)
+	}
+}
+
+func TestGoListWarnings(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake go command is a shell script")
+	}
+	fake := filepath.Join(t.TempDir(), "go")
+	err := ioutil.WriteFile(fake, []byte(`#!/bin/sh
+echo "go: downloading example.com/a v1.0.0" >&2
+echo '{"ImportPath": "example.com/a", "Name": "a"}'
+`), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(bin string) { goBin = bin }(goBin)
+	goBin = fake
+
+	warnings := &bytes.Buffer{}
+	infos, err := goList(context.Background(), goEnv{Warnings: warnings}, nil,
+		[]string{"example.com/a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].ImportPath != "example.com/a" {
+		t.Fatalf("unexpected packages: %+v", infos)
+	}
+	if got := warnings.String(); got != "go: downloading example.com/a v1.0.0\n" {
+		t.Fatalf("unexpected warnings: %q", got)
+	}
+	// Warnings are optional
+	_, err = goList(context.Background(), goEnv{}, nil, []string{"example.com/a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGoListRetries(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake go command is a shell script")
+	}
+	dir := t.TempDir()
+	fake := filepath.Join(dir, "go")
+	// Fail with the error stored in the "error" file, once
+	err := ioutil.WriteFile(fake, []byte(`#!/bin/sh
+dir=$(dirname "$0")
+echo run >> "$dir/runs"
+if [ -f "$dir/error" ]; then
+	cat "$dir/error" >&2
+	rm "$dir/error"
+	exit 1
+fi
+echo '{"ImportPath": "example.com/a", "Name": "a"}'
+`), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(bin string, delay time.Duration) {
+		goBin, retryDelay = bin, delay
+	}(goBin, retryDelay)
+	goBin = fake
+	retryDelay = time.Millisecond
+
+	tests := []struct {
+		Error   string
+		Retries int
+		Runs    int
+		Failed  bool
+	}{
+		{"", 0, 1, false},
+		{"dial tcp 10.0.0.1:443: i/o timeout", 0, 1, true},
+		{"dial tcp 10.0.0.1:443: i/o timeout", 2, 2, false},
+		{"reading https://proxy.golang.org/a/@v/list: 502 Bad Gateway", 1, 2, false},
+		{"reading https://proxy.golang.org/a/@v/list: 503 Service Unavailable", 1, 2,
+			false},
+		// Deterministic errors are not retried
+		{"cannot find package \"example.com/a\"", 2, 1, true},
+		{"cannot find package \"github.com/x/timeout\"", 2, 1, true},
+		{"github.com/x/y@v1.502.0: invalid version: unknown revision v1.502.0", 2, 1,
+			true},
+	}
+	for i, test := range tests {
+		os.Remove(filepath.Join(dir, "runs"))
+		if test.Error != "" {
+			err := ioutil.WriteFile(filepath.Join(dir, "error"),
+				[]byte(test.Error+"\n"), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		warnings := &bytes.Buffer{}
+		_, err := goList(context.Background(), goEnv{
+			Retries:  test.Retries,
+			Warnings: warnings,
+		}, nil, []string{"example.com/a"})
+		if (err != nil) != test.Failed {
+			t.Errorf("%d: unexpected error: %v", i, err)
+		}
+		runs, err := ioutil.ReadFile(filepath.Join(dir, "runs"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(string(runs), "run"); n != test.Runs {
+			t.Errorf("%d: go ran %d times instead of %d", i, n, test.Runs)
+		}
+		retried := strings.Contains(warnings.String(), "retrying")
+		if retried != (test.Runs > 1) {
+			t.Errorf("%d: unexpected warnings: %q", i, warnings.String())
+		}
+		os.Remove(filepath.Join(dir, "error"))
+	}
+}
+
+func TestListCancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake go command is a shell script")
+	}
+	fake := filepath.Join(t.TempDir(), "go")
+	err := ioutil.WriteFile(fake, []byte(`#!/bin/sh
+echo '{"ImportPath": "example.com/a", "Name": "a"}'
+exec sleep 10
+`), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(bin string) { goBin = bin }(goBin)
+	goBin = fake
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	licenses, err := listLicenses(ctx, "", []string{"example.com/a"}, listOptions{})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if licenses != nil {
+		t.Fatalf("partial licenses were returned: %+v", licenses)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("go command was not killed after %s", elapsed)
+	}
+
+	// Canceled contexts do not run anything
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = listLicenses(ctx, "", []string{"colors/red"}, listOptions{})
+	if err != context.Canceled {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPlatformDependencies(t *testing.T) {
+	tests := []struct {
+		Opts   listOptions
+		Wanted string
+	}{
+		{listOptions{GOOS: "linux"}, "colors/tan"},
+		{listOptions{GOOS: "windows", GOARCH: "amd64"}, "colors/red,colors/tan"},
+		{listOptions{GOOS: "linux", Tags: []string{"dark"}},
+			"colors/tan,couleurs/red"},
+	}
+	for _, test := range tests {
+		licenses, err := listTestdataLicensesWith([]string{"colors/tan"}, test.Opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, l := range licenses {
+			got = append(got, l.Package)
+		}
+		if strings.Join(got, ",") != test.Wanted {
+			t.Fatalf("unexpected packages with %+v: %s != %s", test.Opts,
+				strings.Join(got, ","), test.Wanted)
+		}
+	}
+}
+
+func TestGoCommandsCount(t *testing.T) {
+	atomic.StoreInt32(&goCommands, 0)
+	_, err := listTestdataLicenses([]string{"colors/cmd/mix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&goCommands); n != 1 {
+		t.Fatalf("expected a single go command, got %d", n)
+	}
+}
+
+func TestListPackagesFallback(t *testing.T) {
+	gopath, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := goEnv{GOPATH: gopath}
+	pkgs := []string{"colors/purple", "colors/cmd/..."}
+	stringify := func(infos []*PkgInfo) string {
+		names := []string{}
+		for _, info := range infos {
+			s := info.ImportPath + " " + info.Dir
+			if info.Error != nil {
+				s += " error"
+			}
+			names = append(names, s)
+		}
+		return strings.Join(names, "\n")
+	}
+	infos, err := listPackagesInfo(context.Background(), env, pkgs, listOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fallback, err := listPackagesInfoFallback(context.Background(), env, pkgs, listOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, wanted := stringify(fallback), stringify(infos)
+	if got != wanted {
+		t.Fatalf("fallback packages differ:\n%s\n!=\n%s", got, wanted)
+	}
+}
+
+func TestListPackagesNoFallback(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake go command is a shell script")
+	}
+	dir := t.TempDir()
+	fake := filepath.Join(dir, "go")
+	// Fail like go commands with an invalid GOFLAGS
+	err := ioutil.WriteFile(fake, []byte(`#!/bin/sh
+echo run >> "$(dirname "$0")/runs"
+echo 'go: parsing $GOFLAGS: non-flag "bad"' >&2
+exit 1
+`), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(bin string) {
+		goBin = bin
+	}(goBin)
+	goBin = fake
+	_, err = listPackagesInfo(context.Background(), goEnv{}, []string{"example.com/a"},
+		listOptions{})
+	if err == nil || !strings.Contains(err.Error(), "GOFLAGS") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	runs, err := ioutil.ReadFile(filepath.Join(dir, "runs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(runs), "run"); n != 1 {
+		t.Fatalf("go ran %d times instead of 1", n)
+	}
+	if !isDepsUnsupported(fmt.Errorf("'go list -e -json -deps' failed with:\n" +
+		"flag provided but not defined: -deps")) {
+		t.Fatal("go versions without -deps are not detected")
+	}
+}
+
+func TestStandardPackagesMemoized(t *testing.T) {
+	gopath, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := goEnv{GOPATH: gopath}
+	pkgs := []string{"colors/cmd/mix"}
+	key := stdPackagesKey(env)
+	stdPackages.Lock()
+	delete(stdPackages.byKey, key)
+	stdPackages.Unlock()
+	counts := []int32{}
+	for i := 0; i < 2; i++ {
+		atomic.StoreInt32(&goCommands, 0)
+		_, err := listPackagesInfoFallback(context.Background(), env, pkgs, listOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts = append(counts, atomic.LoadInt32(&goCommands))
+	}
+	// The second call does not list standard packages again
+	if counts[1] != counts[0]-1 {
+		t.Fatalf("unexpected go commands counts: %v", counts)
+	}
+	if stdPackagesKey(goEnv{GOPATH: gopath, GOOS: "plan9"}) == key {
+		t.Fatal("standard packages key ignores GOOS")
+	}
+}
+
+func TestTemplateDirs(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join("testdata", "templates"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dirs := range [][]string{nil, {dir}} {
+		licenses, err := listTestdataLicensesWith([]string{"colors/gold"},
+			listOptions{TemplateDirs: dirs})
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := licenses[0]
+		if dirs == nil {
+			if l.Template != nil && l.Score >= 0.9 {
+				t.Fatalf("unexpected match without user templates: %s %f",
+					l.Template.Title, l.Score)
+			}
+			continue
+		}
+		if l.Template == nil || l.Template.SPDX != "LicenseRef-ACME-Internal" ||
+			l.Score < 0.99 {
+			t.Fatalf("user template did not match: %+v", l.MatchResult)
+		}
+	}
+}
+
+func TestPermissiveLicenses(t *testing.T) {
+	// 0BSD and MIT-0 texts are subsets of ISC and MIT ones, check they are
+	// told apart in both directions.
+	err := compareTestLicenses([]string{"colors/cyan", "colors/magenta",
+		"colors/olive", "colors/red", "colors/teal"}, []testResult{
+		{Package: "colors/cyan", License: "BSD Zero Clause License", Score: 100},
+		{Package: "colors/magenta", License: "MIT No Attribution", Score: 100},
+		{Package: "colors/olive", License: "ISC License", Score: 100},
+		{Package: "colors/red", License: "MIT License", Score: 98, Header: 2},
+		{Package: "colors/teal", License: "zlib License", Score: 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDocumentationLicenses(t *testing.T) {
+	licenses, err := listTestdataLicensesWith([]string{"colors/coral", "colors/navy"},
+		listOptions{AllFiles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, l := range licenses {
+		got = append(got, fmt.Sprintf("%s %s %s %d%%", l.Package, l.Path,
+			l.Template.SPDX, int(100*l.Score)))
+	}
+	wanted := []string{
+		"colors/coral colors/coral/LICENSE.docs CC-BY-SA-4.0 100%",
+		"colors/navy colors/navy/LICENSE MIT 98%",
+		"colors/navy colors/navy/COPYING.docs CC-BY-4.0 100%",
+	}
+	if strings.Join(got, "\n") != strings.Join(wanted, "\n") {
+		t.Fatalf("licenses do not match:\n%s\n!=\n%s",
+			strings.Join(got, "\n"), strings.Join(wanted, "\n"))
+	}
+}
+
+func TestListLicensesStream(t *testing.T) {
+	gopath, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	pkgs := []string{"colors/cmd/mix"}
+	licenses, err := listLicenses(ctx, gopath, pkgs, listOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamed := []string{}
+	err = listLicensesStream(ctx, gopath, pkgs, listOptions{}, func(l License) error {
+		streamed = append(streamed, l.Package)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed := []string{}
+	for _, l := range licenses {
+		listed = append(listed, l.Package)
+	}
+	if strings.Join(streamed, ",") != strings.Join(listed, ",") {
+		t.Fatalf("streamed licenses differ: %v != %v", streamed, listed)
+	}
+
+	// Callback errors abort the listing.
+	calls := 0
+	stop := fmt.Errorf("stop")
+	err = listLicensesStream(ctx, gopath, pkgs, listOptions{}, func(l License) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Fatalf("listing not aborted: %v after %d calls", err, calls)
+	}
+}
+
+func TestLongestCommonPrefix(t *testing.T) {
+	tests := []struct {
+		Packages []string
+		Prefix   string
+	}{
+		{[]string{"github.com/a/x", "github.com/b/y"}, "github.com"},
+		{[]string{"github.com/a/x", "github.com/a/y"}, "github.com/a"},
+		{[]string{"github.com/a/x", "github.com/a/x/y"}, "github.com/a/x"},
+		{[]string{"github.com/a/x/y", "github.com/a/x"}, "github.com/a/x"},
+		{[]string{"github.com/a/x"}, "github.com/a/x"},
+		{[]string{"github.com/a/x", "gopkg.in/b/y"}, ""},
+	}
+	for _, test := range tests {
+		licenses := []License{}
+		for _, pkg := range test.Packages {
+			licenses = append(licenses, License{Package: pkg})
+		}
+		prefix := longestCommonPrefix(licenses)
+		if prefix != test.Prefix {
+			t.Errorf("unexpected prefix for %v: %q != %q", test.Packages,
+				prefix, test.Prefix)
+		}
+	}
+}
+
+func TestGroupLicensesSingleComponent(t *testing.T) {
+	licenses, err := groupLicenses([]License{
+		{Package: "github.com/a/x", Path: "github.com/LICENSE"},
+		{Package: "github.com/b/y", Path: "github.com/LICENSE"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(licenses) != 1 || licenses[0].Package != "github.com" {
+		t.Fatalf("unexpected grouped licenses: %+v", licenses)
+	}
+	_, err = groupLicenses([]License{
+		{Package: "github.com/a/x", Path: "LICENSE"},
+		{Package: "gopkg.in/b/y", Path: "LICENSE"},
+	})
+	if err == nil {
+		t.Fatal("diverging packages were grouped")
+	}
+}
+
+func TestGroupLicensesByTemplate(t *testing.T) {
+	mit := &licensecheck.Template{Title: "MIT License", SPDX: "MIT"}
+	bsd := &licensecheck.Template{Title: "BSD 2-clause", SPDX: "BSD-2-Clause"}
+	exact := func(pkg, path string, templ *licensecheck.Template) License {
+		return License{
+			Package:     pkg,
+			Path:        path,
+			MatchResult: licensecheck.MatchResult{Template: templ, Score: 1},
+		}
+	}
+	licenses := []License{
+		exact("github.com/a/x", "github.com/a/x/LICENSE", mit),
+		exact("github.com/a/x/sub", "github.com/a/x/LICENSE", mit),
+		exact("github.com/b/vendor/y", "github.com/b/vendor/y/LICENSE", mit),
+		exact("github.com/c/z", "github.com/c/z/LICENSE", bsd),
+		exact("gopkg.in/d", "gopkg.in/d/LICENSE", mit),
+		{Package: "github.com/e/u", Path: "github.com/e/u/LICENSE",
+			MatchResult: licensecheck.MatchResult{Template: mit, Score: 0.95}},
+		{Package: "github.com/f/v", Path: "github.com/f/v/LICENSE",
+			MatchResult: licensecheck.MatchResult{Template: mit, Score: 0.95}},
+		{Package: "github.com/g/missing", Err: "not found"},
+	}
+	stringify := func(licenses []License) string {
+		names := []string{}
+		for _, l := range licenses {
+			names = append(names, l.Package)
+		}
+		return strings.Join(names, ",")
+	}
+	grouped, err := groupLicenses(licenses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := "github.com/a/x,github.com/b/vendor/y,github.com/c/z,gopkg.in/d," +
+		"github.com/e/u,github.com/f/v,github.com/g/missing"
+	if got := stringify(grouped); got != wanted {
+		t.Fatalf("unexpected path groups:\n%s\n!=\n%s", got, wanted)
+	}
+	grouped, err = groupLicensesByTemplate(licenses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted = "github.com,github.com/c/z,gopkg.in/d,github.com/e/u,github.com/f/v," +
+		"github.com/g/missing"
+	if got := stringify(grouped); got != wanted {
+		t.Fatalf("unexpected template groups:\n%s\n!=\n%s", got, wanted)
+	}
+}
+
+func TestLicenseEncoding(t *testing.T) {
+	// colors/gray license is colors/silver one, encoded in Latin-1 with a
+	// UTF-8 byte order mark.
+	licenses, err := listTestdataLicenses([]string{"colors/gray", "colors/silver"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(licenses) != 2 {
+		t.Fatalf("unexpected licenses: %+v", licenses)
+	}
+	gray, silver := licenses[0], licenses[1]
+	if silver.Template == nil || silver.Template.SPDX != "BSD-3-Clause" {
+		t.Fatalf("unexpected silver license: %s", matchName(silver.MatchResult))
+	}
+	if gray.Template != silver.Template || gray.Score != silver.Score {
+		t.Fatalf("encoding changed match: %s %f != %s %f",
+			matchName(gray.MatchResult), gray.Score,
+			matchName(silver.MatchResult), silver.Score)
+	}
+	wanted := "Copyright (c) 2016, José Müller"
+	for _, l := range licenses {
+		if len(l.Copyright) != 1 || l.Copyright[0] != wanted {
+			t.Fatalf("unexpected %s copyright: %q", l.Package, l.Copyright)
+		}
+	}
+}
+
+func TestShortDedication(t *testing.T) {
+	err := compareTestLicenses([]string{"colors/ivory"}, []testResult{
+		{Package: "colors/ivory", License: "The Unlicense", Score: 99},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Short texts are not matched by containment against other templates,
+	// nor are full licenses.
+	m, err := licensecheck.NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{
+		"Licensed under the Apache License, Version 2.0, see LICENSE for details.",
+		"This code is released into the public domain. Do whatever you want with it.",
+	} {
+		if r := m.Match([]byte(text)); r.Score >= 0.9 {
+			t.Errorf("%q matched %s at %f", text, matchName(r), r.Score)
+		}
+	}
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "src", "colors", "red",
+		"LICENSE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := m.Match(data); r.Template.SPDX != "MIT" {
+		t.Fatalf("MIT license matched %s", matchName(r))
+	}
+}
+
+func TestLicenseNamedFiles(t *testing.T) {
+	// colors/lime license file is named Apache-2.0.txt
+	err := compareTestLicenses([]string{"colors/lime"}, []testResult{
+		{Package: "colors/lime", License: "Apache License 2.0", Score: 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMaxWalk(t *testing.T) {
+	pkgs := []string{"shades/light/pale", "shades/pastel/pink"}
+	// Lookup stops at go.mod directories, even in GOPATH mode
+	err := compareTestLicenses(pkgs, []testResult{
+		{Package: "shades/light/pale", License: "MIT License", Score: 98, Header: 2},
+		{Package: "shades/pastel/pink", License: "", Score: 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, maxWalk := range []int{1, 2} {
+		licenses, err := listTestdataLicensesWith(pkgs[:1], listOptions{
+			MaxWalk: maxWalk,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := len(licenses) == 1 && licenses[0].Path == "shades/LICENSE"
+		if found != (maxWalk >= 2) {
+			t.Fatalf("unexpected licenses with max walk %d: %+v", maxWalk, licenses)
+		}
+	}
+}
+
+func TestFindLicensesWithoutRoot(t *testing.T) {
+	src, err := filepath.Abs(filepath.Join("testdata", "src"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths, _, err := findLicenses(&PkgInfo{
+		ImportPath: "colors/red",
+		Dir:        filepath.Join(src, "colors", "red"),
+	}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(paths, ",") != filepath.Join("colors", "red", "LICENSE") {
+		t.Fatalf("unexpected licenses: %v", paths)
+	}
+	// Parent directories are not inspected
+	paths, _, err = findLicenses(&PkgInfo{
+		ImportPath: "shades/light/pale",
+		Dir:        filepath.Join(src, "shades", "light", "pale"),
+	}, 0, 0)
+	if err != nil || len(paths) != 0 {
+		t.Fatalf("unexpected licenses: %v, %v", paths, err)
+	}
+	_, _, err = findLicenses(&PkgInfo{ImportPath: "synthetic"}, 0, 0)
+	if err == nil || !strings.Contains(err.Error(), "synthetic") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPointerStubs(t *testing.T) {
+	licenses, err := listTestdataLicenses([]string{"stubs/inner"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(licenses) != 1 || licenses[0].Path != "stubs/COPYING" ||
+		licenses[0].SPDX() != "ISC" {
+		t.Fatalf("stub was not skipped: %+v", licenses)
+	}
+	// Stubs are reported if there is nothing else
+	src, err := filepath.Abs(filepath.Join("testdata", "src"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths, _, err := findLicenses(&PkgInfo{
+		ImportPath: "stubs/inner",
+		Dir:        filepath.Join(src, "stubs", "inner"),
+	}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(paths, ",") != filepath.Join("stubs", "inner", "LICENSE") {
+		t.Fatalf("unexpected licenses: %v", paths)
+	}
+	// Notes referring to a well-known license URL are not stubs
+	licenses, err = listTestdataLicenses([]string{"stubs/apache"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(licenses) != 1 || licenses[0].Path != "stubs/apache/LICENSE" ||
+		licenses[0].SPDX() != "Apache-2.0" {
+		t.Fatalf("license URL note was skipped: %+v", licenses)
+	}
+}
+
+func TestMinBytes(t *testing.T) {
+	// shades/muted LICENSE is a 20 bytes stub, shades/LICENSE the MIT license
+	for _, test := range []struct {
+		MinBytes int
+		Path     string
+	}{
+		{0, "shades/muted/LICENSE"},
+		{20, "shades/muted/LICENSE"},
+		{21, "shades/LICENSE"},
+	} {
+		licenses, err := listTestdataLicensesWith([]string{"shades/muted"},
+			listOptions{MinBytes: test.MinBytes})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(licenses) != 1 || licenses[0].Path != test.Path {
+			t.Fatalf("unexpected licenses with %d min bytes: %+v", test.MinBytes,
+				licenses)
+		}
+	}
+}
+
+func TestCopyrightRegexp(t *testing.T) {
+	score := func(re *regexp.Regexp) float64 {
+		licenses, err := listTestdataLicensesWith([]string{"colors/salmon"},
+			listOptions{CopyrightRegexp: re})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(licenses) != 1 || licenses[0].Template == nil ||
+			licenses[0].Template.SPDX != "MIT" {
+			t.Fatalf("unexpected licenses: %+v", licenses)
+		}
+		return licenses[0].Score
+	}
+	plain := score(nil)
+	stripped := score(regexp.MustCompile(`(?m)^.* Project contributors.*$`))
+	if stripped <= plain || stripped < 0.95 {
+		t.Fatalf("custom copyright was not stripped: %f <= %f", stripped, plain)
+	}
+
+	t.Setenv("GO111MODULE", "off")
+	err := printLicenses([]string{"-no-cache", "-copyright-regex", "(", "colors/red"},
+		&bytes.Buffer{}, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "copyright-regex") {
+		t.Fatalf("invalid regexp was accepted: %v", err)
+	}
+}
+
+func TestFailOnUnknown(t *testing.T) {
+	gopath, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOPATH", gopath)
+	t.Setenv("GO111MODULE", "off")
+	run := func(args ...string) (string, error) {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		err := printLicenses(append([]string{"-no-cache"}, args...), stdout, stderr)
+		return stdout.String(), err
+	}
+
+	out, err := run("colors/cmd/...", "colors/red")
+	if err != nil {
+		t.Fatalf("%s\n%s", err, out)
+	}
+	out, err = run("-fail-on-unknown", "colors/cmd/...", "colors/red")
+	if err != nil {
+		t.Fatalf("known licenses failed: %s\n%s", err, out)
+	}
+
+	_, err = run("-fail-on-unknown", "colors/green", "colors/red", "colors/umber",
+		"colors/yellow")
+	unknown, ok := err.(*UnknownError)
+	if !ok {
+		t.Fatalf("unknown licenses did not fail: %v", err)
+	}
+	wanted := `3 packages have unknown licenses:
+  colors/green: no license file found
+  colors/umber: all rights reserved, no license granted
+  colors/yellow: low confidence Microsoft Reciprocal License [MS-RL] (25%)`
+	if unknown.Error() != wanted {
+		t.Fatalf("unexpected error:\n%s\n!=\n%s", unknown.Error(), wanted)
+	}
+
+	if _, err := run("-unknown-flag"); err != errUsage {
+		t.Fatalf("invalid flag was accepted: %v", err)
+	}
+}
+
+func TestQuiet(t *testing.T) {
+	gopath, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOPATH", gopath)
+	t.Setenv("GO111MODULE", "off")
+	run := func(args ...string) (string, error) {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		err := printLicenses(append([]string{"-no-cache", "-quiet"}, args...),
+			stdout, stderr)
+		return stdout.String(), err
+	}
+	// colors/red and colors/white are MIT licensed
+	for _, args := range [][]string{
+		{"colors/red", "colors/white"},
+		{"-a", "-summary", "colors/red", "colors/white"},
+	} {
+		out, err := run(args...)
+		if err != nil || out != "" {
+			t.Fatalf("unexpected output for %v: %q, %v", args, out, err)
+		}
+	}
+	out, err := run("-a", "colors/green", "colors/red")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "colors/green  ? (no license file found)\n" {
+		t.Fatalf("unexpected output: %q", out)
+	}
+	out, err = run("-a", "-deny", "MIT", "colors/red")
+	if _, ok := err.(*PolicyError); !ok {
+		t.Fatalf("policy violation was not reported: %v", err)
+	}
+	if !strings.HasPrefix(out, "colors/red  MIT License") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
+
+func TestTopMatches(t *testing.T) {
+	// colors/khaki mixes the MIT grant with the ISC disclaimer
+	licenses, err := listTestdataLicensesWith([]string{"colors/khaki"},
+		listOptions{Top: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(licenses) != 1 {
+		t.Fatalf("unexpected licenses: %+v", licenses)
+	}
+	l := licenses[0]
+	got := []string{}
+	for i, g := range l.Guesses {
+		got = append(got, g.Template.SPDX)
+		if i > 0 && g.Score > l.Guesses[i-1].Score {
+			t.Fatalf("guesses are not sorted: %+v", l.Guesses)
+		}
+	}
+	if strings.Join(got, ",") != "0BSD,ISC,MIT" {
+		t.Fatalf("unexpected guesses: %s", strings.Join(got, ","))
+	}
+	if l.Template != l.Guesses[0].Template || l.Score != l.Guesses[0].Score {
+		t.Fatalf("best guess differs from match: %+v", l.MatchResult)
+	}
+	buf := &bytes.Buffer{}
+	err = writeText(buf, licenses, textOptions{Confidence: 0.9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := "colors/khaki  ? (BSD Zero Clause License [0BSD], 80%)\n" +
+		"              guesses: BSD Zero Clause License [0BSD] (80%), " +
+		"ISC License [ISC] (78%), MIT License [MIT] (77%)\n"
+	if buf.String() != wanted {
+		t.Fatalf("unexpected output:\n%s\n!=\n%s", buf.String(), wanted)
+	}
 }
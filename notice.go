@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/licenses/licensecheck"
+)
+
+const noticeSeparator = "=========================================" +
+	"======================================="
+
+type sortedNoticeLicenses []License
+
+func (s sortedNoticeLicenses) Len() int {
+	return len(s)
+}
+
+func (s sortedNoticeLicenses) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s sortedNoticeLicenses) Less(i, j int) bool {
+	ti, tj := "", ""
+	if s[i].Template != nil {
+		ti = s[i].Template.Title
+	}
+	if s[j].Template != nil {
+		tj = s[j].Template.Title
+	}
+	if ti != tj {
+		// Unknown licenses go last
+		if ti == "" || tj == "" {
+			return tj == ""
+		}
+		return ti < tj
+	}
+	return s[i].Path < s[j].Path
+}
+
+// writeNotice writes an attribution document listing, for each license file,
+// the license name, the packages using it, its copyright lines and verbatim
+// content. Sections are sorted by license title then license path. Packages
+// without license file are listed at the end.
+func writeNotice(out io.Writer, licenses []License) error {
+	grouped, err := groupLicenses(licenses)
+	if err != nil {
+		return err
+	}
+	files := []License{}
+	missing := []string{}
+	for _, l := range grouped {
+		if l.Path == "" {
+			if l.Err == "" {
+				missing = append(missing, l.Package)
+			}
+			continue
+		}
+		files = append(files, l)
+	}
+	sort.Sort(sortedNoticeLicenses(files))
+	// Write errors are sticky, they are returned by Flush
+	w := bufio.NewWriter(out)
+	for _, l := range files {
+		name := "Unknown license"
+		if l.Template != nil {
+			name = matchName(l.MatchResult)
+		}
+		fmt.Fprintf(w, "%s\n%s\n\nPackages:\n  %s\n\n", noticeSeparator, name,
+			l.Package)
+		if len(l.Copyright) > 0 {
+			fmt.Fprintf(w, "Copyright:\n  %s\n\n", strings.Join(l.Copyright, "\n  "))
+		}
+		if filepath.Ext(l.AbsPath) == ".go" {
+			fmt.Fprintf(w, "License declared in source file %s.\n\n", l.Path)
+			continue
+		}
+		data, err := licensecheck.ReadLicenseFile(l.AbsPath)
+		if err != nil {
+			return err
+		}
+		text := strings.TrimRight(string(data), "\n")
+		fmt.Fprintf(w, "%s\n\n", text)
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(w, "%s\nPackages without license file:\n  %s\n", noticeSeparator,
+			strings.Join(missing, "\n  "))
+	}
+	return w.Flush()
+}
+
+// writeNoticeFile writes the attribution document of supplied licenses to
+// path.
+func writeNoticeFile(path string, licenses []License) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	err = writeNotice(f, licenses)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestNotice(t *testing.T) {
+	licenses, err := listTestdataLicenses([]string{"colors/cmd/...", "colors/green"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	err = writeNotice(buf, licenses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notice := buf.String()
+	re := regexp.MustCompile(`(?m)^=+\n(.*?):?\n(?:\nPackages:\n)?  (.*)\n`)
+	sections := []string{}
+	for _, m := range re.FindAllStringSubmatch(notice, -1) {
+		sections = append(sections, m[1]+": "+m[2])
+	}
+	wanted := []string{
+		"Academic Free License v3.0 [AFL-3.0]: colors/cmd",
+		"GNU Lesser General Public License v2.1 [LGPL-2.1-only]: couleurs/red",
+		"MIT License [MIT]: colors/red",
+		"Packages without license file: colors/green",
+	}
+	if strings.Join(sections, "\n") != strings.Join(wanted, "\n") {
+		t.Fatalf("unexpected sections:\n%s\n!=\n%s", strings.Join(sections, "\n"),
+			strings.Join(wanted, "\n"))
+	}
+	if !strings.Contains(notice, "Copyright:\n  Copyright (c) 2015 Patrick Mézard\n") {
+		t.Fatalf("copyright missing from notice:\n%s", notice)
+	}
+	if !strings.Contains(notice, "Permission is hereby granted, free of charge") {
+		t.Fatalf("license text missing from notice:\n%s", notice)
+	}
+
+	buf2 := &bytes.Buffer{}
+	err = writeNotice(buf2, licenses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf2.String() != notice {
+		t.Fatalf("notice is not deterministic")
+	}
+}
+
+// failingWriter fails every write with its error.
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
+func TestNoticeWriteError(t *testing.T) {
+	licenses, err := listTestdataLicenses([]string{"colors/red"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failure := errors.New("disk full")
+	err = writeNotice(failingWriter{err: failure}, licenses)
+	if err != failure {
+		t.Fatalf("write error was not returned: %v", err)
+	}
+}
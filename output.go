@@ -0,0 +1,822 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pmezard/licenses/licensecheck"
+)
+
+type textOptions struct {
+	// Scores below Confidence are reported as unknown licenses with the best
+	// guess attached.
+	Confidence float64
+	// Scores below MinScore are reported as unknown licenses, without guess.
+	MinScore float64
+	// Words lists words differences with the template for imperfect matches.
+	Words bool
+	// SPDX displays only the SPDX identifier of detected licenses.
+	SPDX bool
+	// Copyright displays copyright lines found in license files.
+	Copyright bool
+	// Diff displays the sentences differing between imperfect matches and
+	// their template.
+	Diff bool
+	// Sort is the key licenses are sorted by, see sortLicenses. Licenses are
+	// printed in input order if it is empty.
+	Sort string
+	// Color highlights the license column of unknown licenses in red and of
+	// exact matches in green, with ANSI escape sequences.
+	Color bool
+}
+
+const (
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorReset = "\x1b[0m"
+)
+
+// isTerminal returns true if w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// checkColor returns an error if flag is not a valid -color value.
+func checkColor(flag string) error {
+	if flag != "auto" && flag != "always" && flag != "never" {
+		return fmt.Errorf("color must be auto, always or never, got %q", flag)
+	}
+	return nil
+}
+
+// useColor returns true if text output should be colored, given the -color
+// flag value, whether output is a terminal and an environment lookup function
+// like os.LookupEnv. "always" and "never" win, "auto" colors terminal output
+// unless NO_COLOR is set to a non-empty value, see https://no-color.org.
+func useColor(flag string, isTTY bool, lookup func(string) (string, bool)) bool {
+	switch flag {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if v, ok := lookup("NO_COLOR"); ok && v != "" {
+		return false
+	}
+	return isTTY
+}
+
+// colorLicense returns the first line of license wrapped in the color of l
+// classification, see textOptions.Color, and its other lines unchanged.
+// Coloring the last column only keeps the tabwriter alignment intact.
+func colorLicense(license string, l License, confidence float64) string {
+	color := ""
+	if c := classify(l, confidence); l.Err != "" || c.Class == Unknown {
+		color = colorRed
+	} else if c.Class == Exact {
+		color = colorGreen
+	}
+	if color == "" {
+		return license
+	}
+	n := strings.Index(license, "\n")
+	if n < 0 {
+		n = len(license)
+	}
+	return color + license[:n] + colorReset + license[n:]
+}
+
+// sortKeys lists the keys accepted by sortLicenses.
+var sortKeys = []string{"package", "license", "score"}
+
+type licensesByPackage []License
+
+func (s licensesByPackage) Len() int {
+	return len(s)
+}
+
+func (s licensesByPackage) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s licensesByPackage) Less(i, j int) bool {
+	return s[i].Package < s[j].Package
+}
+
+type licensesByName struct {
+	licensesByPackage
+	names []string
+}
+
+func (s licensesByName) Swap(i, j int) {
+	s.licensesByPackage.Swap(i, j)
+	s.names[i], s.names[j] = s.names[j], s.names[i]
+}
+
+func (s licensesByName) Less(i, j int) bool {
+	a, b := s.names[i], s.names[j]
+	if (a == "") != (b == "") {
+		return b == ""
+	}
+	return a < b
+}
+
+type licensesByScore struct {
+	licensesByPackage
+}
+
+func (s licensesByScore) Less(i, j int) bool {
+	return s.licensesByPackage[i].Score < s.licensesByPackage[j].Score
+}
+
+// sortLicenses returns a copy of licenses stably sorted by key. "package"
+// sorts by import path, "license" by matched template names, unknown licenses
+// last, and "score" by increasing match score, so the least reliable matches
+// come first.
+func sortLicenses(licenses []License, key string) ([]License, error) {
+	sorted := append([]License{}, licenses...)
+	switch key {
+	case "package":
+		sort.Stable(licensesByPackage(sorted))
+	case "license":
+		names := []string{}
+		for _, l := range sorted {
+			name := ""
+			if l.Template != nil && l.Err == "" {
+				name = matchName(l.MatchResult)
+			}
+			names = append(names, name)
+		}
+		sort.Stable(licensesByName{sorted, names})
+	case "score":
+		sort.Stable(licensesByScore{sorted})
+	default:
+		return nil, fmt.Errorf("sort key must be one of %s, got %q",
+			strings.Join(sortKeys, ", "), key)
+	}
+	return sorted, nil
+}
+
+// exactScore is the score above which a match is considered an exact copy of
+// its template.
+const exactScore = .99
+
+// modifiedMinExtraRatio is the ratio of extra words, header ones excepted,
+// to license words above which a confident match is considered a modified
+// copy of its template, like a license with an additional clause.
+const modifiedMinExtraRatio = 0.06
+
+// Class sorts matches by reliability.
+type Class int
+
+const (
+	// Unknown licenses are missing, failed to load or score below the
+	// confidence threshold.
+	Unknown Class = iota
+	// Modified licenses score above the confidence threshold but add
+	// sentences to their template, which may change its terms.
+	Modified
+	// Confident licenses score above the confidence threshold.
+	Confident
+	// Exact licenses are copies of their template, up to header words.
+	Exact
+	// NoLicense files reserve all rights without granting any, see
+	// licensecheck.NoLicenseTitle.
+	NoLicense
+)
+
+func (c Class) String() string {
+	switch c {
+	case Modified:
+		return "modified"
+	case Confident:
+		return "confident"
+	case Exact:
+		return "exact"
+	case NoLicense:
+		return "no_license"
+	}
+	return "unknown"
+}
+
+// Classification describes how reliable a license match is.
+type Classification struct {
+	// Score is the raw match score, between 0 and 1.
+	Score float64
+	// Percent is the score as a truncated percentage.
+	Percent int
+	Class   Class
+}
+
+// scorePercent returns a match score as a truncated percentage.
+func scorePercent(score float64) int {
+	return int(100 * score)
+}
+
+// classify returns the classification of supplied license given the
+// confidence threshold. Renderers and policies must rely on it, so they agree
+// on which licenses are known.
+func classify(l License, confidence float64) Classification {
+	c := Classification{
+		Score:   l.Score,
+		Percent: scorePercent(l.Score),
+	}
+	switch {
+	case l.Template == nil || l.Err != "":
+		c.Class = Unknown
+	case l.Template.Title == licensecheck.NoLicenseTitle:
+		c.Class = NoLicense
+	case l.Score < confidence:
+		c.Class = Unknown
+	case l.Score > exactScore:
+		c.Class = Exact
+	case len(l.Parts) == 0 && l.LicenseWords > 0 &&
+		float64(len(l.ExtraWords))/float64(l.LicenseWords) >= modifiedMinExtraRatio:
+		c.Class = Modified
+	default:
+		c.Class = Confident
+	}
+	return c
+}
+
+// templateName returns the template title followed by its SPDX identifier,
+// if any.
+func templateName(t *licensecheck.Template) string {
+	if t.SPDX == "" {
+		return t.Title
+	}
+	return fmt.Sprintf("%s [%s]", t.Title, t.SPDX)
+}
+
+// matchName returns the template name of a match, or the names of each part
+// joined with "OR" for multi-licensed files.
+func matchName(m licensecheck.MatchResult) string {
+	if m.OrLater {
+		return fmt.Sprintf("%s [%s]", m.Template.Title, m.SPDX())
+	}
+	if len(m.Parts) == 0 {
+		return templateName(m.Template)
+	}
+	names := []string{}
+	for _, p := range m.Parts {
+		names = append(names, matchName(p))
+	}
+	return strings.Join(names, " OR ")
+}
+
+// formatOverlap returns the number of template words found in the license
+// file of l, and the number of license words not in the template.
+func formatOverlap(l License) string {
+	return fmt.Sprintf("matched %d/%d template words (%d extra)", l.Common,
+		l.TemplateWords, l.LicenseWords-l.Common)
+}
+
+// formatNameScore returns the name score of the license file of l, and why
+// its name looks like a license file name.
+func formatNameScore(l License) string {
+	kind, _ := licensecheck.MatchLicenseName(filepath.Base(l.AbsPath))
+	return fmt.Sprintf("file name score: %.1f (%s)", l.NameScore, kind)
+}
+
+// matchSource returns how the license of l was identified: "override" for
+// override files, "url" for well-known license URLs, "text" for license texts
+// matching a template, or an empty string if it was not.
+func matchSource(l License) string {
+	switch {
+	case l.Template == nil:
+		return ""
+	case l.Override:
+		return "override"
+	case l.URL != "":
+		return "url"
+	}
+	return "text"
+}
+
+// formatSize returns the size of the license file of l.
+func formatSize(l License) string {
+	lines := "lines"
+	if l.Lines == 1 {
+		lines = "line"
+	}
+	return fmt.Sprintf("size: %d bytes, %d %s", l.Size, l.Lines, lines)
+}
+
+// formatError returns the error of a license on a single line, prefixed with
+// its category.
+func formatError(l License) string {
+	err := strings.Replace(l.Err, "\n", " ", -1)
+	if l.ErrCategory == ErrNone {
+		return err
+	}
+	return l.ErrCategory.String() + ": " + err
+}
+
+// confidentSPDX returns the SPDX expression of the license, or an empty
+// string if the license is unknown, scores below confidence or its templates
+// have no identifier.
+func confidentSPDX(l License, confidence float64) string {
+	if l.Err != "" || classify(l, confidence).Class == Unknown {
+		return ""
+	}
+	return l.SPDX()
+}
+
+// formatSPDX returns the SPDX expression of the license, "?" if the license
+// is unknown or its templates have no identifier.
+func formatSPDX(l License, confidence float64) string {
+	if l.Err != "" {
+		return formatError(l)
+	}
+	spdx := confidentSPDX(l, confidence)
+	if spdx == "" {
+		return "?"
+	}
+	return spdx
+}
+
+// formatGuesses returns the names and scores of supplied matches.
+func formatGuesses(guesses []licensecheck.MatchResult) string {
+	names := []string{}
+	for _, g := range guesses {
+		names = append(names, fmt.Sprintf("%s (%d%%)", matchName(g),
+			scorePercent(g.Score)))
+	}
+	return strings.Join(names, ", ")
+}
+
+// writeText writes licenses as tab-aligned text, one package per line.
+func writeText(out io.Writer, licenses []License, opts textOptions) error {
+	if opts.Sort != "" {
+		var err error
+		licenses, err = sortLicenses(licenses, opts.Sort)
+		if err != nil {
+			return err
+		}
+	}
+	w := tabwriter.NewWriter(out, 1, 4, 2, ' ', 0)
+	for _, l := range licenses {
+		_, err := w.Write([]byte(formatTextLicense(l, opts)))
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// streamColumnWidth is the minimum width of the package column of streamed
+// text output. Rows are written before the longest package path is known,
+// longer paths shift the license column of their row.
+const streamColumnWidth = 48
+
+// textStream writes licenses as tab-aligned text as soon as they are
+// resolved, see writeText.
+type textStream struct {
+	w    *tabwriter.Writer
+	opts textOptions
+}
+
+// newTextStream returns a textStream writing to out. Licenses are not sorted.
+func newTextStream(out io.Writer, opts textOptions) *textStream {
+	return &textStream{
+		w:    tabwriter.NewWriter(out, streamColumnWidth, 4, 2, ' ', 0),
+		opts: opts,
+	}
+}
+
+// Write writes the text line of l and flushes it.
+func (s *textStream) Write(l License) error {
+	_, err := s.w.Write([]byte(formatTextLicense(l, s.opts)))
+	if err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+// formatTextLicense returns the tab-separated text line of l, followed by its
+// detail lines, see writeText.
+func formatTextLicense(l License, opts textOptions) string {
+	license := "?"
+	if opts.SPDX {
+		license = formatSPDX(l, opts.Confidence)
+	} else if l.Template != nil && l.Score >= opts.MinScore {
+		name := matchName(l.MatchResult)
+		c := classify(l, opts.Confidence)
+		if c.Class == Exact || c.Class == NoLicense {
+			license = name
+		} else if c.Class == Confident || c.Class == Modified {
+			if c.Class == Modified {
+				name = "modified " + name
+			}
+			license = fmt.Sprintf("%s (%2d%%)", name, c.Percent)
+			if l.URL != "" {
+				license += " (from URL)"
+			}
+			if opts.Words && l.URL != "" {
+				license += "\n\turl: " + l.URL
+			}
+			if opts.Words && len(l.ExtraWords) > 0 {
+				license += "\n\t+words: " + strings.Join(l.ExtraWords, ", ")
+			}
+			if opts.Words && len(l.MissingWords) > 0 {
+				license += "\n\t-words: " + strings.Join(l.MissingWords, ", ")
+			}
+			if opts.Words && len(l.HeaderWords) > 0 {
+				license += "\n\t~words: " + strings.Join(l.HeaderWords, ", ")
+			}
+		} else {
+			license = fmt.Sprintf("? (%s, %2d%%)", name, c.Percent)
+			if len(l.Guesses) > 0 {
+				license += "\n\tguesses: " + formatGuesses(l.Guesses)
+			}
+		}
+		if opts.Words && c.Class != Exact && c.Class != NoLicense {
+			if l.TemplateWords > 0 {
+				license += "\n\t" + formatOverlap(l)
+			}
+			license += "\n\t" + formatSize(l)
+		}
+		if opts.Diff && c.Class != Exact && c.Class != NoLicense {
+			for _, line := range formatDiff(l) {
+				license += "\n\t" + line
+			}
+		}
+	} else if l.Err != "" {
+		license = formatError(l)
+	} else if l.Missing() {
+		license = "? (no license file found)"
+	} else {
+		license = "? (license file present but unrecognized)"
+		if opts.Words {
+			license += "\n\t" + formatSize(l)
+		}
+	}
+	// Markers follow the license name, before its detail lines
+	markers := ""
+	if l.Override {
+		markers += " (override)"
+	}
+	if l.Replace != "" {
+		markers += " (replaced by " + l.Replace + ")"
+	}
+	if i := strings.Index(license, "\n"); i >= 0 {
+		license = license[:i] + markers + license[i:]
+	} else {
+		license += markers
+	}
+	if opts.Words && l.NameScore > 0 && l.NameScore < 1 {
+		license += "\n\t" + formatNameScore(l)
+	}
+	if opts.Copyright {
+		for _, c := range l.Copyright {
+			license += "\n\t" + c
+		}
+	}
+	if opts.Color {
+		license = colorLicense(license, l, opts.Confidence)
+	}
+	return l.Package + "\t" + license + "\n"
+}
+
+type licenseCount struct {
+	Name  string
+	Count int
+}
+
+type sortedLicenseCounts []licenseCount
+
+func (s sortedLicenseCounts) Len() int {
+	return len(s)
+}
+
+func (s sortedLicenseCounts) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s sortedLicenseCounts) Less(i, j int) bool {
+	if s[i].Count != s[j].Count {
+		return s[i].Count > s[j].Count
+	}
+	return s[i].Name < s[j].Name
+}
+
+// summarizeLicenses returns the number of licenses of each type, referred to
+// by SPDX expression or template title, by decreasing count. Licenses without
+// template or scoring below confidence are counted as "unknown", last.
+func summarizeLicenses(licenses []License, confidence float64) []licenseCount {
+	counts := map[string]int{}
+	unknown := 0
+	for _, l := range licenses {
+		if classify(l, confidence).Class == Unknown {
+			unknown++
+			continue
+		}
+		name := l.SPDX()
+		if name == "" {
+			name = matchName(l.MatchResult)
+		}
+		counts[name]++
+	}
+	summary := []licenseCount{}
+	for name, count := range counts {
+		summary = append(summary, licenseCount{name, count})
+	}
+	sort.Sort(sortedLicenseCounts(summary))
+	if unknown > 0 {
+		summary = append(summary, licenseCount{"unknown", unknown})
+	}
+	return summary
+}
+
+// writeSummary writes a line counting licenses by type, see
+// summarizeLicenses.
+func writeSummary(w io.Writer, licenses []License, confidence float64) error {
+	parts := []string{}
+	for _, c := range summarizeLicenses(licenses, confidence) {
+		parts = append(parts, fmt.Sprintf("%s: %d", c.Name, c.Count))
+	}
+	_, err := fmt.Fprintf(w, "Summary: %s\n", strings.Join(parts, ", "))
+	return err
+}
+
+type sortedTemplates []*licensecheck.Template
+
+func (s sortedTemplates) Len() int {
+	return len(s)
+}
+
+func (s sortedTemplates) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s sortedTemplates) Less(i, j int) bool {
+	return s[i].Title < s[j].Title
+}
+
+// writeTemplates writes the title, nickname and SPDX identifier of templates,
+// sorted by title, one per line, or as a JSON array if asJSON is set. Missing
+// nicknames and identifiers are displayed as "-" in text output.
+func writeTemplates(out io.Writer, templates []*licensecheck.Template,
+	asJSON bool) error {
+
+	templates = append([]*licensecheck.Template{}, templates...)
+	sort.Sort(sortedTemplates(templates))
+	if asJSON {
+		entries := []jsonTemplate{}
+		for _, t := range templates {
+			entries = append(entries, makeJSONTemplate(t))
+		}
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(append(data, '\n'))
+		return err
+	}
+	orDash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+	w := tabwriter.NewWriter(out, 1, 4, 2, ' ', 0)
+	for _, t := range templates {
+		_, err := fmt.Fprintf(w, "%s\t%s\t%s\n", t.Title, orDash(t.Nickname),
+			orDash(t.SPDX))
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+type jsonTemplate struct {
+	Title       string   `json:"title"`
+	Nickname    string   `json:"nickname"`
+	SPDX        string   `json:"spdx"`
+	Category    string   `json:"category"`
+	OSIApproved bool     `json:"osiApproved"`
+	Permissions []string `json:"permissions"`
+	Conditions  []string `json:"conditions"`
+	Limitations []string `json:"limitations"`
+}
+
+type jsonGuess struct {
+	Template jsonTemplate `json:"template"`
+	Score    float64      `json:"score"`
+}
+
+type jsonCopyright struct {
+	Text   string `json:"text"`
+	Years  string `json:"years"`
+	Holder string `json:"holder"`
+}
+
+type jsonLicense struct {
+	Package       string          `json:"package"`
+	LicensePath   string          `json:"licensePath"`
+	AbsPath       string          `json:"absPath"`
+	Template      *jsonTemplate   `json:"template"`
+	Templates     []jsonTemplate  `json:"templates"`
+	SPDX          string          `json:"spdxExpression"`
+	Score         float64         `json:"score"`
+	Percent       int             `json:"percent"`
+	Class         string          `json:"class"`
+	ExtraWords    []string        `json:"extraWords"`
+	MissingWords  []string        `json:"missingWords"`
+	HeaderWords   []string        `json:"headerWords"`
+	Copyright     []jsonCopyright `json:"copyright"`
+	Error         string          `json:"error"`
+	ErrorCategory string          `json:"errorCategory"`
+	Override      bool            `json:"override"`
+	Replace       string          `json:"replace"`
+	Version       string          `json:"version"`
+	Guesses       []jsonGuess     `json:"guesses"`
+	Size          int             `json:"size"`
+	Lines         int             `json:"lines"`
+	CommonWords   int             `json:"commonWords"`
+	LicenseWords  int             `json:"licenseWords"`
+	TemplateWords int             `json:"templateWords"`
+	NameScore     float64         `json:"nameScore"`
+	Source        string          `json:"source"`
+	URL           string          `json:"url"`
+}
+
+func makeJSONTemplate(t *licensecheck.Template) jsonTemplate {
+	orEmpty := func(s []string) []string {
+		if s == nil {
+			return []string{}
+		}
+		return s
+	}
+	return jsonTemplate{
+		Title:       t.Title,
+		Nickname:    t.Nickname,
+		SPDX:        t.SPDX,
+		Category:    t.Category,
+		OSIApproved: t.OSIApproved,
+		Permissions: orEmpty(t.Permissions),
+		Conditions:  orEmpty(t.Conditions),
+		Limitations: orEmpty(t.Limitations),
+	}
+}
+
+type jsonOptions struct {
+	// Sort is the key licenses are sorted by, see sortLicenses. It defaults
+	// to "package".
+	Sort string
+	// Confidence is the threshold used to classify licenses, see classify.
+	Confidence float64
+}
+
+// writeJSON writes licenses as a JSON array sorted by opts.Sort. Packages
+// without detected license have a null template. Multi-licensed files list
+// every matched template in "templates", the first one being "template".
+// "class" is the classification of the match, see classify. "spdxExpression"
+// follows SPDX conventions, being NOASSERTION for unknown or low-confidence
+// licenses and NONE for packages without license file, see spdxLicense.
+// "source" tells whether the license was identified from its text, a
+// well-known license URL or an override, see matchSource.
+func writeJSON(out io.Writer, licenses []License, opts jsonOptions) error {
+	sortKey := opts.Sort
+	if sortKey == "" {
+		sortKey = "package"
+	}
+	licenses, err := sortLicenses(licenses, sortKey)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(makeJSONLicenses(licenses, opts.Confidence), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(append(data, '\n'))
+	return err
+}
+
+// makeJSONLicenses returns the JSON entries of licenses, in input order, see
+// writeJSON.
+func makeJSONLicenses(licenses []License, confidence float64) []jsonLicense {
+	entries := []jsonLicense{}
+	for _, l := range licenses {
+		c := classify(l, confidence)
+		e := jsonLicense{
+			Package:       l.Package,
+			LicensePath:   l.Path,
+			AbsPath:       l.AbsPath,
+			Score:         c.Score,
+			Percent:       c.Percent,
+			Class:         c.Class.String(),
+			SPDX:          spdxLicense(l, confidence),
+			ExtraWords:    l.ExtraWords,
+			MissingWords:  l.MissingWords,
+			HeaderWords:   l.HeaderWords,
+			Error:         l.Err,
+			ErrorCategory: l.ErrCategory.String(),
+			Override:      l.Override,
+			Replace:       l.Replace,
+			Version:       l.Version,
+			Size:          l.Size,
+			Lines:         l.Lines,
+			CommonWords:   l.Common,
+			LicenseWords:  l.LicenseWords,
+			TemplateWords: l.TemplateWords,
+			NameScore:     l.NameScore,
+			Source:        matchSource(l),
+			URL:           l.URL,
+		}
+		e.Templates = []jsonTemplate{}
+		if l.Template != nil {
+			t := makeJSONTemplate(l.Template)
+			e.Template = &t
+			if len(l.Parts) == 0 {
+				e.Templates = append(e.Templates, t)
+			}
+		}
+		for _, p := range l.Parts {
+			e.Templates = append(e.Templates, makeJSONTemplate(p.Template))
+		}
+		e.Guesses = []jsonGuess{}
+		for _, g := range l.Guesses {
+			e.Guesses = append(e.Guesses, jsonGuess{
+				Template: makeJSONTemplate(g.Template),
+				Score:    g.Score,
+			})
+		}
+		e.Copyright = []jsonCopyright{}
+		for _, c := range l.Copyright {
+			years, holder := licensecheck.ParseCopyright(c)
+			e.Copyright = append(e.Copyright, jsonCopyright{
+				Text:   c,
+				Years:  years,
+				Holder: holder,
+			})
+		}
+		if e.ExtraWords == nil {
+			e.ExtraWords = []string{}
+		}
+		if e.MissingWords == nil {
+			e.MissingWords = []string{}
+		}
+		if e.HeaderWords == nil {
+			e.HeaderWords = []string{}
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// atomicFile is a temporary file renamed to its target path when committed,
+// so readers never see partial content.
+type atomicFile struct {
+	*os.File
+	path string
+}
+
+// createAtomic creates a temporary file next to path, to be committed or
+// aborted. The committed file is readable by everyone, like os.Create ones.
+func createAtomic(path string) (*atomicFile, error) {
+	f, err := ioutil.TempFile(filepath.Dir(path), ".tmp."+filepath.Base(path)+"-")
+	if err != nil {
+		return nil, err
+	}
+	// Temporary files are only readable by their owner
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &atomicFile{File: f, path: path}, nil
+}
+
+// Commit closes the temporary file and renames it to the target path.
+func (f *atomicFile) Commit() error {
+	err := f.File.Close()
+	if err == nil {
+		err = os.Rename(f.Name(), f.path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// Abort closes and removes the temporary file. It does nothing once the file
+// is committed.
+func (f *atomicFile) Abort() {
+	if f.File.Close() == nil {
+		os.Remove(f.Name())
+	}
+}
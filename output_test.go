@@ -0,0 +1,692 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pmezard/licenses/licensecheck"
+)
+
+func TestJSONOutput(t *testing.T) {
+	licenses, err := listTestdataLicenses([]string{"colors/purple"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Reverse the input to check the output is sorted
+	for i, j := 0, len(licenses)-1; i < j; i, j = i+1, j-1 {
+		licenses[i], licenses[j] = licenses[j], licenses[i]
+	}
+	buf := &bytes.Buffer{}
+	err = writeJSON(buf, licenses, jsonOptions{Confidence: 0.9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []map[string]interface{}{}
+	err = json.Unmarshal(buf.Bytes(), &entries)
+	if err != nil {
+		t.Fatalf("could not decode output: %s\n%s", err, buf.String())
+	}
+	wanted := []struct {
+		Package string
+		Title   string
+		Error   bool
+		Class   string
+	}{
+		{"colors/broken", "GNU General Public License v3.0", false, "exact"},
+		{"colors/missing", "", true, "unknown"},
+		{"colors/purple", "", false, "unknown"},
+		{"colors/red", "MIT License", false, "confident"},
+	}
+	if len(entries) != len(wanted) {
+		t.Fatalf("unexpected entries count: %d != %d", len(entries), len(wanted))
+	}
+	for i, w := range wanted {
+		e := entries[i]
+		if e["package"] != w.Package {
+			t.Fatalf("unexpected package at %d: %v != %s", i, e["package"], w.Package)
+		}
+		if w.Title == "" {
+			if e["template"] != nil {
+				t.Fatalf("template should be null for %s: %v", w.Package, e["template"])
+			}
+		} else {
+			templ, ok := e["template"].(map[string]interface{})
+			if !ok || templ["title"] != w.Title {
+				t.Fatalf("unexpected template for %s: %v", w.Package, e["template"])
+			}
+		}
+		if (e["error"] != "") != w.Error {
+			t.Fatalf("unexpected error for %s: %v", w.Package, e["error"])
+		}
+		if e["class"] != w.Class {
+			t.Fatalf("unexpected class for %s: %v", w.Package, e["class"])
+		}
+	}
+	copyrights := entries[3]["copyright"].([]interface{})
+	if len(copyrights) != 1 {
+		t.Fatalf("unexpected copyrights: %v", copyrights)
+	}
+	c := copyrights[0].(map[string]interface{})
+	if c["years"] != "2015" || c["holder"] != "Patrick Mézard" {
+		t.Fatalf("unexpected copyright: %v", c)
+	}
+	gopath, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	absPath, _ := entries[3]["absPath"].(string)
+	if absPath != filepath.Join(gopath, "src", "colors", "red", "LICENSE") {
+		t.Fatalf("unexpected absolute path: %v", entries[3]["absPath"])
+	}
+	if fi, err := os.Stat(absPath); err != nil || fi.Size() != 1059 {
+		t.Fatalf("absolute path does not point at the license file: %v", err)
+	}
+	if entries[3]["nameScore"] != 1. {
+		t.Fatalf("unexpected name score: %v", entries[3]["nameScore"])
+	}
+	if entries[3]["size"] != 1059. || entries[3]["lines"] != 19. {
+		t.Fatalf("unexpected size: %v, %v", entries[3]["size"], entries[3]["lines"])
+	}
+	if entries[3]["commonWords"] != 91. || entries[3]["licenseWords"] != 91. ||
+		entries[3]["templateWords"] != 93. {
+		t.Fatalf("unexpected word counts: %v, %v, %v", entries[3]["commonWords"],
+			entries[3]["licenseWords"], entries[3]["templateWords"])
+	}
+}
+
+func TestClassify(t *testing.T) {
+	m := licensecheck.MatchResult{
+		Template: &licensecheck.Template{Title: "MIT License"},
+	}
+	tests := []struct {
+		Score      float64
+		Confidence float64
+		Err        string
+		Class      Class
+		Percent    int
+	}{
+		{1, 0.9, "", Exact, 100},
+		{0.995, 0.9, "", Exact, 99},
+		{0.99, 0.9, "", Confident, 99},
+		{0.9, 0.9, "", Confident, 90},
+		{0.8999, 0.9, "", Unknown, 89},
+		{0, 0.9, "", Unknown, 0},
+		{1, 0.9, "cannot load package", Unknown, 100},
+		{1, 1, "", Exact, 100},
+		{0.995, 1, "", Unknown, 99},
+	}
+	for _, test := range tests {
+		m.Score = test.Score
+		c := classify(License{MatchResult: m, Err: test.Err}, test.Confidence)
+		if c.Class != test.Class || c.Percent != test.Percent || c.Score != test.Score {
+			t.Errorf("unexpected classification for %v: %+v", test.Score, c)
+		}
+	}
+	if c := classify(License{}, 0); c.Class != Unknown {
+		t.Errorf("license without template is %s", c.Class)
+	}
+	m.Score = 0.95
+	m.ExtraWords = strings.Fields("not be used to operate weapons")
+	m.LicenseWords = 99
+	if c := classify(License{MatchResult: m}, 0.9); c.Class != Modified {
+		t.Errorf("license with extra words is %s", c.Class)
+	}
+	// The same words are a small part of a long license
+	m.LicenseWords = 1000
+	if c := classify(License{MatchResult: m}, 0.9); c.Class != Confident {
+		t.Errorf("long license with extra words is %s", c.Class)
+	}
+	m.LicenseWords = 99
+	m.Score = 0.85
+	if c := classify(License{MatchResult: m}, 0.9); c.Class != Unknown {
+		t.Errorf("license below confidence with extra words is %s", c.Class)
+	}
+}
+
+func TestTextOutputColor(t *testing.T) {
+	licenses, err := listTestdataLicenses([]string{"colors/purple", "colors/blue"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	opts := textOptions{Confidence: 0.9, Words: true, Color: true}
+	if err := writeText(buf, licenses, opts); err != nil {
+		t.Fatal(err)
+	}
+	// Only the first line of the license column is colored
+	wanted := []string{
+		"colors/blue     \x1b[32mApache License 2.0 [Apache-2.0]\x1b[0m",
+		"colors/broken   \x1b[32mGNU General Public License v3.0 [GPL-3.0-only]\x1b[0m",
+		"colors/missing  \x1b[31mmissing package: ",
+		"colors/purple   \x1b[31m? (no license file found)\x1b[0m",
+		"colors/red      MIT License [MIT] (98%)",
+		"                ~words: mit, license",
+	}
+	lines := strings.Split(buf.String(), "\n")
+	for i, w := range wanted {
+		if i >= len(lines) || !strings.HasPrefix(lines[i], w) {
+			t.Fatalf("unexpected output at line %d:\n%q\n!=\n%q", i, buf.String(), w)
+		}
+	}
+	buf.Reset()
+	opts.Color = false
+	if err := writeText(buf, licenses, opts); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Fatalf("uncolored output has escape sequences: %q", buf.String())
+	}
+	if isTerminal(buf) {
+		t.Fatal("buffer is a terminal")
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	unset := func(string) (string, bool) { return "", false }
+	empty := func(string) (string, bool) { return "", true }
+	set := func(name string) (string, bool) { return "1", name == "NO_COLOR" }
+	tests := []struct {
+		Flag   string
+		TTY    bool
+		Lookup func(string) (string, bool)
+		Wanted bool
+	}{
+		{"auto", true, unset, true},
+		{"auto", true, empty, true},
+		{"auto", true, set, false},
+		{"auto", false, unset, false},
+		{"auto", false, empty, false},
+		{"auto", false, set, false},
+		{"always", true, unset, true},
+		{"always", true, set, true},
+		{"always", false, unset, true},
+		{"always", false, set, true},
+		{"never", true, unset, false},
+		{"never", true, set, false},
+		{"never", false, unset, false},
+		{"never", false, set, false},
+	}
+	for i, test := range tests {
+		if got := useColor(test.Flag, test.TTY, test.Lookup); got != test.Wanted {
+			t.Errorf("%d: useColor(%q, %v) = %v, wanted %v", i, test.Flag, test.TTY,
+				got, test.Wanted)
+		}
+	}
+	for _, flag := range []string{"auto", "always", "never"} {
+		if err := checkColor(flag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := checkColor("yes"); err == nil {
+		t.Fatal("invalid color flag was accepted")
+	}
+}
+
+func TestNoLicenseFile(t *testing.T) {
+	licenses, err := listTestdataLicenses([]string{"colors/green", "colors/umber"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, l := range licenses {
+		got = append(got, l.Package+" "+classify(l, 0.9).Class.String())
+	}
+	// colors/green has no license file at all
+	wanted := "colors/green unknown,colors/umber no_license"
+	if strings.Join(got, ",") != wanted {
+		t.Fatalf("unexpected classes: %s != %s", strings.Join(got, ","), wanted)
+	}
+	buf := &bytes.Buffer{}
+	err = writeText(buf, licenses, textOptions{Confidence: 0.9, Words: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantedText := `colors/green  ? (no license file found)
+colors/umber  No License
+`
+	if buf.String() != wantedText {
+		t.Fatalf("unexpected output:\n%s\n!=\n%s", buf.String(), wantedText)
+	}
+}
+
+func TestModifiedLicense(t *testing.T) {
+	licenses, err := listTestdataLicenses([]string{"colors/crimson"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(licenses) != 1 {
+		t.Fatalf("unexpected licenses: %+v", licenses)
+	}
+	if c := classify(licenses[0], 0.9); c.Class != Modified {
+		t.Fatalf("MIT license with extra clause is %s", c.Class)
+	}
+	buf := &bytes.Buffer{}
+	err = writeText(buf, licenses, textOptions{Confidence: 0.9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := "colors/crimson  modified MIT License [MIT] (94%)\n"
+	if got := buf.String(); got != wanted {
+		t.Fatalf("unexpected output: %q != %q", got, wanted)
+	}
+}
+
+func TestTextOutput(t *testing.T) {
+	licenses, err := listTestdataLicenses([]string{"colors/purple"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	licenses = licenses[len(licenses)-2:]
+	buf := &bytes.Buffer{}
+	err = writeText(buf, licenses, textOptions{Confidence: 0.9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := `colors/purple  ? (no license file found)
+colors/red     MIT License [MIT] (98%)
+`
+	if buf.String() != wanted {
+		t.Fatalf("unexpected output:\n%s\n!=\n%s", buf.String(), wanted)
+	}
+
+	buf.Reset()
+	err = writeText(buf, licenses, textOptions{Confidence: 0.9, SPDX: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted = `colors/purple  ?
+colors/red     MIT
+`
+	if buf.String() != wanted {
+		t.Fatalf("unexpected output:\n%s\n!=\n%s", buf.String(), wanted)
+	}
+}
+
+func TestTextOutputConfidence(t *testing.T) {
+	licenses, err := listTestdataLicenses([]string{"colors/red"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	err = writeText(buf, licenses, textOptions{Confidence: 0.99})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := "colors/red  ? (MIT License [MIT], 98%)\n"
+	if buf.String() != wanted {
+		t.Fatalf("unexpected output:\n%s\n!=\n%s", buf.String(), wanted)
+	}
+}
+
+func TestTextOutputMinScore(t *testing.T) {
+	licenses, err := listTestdataLicenses([]string{"colors/yellow"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, minScore := range []float64{0, 0.5} {
+		buf := &bytes.Buffer{}
+		err = writeText(buf, licenses, textOptions{
+			Confidence: 0.9,
+			MinScore:   minScore,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		wanted := "colors/yellow  ? (Microsoft Reciprocal License [MS-RL], 25%)\n"
+		if minScore > 0 {
+			wanted = "colors/yellow  ? (license file present but unrecognized)\n"
+		}
+		if buf.String() != wanted {
+			t.Fatalf("unexpected output:\n%s\n!=\n%s", buf.String(), wanted)
+		}
+	}
+}
+
+func TestTextOutputCopyright(t *testing.T) {
+	licenses, err := listTestdataLicenses([]string{"colors/red"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	err = writeText(buf, licenses, textOptions{Confidence: 0.9, Copyright: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := `colors/red  MIT License [MIT] (98%)
+            Copyright (c) 2015 Patrick Mézard
+`
+	if buf.String() != wanted {
+		t.Fatalf("unexpected output:\n%s\n!=\n%s", buf.String(), wanted)
+	}
+}
+
+func TestTextOutputSize(t *testing.T) {
+	licenses, err := listTestdataLicenses([]string{"colors/red", "colors/azure"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	err = writeText(buf, licenses, textOptions{Confidence: 0.9, Words: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := `colors/azure  MIT License [MIT] (98%)
+              ~words: mit, license
+              matched 91/93 template words (0 extra)
+              size: 1049 bytes, 1 line
+colors/red    MIT License [MIT] (98%)
+              ~words: mit, license
+              matched 91/93 template words (0 extra)
+              size: 1059 bytes, 19 lines
+`
+	if buf.String() != wanted {
+		t.Fatalf("unexpected output:\n%s\n!=\n%s", buf.String(), wanted)
+	}
+}
+
+func TestTextOutputMarkers(t *testing.T) {
+	// Markers follow the license name, not its size
+	licenses := []License{{
+		Package:  "colors/yellow",
+		Path:     "colors/yellow/COPYRIGHT",
+		Override: true,
+		Replace:  "colors/amber",
+		Size:     12,
+		Lines:    1,
+	}}
+	buf := &bytes.Buffer{}
+	err := writeText(buf, licenses, textOptions{Confidence: 0.9, Words: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := `colors/yellow  ? (license file present but unrecognized) (override) ` +
+		`(replaced by colors/amber)
+               size: 12 bytes, 1 line
+`
+	if buf.String() != wanted {
+		t.Fatalf("unexpected output:\n%s\n!=\n%s", buf.String(), wanted)
+	}
+}
+
+func TestTextOutputNameScore(t *testing.T) {
+	// Only names less likely than LICENSE are reported
+	licenses, err := listTestdataLicenses([]string{"colors/coral", "colors/red"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	err = writeText(buf, licenses, textOptions{Confidence: 0.9, Words: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := `colors/coral  Creative Commons Attribution Share Alike 4.0 International [CC-BY-SA-4.0]
+              file name score: 0.7 (license with extension)
+colors/red    MIT License [MIT] (98%)
+              ~words: mit, license
+              matched 91/93 template words (0 extra)
+              size: 1059 bytes, 19 lines
+`
+	if buf.String() != wanted {
+		t.Fatalf("unexpected output:\n%s\n!=\n%s", buf.String(), wanted)
+	}
+}
+
+func TestTextOutputLicenseURL(t *testing.T) {
+	licenses, err := listTestdataLicenses([]string{"colors/jade"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	err = writeText(buf, licenses, textOptions{Confidence: 0.9, Words: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := `colors/jade  Apache License 2.0 [Apache-2.0] (95%) (from URL)
+             url: https://www.apache.org/licenses/LICENSE-2.0
+             size: 59 bytes, 1 line
+`
+	if buf.String() != wanted {
+		t.Fatalf("unexpected output:\n%s\n!=\n%s", buf.String(), wanted)
+	}
+	entries := makeJSONLicenses(licenses, 0.9)
+	if entries[0].Source != "url" || entries[0].URL == "" {
+		t.Fatalf("unexpected JSON source: %q %q", entries[0].Source, entries[0].URL)
+	}
+}
+
+func TestSortLicenses(t *testing.T) {
+	licenses, err := listTestdataLicenses([]string{"colors/purple", "colors/blue",
+		"colors/yellow"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		Key    string
+		Wanted string
+	}{
+		{"package", "blue broken missing purple red yellow"},
+		// Unknown licenses last
+		{"license", "blue broken red yellow missing purple"},
+		// Equal scores keep the input order
+		{"score", "missing purple yellow red blue broken"},
+	}
+	for _, test := range tests {
+		sorted, err := sortLicenses(licenses, test.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, l := range sorted {
+			got = append(got, strings.TrimPrefix(l.Package, "colors/"))
+		}
+		if strings.Join(got, " ") != test.Wanted {
+			t.Errorf("unexpected %s order: %s != %s", test.Key,
+				strings.Join(got, " "), test.Wanted)
+		}
+	}
+	if _, err := sortLicenses(licenses, "path"); err == nil {
+		t.Fatal("invalid sort key was accepted")
+	}
+
+	// Text and JSON output honor the sort key
+	buf := &bytes.Buffer{}
+	err = writeText(buf, licenses, textOptions{Confidence: 0.9, Sort: "score"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(licenses) || !strings.HasPrefix(lines[2], "colors/yellow") {
+		t.Fatalf("text output is not sorted by score:\n%s", buf.String())
+	}
+	buf.Reset()
+	err = writeJSON(buf, licenses, jsonOptions{Sort: "license"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []map[string]interface{}{}
+	err = json.Unmarshal(buf.Bytes(), &entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(licenses) || entries[2]["package"] != "colors/red" {
+		t.Fatalf("JSON output is not sorted by license:\n%s", buf.String())
+	}
+}
+
+func TestSummary(t *testing.T) {
+	licenses, err := listTestdataLicenses([]string{"colors/purple", "colors/blue",
+		"colors/black", "colors/navy", "colors/yellow", "colors/green"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	err = writeSummary(buf, licenses, 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// colors/missing, colors/purple and colors/green have no license, and
+	// colors/yellow scores below confidence.
+	wanted := "Summary: MIT: 2, Apache-2.0: 1, GPL-3.0-only: 1, " +
+		"MIT OR Apache-2.0: 1, unknown: 4\n"
+	if buf.String() != wanted {
+		t.Fatalf("unexpected summary:\n%s\n!=\n%s", buf.String(), wanted)
+	}
+}
+
+func TestAtomicFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.txt")
+	err := ioutil.WriteFile(path, []byte("old\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkContent := func(wanted string) {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != wanted {
+			t.Fatalf("unexpected content: %q != %q", string(data), wanted)
+		}
+		entries, err := ioutil.ReadDir(filepath.Dir(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 {
+			t.Fatalf("temporary files were left behind: %d entries", len(entries))
+		}
+	}
+
+	// Aborted files do not change the target
+	f, err := createAtomic(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("partial")); err != nil {
+		t.Fatal(err)
+	}
+	f.Abort()
+	checkContent("old\n")
+
+	f, err = createAtomic(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Abort()
+	if _, err := f.Write([]byte("new\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	f.Abort()
+	checkContent("new\n")
+
+	if _, err := createAtomic(filepath.Join(path, "missing", "report.txt")); err == nil {
+		t.Fatal("file was created in missing directory")
+	}
+}
+
+func TestTextOutputMissing(t *testing.T) {
+	// colors/green has no license file, colors/yellow one does not look like
+	// a license.
+	licenses, err := listTestdataLicenses([]string{"colors/green", "colors/yellow"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(licenses) != 2 || !licenses[0].Missing() || licenses[1].Missing() {
+		t.Fatalf("unexpected missing states: %+v", licenses)
+	}
+	buf := &bytes.Buffer{}
+	err = writeText(buf, licenses, textOptions{Confidence: 0.9, MinScore: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := `colors/green   ? (no license file found)
+colors/yellow  ? (license file present but unrecognized)
+`
+	if buf.String() != wanted {
+		t.Fatalf("unexpected output:\n%s\n!=\n%s", buf.String(), wanted)
+	}
+}
+
+func TestTextStream(t *testing.T) {
+	// Rows are flushed one by one but their license column stays aligned
+	licenses, err := listTestdataLicenses([]string{"colors/green", "colors/yellow"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	stream := newTextStream(buf, textOptions{Confidence: 0.9, MinScore: 0.5})
+	for _, l := range licenses {
+		if err := stream.Write(l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+	for _, line := range lines {
+		if strings.Index(line, "?") != streamColumnWidth {
+			t.Fatalf("unaligned license column:\n%s", buf.String())
+		}
+	}
+}
+
+func TestListTemplates(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := printLicenses([]string{"-list-templates"}, stdout, stderr); err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]bool{}
+	titles := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		fields := regexp.MustCompile(`\s{2,}`).Split(line, -1)
+		if len(fields) != 3 {
+			t.Fatalf("invalid template line: %q", line)
+		}
+		titles = append(titles, fields[0])
+		found[strings.Join(fields, "|")] = true
+	}
+	for _, wanted := range []string{
+		"MIT License|-|MIT",
+		"Apache License 2.0|Apache|Apache-2.0",
+	} {
+		if !found[wanted] {
+			t.Fatalf("template %q is not listed:\n%s", wanted, stdout.String())
+		}
+	}
+	if !sort.StringsAreSorted(titles) {
+		t.Fatalf("templates are not sorted: %v", titles)
+	}
+
+	stdout.Reset()
+	err := printLicenses([]string{"-list-templates", "-json"}, stdout, stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates := []jsonTemplate{}
+	if err := json.Unmarshal(stdout.Bytes(), &templates); err != nil {
+		t.Fatalf("could not decode output: %s\n%s", err, stdout.String())
+	}
+	if len(templates) != len(titles) {
+		t.Fatalf("unexpected templates count: %d != %d", len(templates), len(titles))
+	}
+	for _, templ := range templates {
+		if templ.SPDX == "LGPL-2.1-only" && (templ.Category != "LGPL" ||
+			len(templ.Conditions) == 0 || templ.Limitations == nil) {
+			t.Fatalf("unexpected LGPL-2.1 entry: %+v", templ)
+		}
+	}
+	if err := printLicenses([]string{"-list-templates", "colors/red"}, stdout,
+		stderr); err == nil {
+		t.Fatal("package arguments were accepted")
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/licenses/licensecheck"
+)
+
+// overrides maps import paths to manually assigned license templates.
+type overrides map[string]*licensecheck.Template
+
+// parseOverridesYAML parses the flat "import/path: license" mapping of YAML
+// override files, see parseYAMLFields.
+func parseOverridesYAML(data []byte) (map[string]string, error) {
+	fields, err := parseYAMLFields(data)
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]string{}
+	for _, f := range fields {
+		if f.Item || f.Key == "" || f.Value == "" {
+			return nil, fmt.Errorf("expected \"path: license\" at line %d", f.Line)
+		}
+		names[f.Key] = f.Value
+	}
+	return names, nil
+}
+
+// loadOverrides reads an override file mapping import paths to license names,
+// either as a JSON object or a flat YAML mapping for .yaml and .yml files.
+// Names must refer to templates of matcher, see licensecheck.FindTemplate.
+func loadOverrides(path string, matcher *licensecheck.Matcher) (overrides, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]string{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		names, err = parseOverridesYAML(data)
+	default:
+		err = json.Unmarshal(data, &names)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %s", path, err)
+	}
+	o := overrides{}
+	for pkg, name := range names {
+		t := licensecheck.FindTemplate(matcher.Templates(), name)
+		if t == nil {
+			return nil, fmt.Errorf("unknown license %q for %s in %s", name, pkg, path)
+		}
+		o[strings.TrimSuffix(pkg, "/")] = t
+	}
+	return o, nil
+}
+
+// overridesFiles lists the default override file names, by order of
+// preference.
+var overridesFiles = []string{".licenses.json", ".licenses.yaml", ".licenses.yml"}
+
+// findOverridesFile returns the path of the default override file in dir, or
+// an empty string if there is none.
+func findOverridesFile(dir string) string {
+	for _, name := range overridesFiles {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// Lookup returns the template assigned to pkg or its closest parent package,
+// or nil if there is none.
+func (o overrides) Lookup(pkg string) *licensecheck.Template {
+	prefixes := []string{}
+	for prefix := range o {
+		if pkg == prefix || strings.HasPrefix(pkg, prefix+"/") {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	if len(prefixes) == 0 {
+		return nil
+	}
+	sort.Strings(prefixes)
+	return o[prefixes[len(prefixes)-1]]
+}
+
+// Apply replaces the match of supplied license with its override, if any.
+// Packages which failed to load are left unchanged.
+func (o overrides) Apply(l License) License {
+	if l.Err != "" {
+		return l
+	}
+	if t := o.Lookup(l.Package); t != nil {
+		l.MatchResult = licensecheck.MatchResult{Template: t, Score: 1}
+		l.Override = true
+	}
+	return l
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOverrides(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "overrides.json")
+	err := ioutil.WriteFile(jsonPath, []byte(`{
+	"colors/green": "mit",
+	"colors": "Apache License 2.0"
+}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	yamlPath := filepath.Join(dir, "overrides.yaml")
+	err = ioutil.WriteFile(yamlPath, []byte(`# Manual licenses
+colors/green: MIT
+"colors": 'Apache-2.0' # Known out-of-band
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{jsonPath, yamlPath} {
+		licenses, err := listTestdataLicensesWith([]string{"colors/green",
+			"colors/purple"}, listOptions{Overrides: path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := &bytes.Buffer{}
+		err = writeText(buf, licenses, textOptions{Confidence: 0.9, SPDX: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		wanted := []string{
+			"colors/broken   Apache-2.0 (override)",
+			"colors/green    MIT (override)",
+			"colors/missing  missing package: cannot find package",
+			"colors/purple   Apache-2.0 (override)",
+			"colors/red      Apache-2.0 (override)",
+		}
+		if len(got) != len(wanted) {
+			t.Fatalf("unexpected output:\n%s", buf.String())
+		}
+		for i, w := range wanted {
+			if !strings.HasPrefix(got[i], w) {
+				t.Fatalf("unexpected output:\n%s", buf.String())
+			}
+		}
+		if licenses[4].Path != "colors/red/LICENSE" {
+			t.Fatalf("license file was not kept: %+v", licenses[4])
+		}
+	}
+
+	// Unknown license names are rejected
+	err = ioutil.WriteFile(jsonPath, []byte(`{"colors/green": "Beerware"}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = listTestdataLicensesWith([]string{"colors/green"},
+		listOptions{Overrides: jsonPath})
+	if err == nil || !strings.Contains(err.Error(), `unknown license "Beerware"`) {
+		t.Fatalf("unknown license was not rejected: %v", err)
+	}
+}
+
+func TestParseOverridesYAMLComments(t *testing.T) {
+	// Overrides and exceptions files share their comment rules
+	names, err := parseOverridesYAML([]byte("\t# Indented comment\n" +
+		"colors/green: \"LicenseRef-#1\"\t# Quoted\n" +
+		"'colors/red': MIT # Plain\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names["colors/green"] != "LicenseRef-#1" ||
+		names["colors/red"] != "MIT" {
+		t.Fatalf("unexpected overrides: %v", names)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pmezard/licenses/licensecheck"
+)
+
+// policy describes which licenses are acceptable. Licenses are referred to by
+// template nickname, title or SPDX identifier, case-insensitively.
+type policy struct {
+	// Deny lists forbidden licenses.
+	Deny []string
+	// Allow lists acceptable licenses. If not empty, any other license is a
+	// violation.
+	Allow []string
+	// DenyUnknown makes unknown or low-confidence licenses violations.
+	DenyUnknown bool
+}
+
+// splitNames splits a comma-separated list of license names.
+func splitNames(s string) []string {
+	names := []string{}
+	for _, n := range strings.Split(s, ",") {
+		n = strings.TrimSpace(n)
+		if n != "" {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// accepts returns true if supplied match complies with the policy. Licensees
+// of multi-licensed packages can pick any of the licenses, so only one of them
+// has to comply.
+func (p *policy) accepts(m licensecheck.MatchResult) bool {
+	parts := m.Parts
+	if len(parts) == 0 {
+		parts = []licensecheck.MatchResult{m}
+	}
+	for _, part := range parts {
+		if !licensecheck.MatchNames(part.Template, p.Deny) &&
+			(len(p.Allow) == 0 || licensecheck.MatchNames(part.Template, p.Allow)) {
+			return true
+		}
+	}
+	return false
+}
+
+// empty returns true if the policy accepts any license.
+func (p *policy) empty() bool {
+	return len(p.Deny) == 0 && len(p.Allow) == 0 && !p.DenyUnknown
+}
+
+// check returns the licenses violating the policy. Licenses whose score is
+// below confidence are considered unknown. Files reserving all rights violate
+// any policy which is not empty, as they grant nothing to comply with.
+func (p *policy) check(licenses []License, confidence float64) []License {
+	violations := []License{}
+	for _, l := range licenses {
+		switch classify(l, confidence).Class {
+		case Unknown:
+			if p.DenyUnknown {
+				violations = append(violations, l)
+			}
+		case NoLicense:
+			if !p.empty() {
+				violations = append(violations, l)
+			}
+		default:
+			if !p.accepts(l.MatchResult) {
+				violations = append(violations, l)
+			}
+		}
+	}
+	return violations
+}
+
+// problems returns the licenses which are unknown or reserve all rights, see
+// unknownLicenses, or violate the policy, in their original order.
+func (p *policy) problems(licenses []License, confidence float64) []License {
+	kept := []License{}
+	for _, l := range licenses {
+		c := classify(l, confidence).Class
+		if c == Unknown || c == NoLicense || !p.accepts(l.MatchResult) {
+			kept = append(kept, l)
+		}
+	}
+	return kept
+}
+
+// PolicyError is returned when some licenses violate the license policy.
+type PolicyError struct {
+	Violations []License
+}
+
+func (err *PolicyError) Error() string {
+	lines := []string{
+		fmt.Sprintf("%d packages violate the license policy:", len(err.Violations)),
+	}
+	for _, l := range err.Violations {
+		license := "unknown license"
+		if l.Template != nil {
+			license = fmt.Sprintf("%s (%d%%)", templateName(l.Template),
+				scorePercent(l.Score))
+		}
+		lines = append(lines, fmt.Sprintf("  %s: %s", l.Package, license))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// unknownLicenses returns the licenses of packages which failed to load, have
+// no license file, one scoring below confidence or one reserving all rights.
+func unknownLicenses(licenses []License, confidence float64) []License {
+	unknown := []License{}
+	for _, l := range licenses {
+		if c := classify(l, confidence).Class; c == Unknown || c == NoLicense {
+			unknown = append(unknown, l)
+		}
+	}
+	return unknown
+}
+
+// UnknownError is returned when some licenses are missing or unknown, and
+// they are not allowed.
+type UnknownError struct {
+	Licenses []License
+}
+
+func (err *UnknownError) Error() string {
+	lines := []string{
+		fmt.Sprintf("%d packages have unknown licenses:", len(err.Licenses)),
+	}
+	for _, l := range err.Licenses {
+		reason := "license file present but unrecognized"
+		switch {
+		case l.Err != "":
+			reason = formatError(l)
+		case l.Missing():
+			reason = "no license file found"
+		case l.Template != nil && l.Template.Title == licensecheck.NoLicenseTitle:
+			reason = "all rights reserved, no license granted"
+		case l.Template != nil:
+			reason = fmt.Sprintf("low confidence %s (%d%%)", matchName(l.MatchResult),
+				scorePercent(l.Score))
+		}
+		lines = append(lines, fmt.Sprintf("  %s: %s", l.Package, reason))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func checkTestPolicy(pkgs []string, p *policy) (string, error) {
+	licenses, err := listTestdataLicenses(pkgs)
+	if err != nil {
+		return "", err
+	}
+	licenses, err = groupLicenses(licenses)
+	if err != nil {
+		return "", err
+	}
+	names := []string{}
+	for _, l := range p.check(licenses, 0.9) {
+		names = append(names, l.Package)
+	}
+	return strings.Join(names, ","), nil
+}
+
+func TestPolicy(t *testing.T) {
+	pkgs := []string{"colors/cmd/mix", "colors/yellow", "colors/green",
+		"colors/black"}
+	tests := []struct {
+		Policy     policy
+		Violations string
+	}{
+		{policy{}, ""},
+		{policy{Deny: splitNames("LGPL-2.1, gpl-3.0")}, "couleurs/red"},
+		{policy{Deny: splitNames("MIT")}, "colors/red"},
+		{policy{Deny: splitNames("MIT,Apache-2.0")}, "colors/black,colors/red"},
+		{policy{Deny: splitNames("gnu lgpl v2.1")}, "couleurs/red"},
+		{policy{Allow: splitNames("MIT,AFL-3.0")}, "couleurs/red"},
+		{policy{Allow: splitNames("Apache-2.0")},
+			"colors/cmd/mix,colors/red,couleurs/red"},
+		{policy{Allow: splitNames("MIT,AFL-3.0"), DenyUnknown: true},
+			"colors/green,colors/yellow,couleurs/red"},
+		{policy{DenyUnknown: true}, "colors/green,colors/yellow"},
+	}
+	for _, test := range tests {
+		violations, err := checkTestPolicy(pkgs, &test.Policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if violations != test.Violations {
+			t.Fatalf("unexpected violations for %+v: %q != %q", test.Policy,
+				violations, test.Violations)
+		}
+	}
+}
+
+func TestPolicyNoLicense(t *testing.T) {
+	// colors/umber reserves all rights
+	pkgs := []string{"colors/umber", "colors/red"}
+	tests := []struct {
+		Policy     policy
+		Violations string
+	}{
+		{policy{}, ""},
+		{policy{Deny: splitNames("GPL-3.0")}, "colors/umber"},
+		{policy{Allow: splitNames("MIT")}, "colors/umber"},
+		{policy{DenyUnknown: true}, "colors/umber"},
+	}
+	for _, test := range tests {
+		violations, err := checkTestPolicy(pkgs, &test.Policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if violations != test.Violations {
+			t.Fatalf("unexpected violations for %+v: %q != %q", test.Policy,
+				violations, test.Violations)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	spdxVersion     = "SPDX-2.3"
+	spdxDataLicense = "CC0-1.0"
+	spdxDocumentID  = "SPDXRef-DOCUMENT"
+	spdxNoAssertion = "NOASSERTION"
+	// spdxNone is the license of packages without license file, as opposed
+	// to unknown ones.
+	spdxNone = "NONE"
+	// spdxNamespacePrefix prefixes generated document namespaces.
+	spdxNamespacePrefix = "https://spdx.org/spdxdocs/licenses-"
+)
+
+// reSPDXIDChars matches the characters not allowed in SPDX element
+// identifiers.
+var reSPDXIDChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+type sbomOptions struct {
+	// Format is either "json" or "tag", for the SPDX tag:value format.
+	Format string
+	// Confidence is the score below which licenses are not asserted, see
+	// classify.
+	Confidence float64
+	// Created is the document creation time.
+	Created time.Time
+}
+
+// checkSBOMFormat returns an error if format is not a supported SBOM format.
+func checkSBOMFormat(format string) error {
+	if format != "json" && format != "tag" {
+		return fmt.Errorf("sbom must be json or tag, got %q", format)
+	}
+	return nil
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name             string `json:"name"`
+	SPDXID           string `json:"SPDXID"`
+	DownloadLocation string `json:"downloadLocation"`
+	FilesAnalyzed    bool   `json:"filesAnalyzed"`
+	LicenseConcluded string `json:"licenseConcluded"`
+	LicenseDeclared  string `json:"licenseDeclared"`
+	CopyrightText    string `json:"copyrightText"`
+}
+
+type spdxRelationship struct {
+	Element        string `json:"spdxElementId"`
+	Type           string `json:"relationshipType"`
+	RelatedElement string `json:"relatedSpdxElement"`
+}
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+// spdxDownloadLocation returns the download location of a package, derived
+// from its import path when it starts with a domain name, like
+// "github.com/foo/bar".
+func spdxDownloadLocation(pkg string) string {
+	root := strings.SplitN(pkg, "/", 2)[0]
+	if !strings.Contains(root, ".") {
+		return spdxNoAssertion
+	}
+	return "https://" + pkg
+}
+
+// spdxLicense returns the SPDX expression of the license, NONE if the package
+// has no license file, or NOASSERTION if the license is unknown, below
+// confidence or has no SPDX identifier.
+func spdxLicense(l License, confidence float64) string {
+	if spdx := confidentSPDX(l, confidence); spdx != "" {
+		return spdx
+	}
+	if l.Missing() {
+		return spdxNone
+	}
+	return spdxNoAssertion
+}
+
+// makeSPDXDocument returns the SPDX document describing licenses, sorted by
+// package. Detected licenses are both concluded and declared, while
+// overridden ones are only concluded, the license file not declaring them.
+func makeSPDXDocument(licenses []License, opts sbomOptions) (*spdxDocument, error) {
+	licenses, err := sortLicenses(licenses, "package")
+	if err != nil {
+		return nil, err
+	}
+	created := opts.Created.UTC().Format(time.RFC3339)
+	doc := &spdxDocument{
+		SPDXVersion: spdxVersion,
+		DataLicense: spdxDataLicense,
+		SPDXID:      spdxDocumentID,
+		Name:        "licenses",
+		CreationInfo: spdxCreationInfo{
+			Created:  created,
+			Creators: []string{"Tool: licenses"},
+		},
+		Packages:      []spdxPackage{},
+		Relationships: []spdxRelationship{},
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", created)
+	seen := map[string]bool{}
+	for _, l := range licenses {
+		id := "SPDXRef-Package-" + strings.Trim(
+			reSPDXIDChars.ReplaceAllString(l.Package, "-"), "-")
+		base := id
+		for i := 2; seen[id]; i++ {
+			id = fmt.Sprintf("%s-%d", base, i)
+		}
+		seen[id] = true
+		concluded := spdxLicense(l, opts.Confidence)
+		declared := concluded
+		if l.Override {
+			declared = spdxNoAssertion
+		}
+		copyright := spdxNoAssertion
+		if len(l.Copyright) > 0 {
+			copyright = strings.Join(l.Copyright, "\n")
+		}
+		doc.Packages = append(doc.Packages, spdxPackage{
+			Name:             l.Package,
+			SPDXID:           id,
+			DownloadLocation: spdxDownloadLocation(l.Package),
+			LicenseConcluded: concluded,
+			LicenseDeclared:  declared,
+			CopyrightText:    copyright,
+		})
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			Element:        spdxDocumentID,
+			Type:           "DESCRIBES",
+			RelatedElement: id,
+		})
+		fmt.Fprintf(h, "%s %s\n", l.Package, concluded)
+	}
+	doc.DocumentNamespace = spdxNamespacePrefix + hex.EncodeToString(h.Sum(nil))[:32]
+	return doc, nil
+}
+
+// writeSPDXTag writes doc in the SPDX tag:value format.
+func writeSPDXTag(out io.Writer, doc *spdxDocument) error {
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "SPDXVersion: %s\n", doc.SPDXVersion)
+	fmt.Fprintf(w, "DataLicense: %s\n", doc.DataLicense)
+	fmt.Fprintf(w, "SPDXID: %s\n", doc.SPDXID)
+	fmt.Fprintf(w, "DocumentName: %s\n", doc.Name)
+	fmt.Fprintf(w, "DocumentNamespace: %s\n", doc.DocumentNamespace)
+	for _, c := range doc.CreationInfo.Creators {
+		fmt.Fprintf(w, "Creator: %s\n", c)
+	}
+	fmt.Fprintf(w, "Created: %s\n", doc.CreationInfo.Created)
+	for _, p := range doc.Packages {
+		copyright := p.CopyrightText
+		if copyright != spdxNoAssertion {
+			copyright = "<text>" + copyright + "</text>"
+		}
+		fmt.Fprintf(w, "\nPackageName: %s\n", p.Name)
+		fmt.Fprintf(w, "SPDXID: %s\n", p.SPDXID)
+		fmt.Fprintf(w, "PackageDownloadLocation: %s\n", p.DownloadLocation)
+		fmt.Fprintf(w, "FilesAnalyzed: %v\n", p.FilesAnalyzed)
+		fmt.Fprintf(w, "PackageLicenseConcluded: %s\n", p.LicenseConcluded)
+		fmt.Fprintf(w, "PackageLicenseDeclared: %s\n", p.LicenseDeclared)
+		fmt.Fprintf(w, "PackageCopyrightText: %s\n", copyright)
+	}
+	if len(doc.Relationships) > 0 {
+		fmt.Fprintf(w, "\n")
+	}
+	for _, r := range doc.Relationships {
+		fmt.Fprintf(w, "Relationship: %s %s %s\n", r.Element, r.Type, r.RelatedElement)
+	}
+	return w.Flush()
+}
+
+// writeSBOM writes licenses as an SPDX document, in JSON or tag:value format,
+// listing every entry as an SPDX package with its license expression, see
+// makeSPDXDocument.
+func writeSBOM(out io.Writer, licenses []License, opts sbomOptions) error {
+	doc, err := makeSPDXDocument(licenses, opts)
+	if err != nil {
+		return err
+	}
+	if opts.Format == "tag" {
+		return writeSPDXTag(out, doc)
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSBOM(t *testing.T) {
+	licenses, err := listTestdataLicenses([]string{"colors/purple"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := sbomOptions{
+		Format:     "json",
+		Confidence: 0.9,
+		Created:    time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	buf := &bytes.Buffer{}
+	if err := writeSBOM(buf, licenses, opts); err != nil {
+		t.Fatal(err)
+	}
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("could not decode document: %s\n%s", err, buf.String())
+	}
+	for _, key := range []string{"spdxVersion", "dataLicense", "SPDXID", "name",
+		"documentNamespace"} {
+		if s, _ := doc[key].(string); s == "" {
+			t.Fatalf("missing document %s: %v", key, doc[key])
+		}
+	}
+	if doc["spdxVersion"] != "SPDX-2.3" || doc["SPDXID"] != "SPDXRef-DOCUMENT" ||
+		!strings.HasPrefix(doc["documentNamespace"].(string), "https://") {
+		t.Fatalf("unexpected document header: %v", doc)
+	}
+	info := doc["creationInfo"].(map[string]interface{})
+	if info["created"] != "2020-01-02T03:04:05Z" || len(info["creators"].([]interface{})) != 1 {
+		t.Fatalf("unexpected creation info: %v", info)
+	}
+	got := []string{}
+	for _, p := range doc["packages"].([]interface{}) {
+		pkg := p.(map[string]interface{})
+		for _, key := range []string{"name", "SPDXID", "downloadLocation",
+			"licenseConcluded", "licenseDeclared", "copyrightText"} {
+			if s, _ := pkg[key].(string); s == "" {
+				t.Fatalf("missing package %s: %v", key, pkg)
+			}
+		}
+		got = append(got, pkg["SPDXID"].(string)+" "+pkg["licenseConcluded"].(string))
+	}
+	wanted := []string{
+		"SPDXRef-Package-colors-broken GPL-3.0-only",
+		"SPDXRef-Package-colors-missing NOASSERTION",
+		"SPDXRef-Package-colors-purple NONE",
+		"SPDXRef-Package-colors-red MIT",
+	}
+	if strings.Join(got, "\n") != strings.Join(wanted, "\n") {
+		t.Fatalf("unexpected packages:\n%s\n!=\n%s", strings.Join(got, "\n"),
+			strings.Join(wanted, "\n"))
+	}
+	if n := len(doc["relationships"].([]interface{})); n != len(wanted) {
+		t.Fatalf("unexpected relationships count: %d", n)
+	}
+
+	// tag:value documents have one "Tag: value" pair per line
+	opts.Format = "tag"
+	buf = &bytes.Buffer{}
+	if err := writeSBOM(buf, licenses, opts); err != nil {
+		t.Fatal(err)
+	}
+	tags := map[string]int{}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if line == "" {
+			continue
+		}
+		i := strings.Index(line, ": ")
+		if i <= 0 || strings.TrimSpace(line[i+2:]) == "" {
+			t.Fatalf("invalid tag:value line: %q", line)
+		}
+		tags[line[:i]]++
+	}
+	for _, tag := range []string{"SPDXVersion", "DataLicense", "DocumentName",
+		"DocumentNamespace", "Creator", "Created"} {
+		if tags[tag] != 1 {
+			t.Fatalf("unexpected %s count: %d", tag, tags[tag])
+		}
+	}
+	for _, tag := range []string{"PackageName", "PackageDownloadLocation",
+		"PackageLicenseConcluded", "PackageLicenseDeclared", "PackageCopyrightText",
+		"Relationship"} {
+		if tags[tag] != len(wanted) {
+			t.Fatalf("unexpected %s count: %d", tag, tags[tag])
+		}
+	}
+
+	if err := checkSBOMFormat("xml"); err == nil {
+		t.Fatal("unknown format was accepted")
+	}
+}
+
+func TestSPDXPlaceholders(t *testing.T) {
+	// colors/yellow license scores below confidence, colors/green has none
+	licenses, err := listTestdataLicenses([]string{"colors/green", "colors/yellow"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := writeJSON(buf, licenses, jsonOptions{Confidence: 0.9}); err != nil {
+		t.Fatal(err)
+	}
+	entries := []map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &entries); err != nil {
+		t.Fatalf("could not decode output: %s\n%s", err, buf.String())
+	}
+	got := []string{}
+	for _, e := range entries {
+		got = append(got, e["package"].(string)+" "+e["spdxExpression"].(string))
+	}
+	wanted := []string{
+		"colors/green NONE",
+		"colors/yellow NOASSERTION",
+	}
+	if strings.Join(got, "\n") != strings.Join(wanted, "\n") {
+		t.Fatalf("unexpected JSON expressions:\n%s\n!=\n%s", strings.Join(got, "\n"),
+			strings.Join(wanted, "\n"))
+	}
+
+	doc, err := makeSPDXDocument(licenses, sbomOptions{Confidence: 0.9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = []string{}
+	for _, p := range doc.Packages {
+		got = append(got, p.Name+" "+p.LicenseConcluded)
+	}
+	if strings.Join(got, "\n") != strings.Join(wanted, "\n") {
+		t.Fatalf("unexpected SPDX licenses:\n%s\n!=\n%s", strings.Join(got, "\n"),
+			strings.Join(wanted, "\n"))
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pmezard/licenses/assets"
+	"github.com/pmezard/licenses/licensecheck"
+)
+
+// maxServedLicenseSize is the maximum size of license texts posted to the
+// server, in bytes.
+const maxServedLicenseSize = 1 << 20
+
+const (
+	// serverReadHeaderTimeout bounds the time clients take to send request
+	// headers, so idle connections cannot exhaust the server.
+	serverReadHeaderTimeout = 10 * time.Second
+	// serverReadTimeout bounds the time clients take to send whole requests,
+	// license texts included.
+	serverReadTimeout = time.Minute
+	// serverWriteTimeout bounds the time spent matching and writing
+	// responses.
+	serverWriteTimeout = time.Minute
+	// serverIdleTimeout is the time keep-alive connections are kept open
+	// between requests.
+	serverIdleTimeout = 2 * time.Minute
+)
+
+type serverOptions struct {
+	// Confidence is the threshold used to classify licenses, see classify.
+	Confidence float64
+	// Top is the number of best matching templates reported as guesses.
+	Top int
+}
+
+// licenseServer exposes a matcher over HTTP, see newServer.
+type licenseServer struct {
+	matcher *licensecheck.Matcher
+	opts    serverOptions
+}
+
+// newServer returns the HTTP handler of -serve mode:
+//
+// POST /match matches the license text in the request body, possibly gzip
+// compressed, and returns a JSON entry like -json ones. The "name" query
+// parameter is the license file name, used to strip Markdown or HTML markup
+// and reported as package and license path. It defaults to "LICENSE".
+//
+// GET /templates lists the templates of matcher, like -list-templates -json.
+//
+// GET /templates/NAME returns the embedded template asset NAME, like
+// "mit.txt".
+func newServer(matcher *licensecheck.Matcher, opts serverOptions) http.Handler {
+	s := &licenseServer{
+		matcher: matcher,
+		opts:    opts,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/match", s.serveMatch)
+	mux.HandleFunc("/templates", s.serveTemplates)
+	mux.HandleFunc("/templates/", s.serveTemplateAsset)
+	return mux
+}
+
+// newHTTPServer returns the -serve mode server listening on addr, with
+// timeouts suited to a service shared by many clients.
+func newHTTPServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: serverReadHeaderTimeout,
+		ReadTimeout:       serverReadTimeout,
+		WriteTimeout:      serverWriteTimeout,
+		IdleTimeout:       serverIdleTimeout,
+	}
+}
+
+// writeJSONResponse writes v as an indented JSON response.
+func writeJSONResponse(w http.ResponseWriter, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(data, '\n'))
+}
+
+func (s *licenseServer) serveMatch(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "license text must be posted", http.StatusMethodNotAllowed)
+		return
+	}
+	name := req.URL.Query().Get("name")
+	if name == "" {
+		name = "LICENSE"
+	}
+	data, err := licensecheck.ReadLicenseData(http.MaxBytesReader(w, req.Body,
+		maxServedLicenseSize))
+	if err != nil {
+		http.Error(w, "cannot read license: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	data = licensecheck.StripMarkup(name, data)
+	l := License{
+		Package:     name,
+		MatchResult: s.matcher.Match(data),
+		Path:        name,
+		NameScore:   licensecheck.ScoreLicenseName(name),
+		Copyright:   licensecheck.ExtractCopyrights(data),
+		Size:        len(data),
+		Lines:       countLines(data),
+	}
+	if s.opts.Top > 0 {
+		l.Guesses = s.matcher.MatchN(data, s.opts.Top)
+	}
+	writeJSONResponse(w, makeJSONLicenses([]License{l}, s.opts.Confidence)[0])
+}
+
+func (s *licenseServer) serveTemplates(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "templates can only be listed", http.StatusMethodNotAllowed)
+		return
+	}
+	buf := &bytes.Buffer{}
+	if err := writeTemplates(buf, s.matcher.Templates(), true); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf.Bytes())
+}
+
+func (s *licenseServer) serveTemplateAsset(w http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(req.URL.Path, "/templates/")
+	for _, a := range assets.Assets {
+		if a.Name == name {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			a.ServeHTTP(w, req)
+			return
+		}
+	}
+	http.NotFound(w, req)
+}
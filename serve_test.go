@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+	matcher, err := newMatcher(listOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(newServer(matcher, serverOptions{Confidence: 0.9}))
+	defer server.Close()
+
+	f, err := os.Open(filepath.Join("testdata", "src", "colors", "red", "LICENSE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rsp, err := http.Post(server.URL+"/match?name=LICENSE.txt", "text/plain", f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status: %s", rsp.Status)
+	}
+	e := jsonLicense{}
+	if err := json.NewDecoder(rsp.Body).Decode(&e); err != nil {
+		t.Fatal(err)
+	}
+	if e.SPDX != "MIT" || e.Percent != 98 || e.Class != "confident" ||
+		e.LicensePath != "LICENSE.txt" || len(e.Copyright) != 1 {
+		t.Fatalf("unexpected match: %+v", e)
+	}
+
+	rsp, err = http.Get(server.URL + "/match")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+	if rsp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("unexpected status: %s", rsp.Status)
+	}
+
+	rsp, err = http.Get(server.URL + "/templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates := []jsonTemplate{}
+	err = json.NewDecoder(rsp.Body).Decode(&templates)
+	rsp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != len(matcher.Templates()) {
+		t.Fatalf("unexpected templates: %+v", templates)
+	}
+
+	rsp, err = http.Get(server.URL + "/templates/mit.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(rsp.Body)
+	rsp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.StatusCode != http.StatusOK || !strings.Contains(string(data), "title: MIT License") {
+		t.Fatalf("unexpected template: %s\n%s", rsp.Status, data)
+	}
+
+	rsp, err = http.Get(server.URL + "/templates/unknown.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+	if rsp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unexpected status: %s", rsp.Status)
+	}
+}
+
+func TestHTTPServerTimeouts(t *testing.T) {
+	s := newHTTPServer(":8080", http.NotFoundHandler())
+	if s.Addr != ":8080" || s.ReadHeaderTimeout <= 0 || s.ReadTimeout <= 0 ||
+		s.WriteTimeout <= 0 || s.IdleTimeout <= 0 {
+		t.Fatalf("server has no timeouts: %+v", s)
+	}
+}
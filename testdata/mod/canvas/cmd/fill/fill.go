@@ -0,0 +1,9 @@
+package main
+
+import (
+	"example.com/paint"
+)
+
+func main() {
+	paint.Fill()
+}
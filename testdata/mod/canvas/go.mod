@@ -0,0 +1,7 @@
+module canvas
+
+go 1.16
+
+require example.com/paint v1.0.0
+
+replace example.com/paint => ../paint
@@ -0,0 +1,9 @@
+package main
+
+import (
+	_ "mixed/soft"
+	_ "mixed/strict"
+)
+
+func main() {
+}
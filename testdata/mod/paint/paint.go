@@ -0,0 +1,4 @@
+package paint
+
+func Fill() {
+}
@@ -0,0 +1,5 @@
+package circle
+
+func Circle() string {
+	return "circle"
+}
@@ -0,0 +1,10 @@
+package main
+
+import (
+	"fmt"
+	"shapes/circle"
+)
+
+func main() {
+	fmt.Println(circle.Circle())
+}
@@ -0,0 +1,7 @@
+// SPDX-License-Identifier: (MIT OR GPL-2.0+)
+
+package amber
+
+func amber() string {
+	return "amber"
+}
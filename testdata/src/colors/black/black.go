@@ -0,0 +1,5 @@
+package black
+
+func black() string {
+	return "black"
+}
@@ -0,0 +1,5 @@
+package coral
+
+func coral() string {
+	return "coral"
+}
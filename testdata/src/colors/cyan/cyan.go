@@ -0,0 +1,5 @@
+package cyan
+
+func cyan() string {
+	return "cyan"
+}
@@ -0,0 +1,5 @@
+package gold
+
+func gold() string {
+	return "gold"
+}
@@ -0,0 +1,5 @@
+package khaki
+
+func khaki() string {
+	return "khaki"
+}
@@ -0,0 +1,5 @@
+package lime
+
+func lime() string {
+	return "lime"
+}
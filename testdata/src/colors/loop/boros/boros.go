@@ -0,0 +1,5 @@
+package boros
+
+import (
+	_ "colors/loop/ouro"
+)
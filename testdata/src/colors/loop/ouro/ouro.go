@@ -0,0 +1,5 @@
+package ouro
+
+import (
+	_ "colors/loop/boros"
+)
@@ -0,0 +1,5 @@
+package magenta
+
+func magenta() string {
+	return "magenta"
+}
@@ -0,0 +1,7 @@
+// SPDX-License-Identifier: MIT
+
+package orange
+
+func orange() string {
+	return "orange"
+}
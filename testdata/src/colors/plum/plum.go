@@ -0,0 +1,5 @@
+package plum
+
+func plum() string {
+	return "plum"
+}
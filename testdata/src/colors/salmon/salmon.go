@@ -0,0 +1,5 @@
+package salmon
+
+func salmon() string {
+	return "salmon"
+}
@@ -0,0 +1,5 @@
+package silver
+
+func silver() string {
+	return "silver"
+}
@@ -0,0 +1,5 @@
+package tan
+
+func tan() string {
+	return "tan"
+}
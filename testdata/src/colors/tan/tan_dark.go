@@ -0,0 +1,8 @@
+//go:build dark
+// +build dark
+
+package tan
+
+import (
+	_ "couleurs/red"
+)
@@ -0,0 +1,5 @@
+package tan
+
+import (
+	_ "colors/red"
+)
@@ -0,0 +1,6 @@
+package white_test
+
+import (
+	_ "colors/white"
+	_ "couleurs/red"
+)
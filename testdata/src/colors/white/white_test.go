@@ -0,0 +1,13 @@
+package white
+
+import (
+	"testing"
+
+	_ "colors/red"
+)
+
+func TestWhite(t *testing.T) {
+	if white() != "white" {
+		t.Fatal("white is not white")
+	}
+}
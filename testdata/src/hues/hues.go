@@ -0,0 +1,9 @@
+package hues
+
+import (
+	"hues/warm"
+)
+
+func hues() string {
+	return warm.Warm()
+}
@@ -0,0 +1,5 @@
+package warm
+
+func Warm() string {
+	return "warm"
+}
@@ -0,0 +1,10 @@
+package dark
+
+import (
+	"bare"
+	"ink/black"
+)
+
+func dark() string {
+	return bare.Bare() + black.Black()
+}
@@ -0,0 +1,5 @@
+package pale
+
+func Pale() string {
+	return "pale"
+}
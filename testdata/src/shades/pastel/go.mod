@@ -0,0 +1,3 @@
+module pastel
+
+go 1.16
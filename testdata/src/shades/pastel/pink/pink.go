@@ -0,0 +1,5 @@
+package pink
+
+func Pink() string {
+	return "pink"
+}
@@ -0,0 +1,5 @@
+package apache
+
+func apache() string {
+	return "apache"
+}
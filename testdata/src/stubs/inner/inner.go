@@ -0,0 +1,5 @@
+package inner
+
+func inner() string {
+	return "inner"
+}
@@ -0,0 +1 @@
+int inner(void) { return 0; }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// yamlField is a "key: value" line of a flat YAML file.
+type yamlField struct {
+	// Line is the line number of the field, starting at 1.
+	Line int
+	// Item is true if the field starts a sequence item, with "- ".
+	Item  bool
+	Key   string
+	Value string
+}
+
+// stripYAMLComment returns line without its trailing comment, starting with a
+// "#" at the beginning of the line or after a space, outside quoted values.
+func stripYAMLComment(line string) string {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// splitYAMLField returns the key and value of a "key: value" line, split at
+// the first colon outside quotes followed by a space or ending the line. Both
+// are trimmed and unquoted. It returns false if there is no such colon.
+func splitYAMLField(line string) (string, string, bool) {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ':' && (i+1 == len(line) || line[i+1] == ' ' || line[i+1] == '\t'):
+			unquote := func(s string) string {
+				return strings.Trim(strings.TrimSpace(s), `"'`)
+			}
+			return unquote(line[:i]), unquote(line[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// parseYAMLFields returns the fields of flat YAML data, like override and
+// exception files. Comments, blank lines and document markers are skipped.
+// Nested structures are not supported.
+func parseYAMLFields(data []byte) ([]yamlField, error) {
+	fields := []yamlField{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(stripYAMLComment(scanner.Text()))
+		if line == "" || line == "---" {
+			continue
+		}
+		f := yamlField{Line: n}
+		if strings.HasPrefix(line, "- ") {
+			f.Item = true
+			line = strings.TrimSpace(line[2:])
+		}
+		key, value, ok := splitYAMLField(line)
+		if !ok {
+			return nil, fmt.Errorf("expected \"key: value\" at line %d: %s", n, line)
+		}
+		f.Key, f.Value = key, value
+		fields = append(fields, f)
+	}
+	return fields, scanner.Err()
+}